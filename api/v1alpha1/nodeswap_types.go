@@ -63,15 +63,107 @@ type SwapSpec struct {
 
 type Swaps []SwapSpec
 
+// ManagedOOMMode is the systemd-oomd policy applied to a slice.
+// +kubebuilder:validation:Enum=auto;kill
+type ManagedOOMMode string
+
+const (
+	ManagedOOMAuto ManagedOOMMode = "auto"
+	ManagedOOMKill ManagedOOMMode = "kill"
+)
+
+// OomdSlice configures systemd-oomd for a single systemd slice.
+type OomdSlice struct {
+	// Name of the systemd slice, for example "kubepods.slice".
+	// +kubebuilder:validation:Pattern=`^[a-zA-Z0-9:_.-]+\.slice$`
+	Name string `json:"name"`
+
+	// ManagedOOMSwap sets ManagedOOMSwap= on the slice. With "kill",
+	// systemd-oomd kills the cgroup using the most swap once the swap usage
+	// exceeds SwapUsedLimit.
+	// +optional
+	ManagedOOMSwap ManagedOOMMode `json:"managedOOMSwap,omitempty"`
+}
+
+// OomdSpec tunes systemd-oomd for swap pressure on the selected nodes.
+type OomdSpec struct {
+	// SwapUsedLimit is the swap usage, as a percentage, above which
+	// systemd-oomd acts on slices with ManagedOOMSwap=kill. Defaults to the
+	// systemd default when empty.
+	// +kubebuilder:validation:Pattern=`^[0-9]+(\.[0-9]+)?%$`
+	// +optional
+	SwapUsedLimit string `json:"swapUsedLimit,omitempty"`
+
+	// DefaultMemoryPressureLimit is the memory pressure, as a percentage,
+	// above which systemd-oomd acts on slices with
+	// ManagedOOMMemoryPressure=kill. Defaults to the systemd default when empty.
+	// +kubebuilder:validation:Pattern=`^[0-9]+(\.[0-9]+)?%$`
+	// +optional
+	DefaultMemoryPressureLimit string `json:"defaultMemoryPressureLimit,omitempty"`
+
+	// Slices lists the per-slice ManagedOOMSwap policies.
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	Slices []OomdSlice `json:"slices,omitempty"`
+}
+
+// DedicatedPoolSpec describes a MachineConfigPool created and owned by the
+// operator for the nodes selected by spec.nodeSelector.
+type DedicatedPoolSpec struct {
+	// Name of the MachineConfigPool. The pool inherits the worker
+	// MachineConfigs and the swap MachineConfigs are labeled with its role.
+	// +kubebuilder:validation:MaxLength=63
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	// +required
+	Name string `json:"name"`
+}
+
 // NodeSwapSpec defines the desired state of NodeSwap
+// +kubebuilder:validation:XValidation:rule="!has(self.dedicatedPool) || has(self.nodeSelector)",message="dedicatedPool requires nodeSelector"
 type NodeSwapSpec struct {
 	// The following markers will use OpenAPI v3 schema to validate the value
 	// More info: https://book.kubebuilder.io/reference/markers/crd-validation.html
 
 	// Label selector for Machines on which swap will be deployed.
+	//
+	// Deprecated: use poolSelector. The "key:value" selector is matched
+	// against the machineConfigSelector match labels of the pools and is
+	// only used when poolSelector is not set.
+	// +optional
 	MachineConfigPoolSelector string `json:"machineConfigPoolSelector,omitempty"`
 
+	// PoolSelector selects, by their labels, the MachineConfigPools on which
+	// swap will be deployed. It takes precedence over machineConfigPoolSelector.
+	// +optional
+	PoolSelector *metav1.LabelSelector `json:"poolSelector,omitempty"`
+
+	// MachineConfigPoolNames lists, by name, MachineConfigPools on which swap
+	// will be deployed in addition to the selected ones.
+	// +listType=set
+	// +optional
+	MachineConfigPoolNames []string `json:"machineConfigPoolNames,omitempty"`
+
+	// NodeSelector selects the nodes on which swap will be deployed. Swap is
+	// deployed on the MachineConfigPools whose nodes are all selected; pools
+	// of which only some nodes are selected block the rollout.
+	// +optional
+	NodeSelector *metav1.LabelSelector `json:"nodeSelector,omitempty"`
+
+	// DedicatedPool makes the operator create a MachineConfigPool for the
+	// nodes selected by nodeSelector, keep it in sync and remove it, moving
+	// the nodes back to the worker pool, when it is unset or the NodeSwap is
+	// deleted.
+	// +optional
+	DedicatedPool *DedicatedPoolSpec `json:"dedicatedPool,omitempty"`
+
 	Swaps Swaps `json:"swaps,omitempty"`
+
+	// Oomd enables and tunes systemd-oomd on the selected nodes. systemd-oomd
+	// is left untouched when unset.
+	// +optional
+	Oomd *OomdSpec `json:"oomd,omitempty"`
+
 	// +optional
 	LogLevel *int32 `json:"logLevel,omitempty"`
 }
@@ -101,8 +193,16 @@ type NodeSwapStatus struct {
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
-
-// NodeSwap is the Schema for the nodeswaps API
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:unservedversion
+
+// NodeSwap is the Schema for the nodeswaps API.
+//
+// The v1alpha1 schema is frozen and no longer served: without a conversion
+// webhook, writing a NodeSwap as v1alpha1 would prune the fields only
+// v1beta1 has. NodeSwaps stored as v1alpha1 are read as v1beta1.
+//
+// Deprecated: use node-swap.openshift.io/v1beta1.
 type NodeSwap struct {
 	metav1.TypeMeta `json:",inline"`
 
@@ -25,6 +25,21 @@ import (
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DedicatedPoolSpec) DeepCopyInto(out *DedicatedPoolSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DedicatedPoolSpec.
+func (in *DedicatedPoolSpec) DeepCopy() *DedicatedPoolSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DedicatedPoolSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *NodeSwap) DeepCopyInto(out *NodeSwap) {
 	*out = *in
@@ -87,6 +102,26 @@ func (in *NodeSwapList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *NodeSwapSpec) DeepCopyInto(out *NodeSwapSpec) {
 	*out = *in
+	if in.PoolSelector != nil {
+		in, out := &in.PoolSelector, &out.PoolSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.MachineConfigPoolNames != nil {
+		in, out := &in.MachineConfigPoolNames, &out.MachineConfigPoolNames
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DedicatedPool != nil {
+		in, out := &in.DedicatedPool, &out.DedicatedPool
+		*out = new(DedicatedPoolSpec)
+		**out = **in
+	}
 	if in.Swaps != nil {
 		in, out := &in.Swaps, &out.Swaps
 		*out = make(Swaps, len(*in))
@@ -94,6 +129,11 @@ func (in *NodeSwapSpec) DeepCopyInto(out *NodeSwapSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Oomd != nil {
+		in, out := &in.Oomd, &out.Oomd
+		*out = new(OomdSpec)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.LogLevel != nil {
 		in, out := &in.LogLevel, &out.LogLevel
 		*out = new(int32)
@@ -133,6 +173,41 @@ func (in *NodeSwapStatus) DeepCopy() *NodeSwapStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OomdSlice) DeepCopyInto(out *OomdSlice) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OomdSlice.
+func (in *OomdSlice) DeepCopy() *OomdSlice {
+	if in == nil {
+		return nil
+	}
+	out := new(OomdSlice)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OomdSpec) DeepCopyInto(out *OomdSpec) {
+	*out = *in
+	if in.Slices != nil {
+		in, out := &in.Slices, &out.Slices
+		*out = make([]OomdSlice, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OomdSpec.
+func (in *OomdSpec) DeepCopy() *OomdSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(OomdSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Partition) DeepCopyInto(out *Partition) {
 	*out = *in
@@ -0,0 +1,644 @@
+/*
+Copyright The Swap Operator authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type SwapType string
+
+const (
+	FileBasedSwap SwapType = "file"
+	SwapOnZram    SwapType = "zram"
+	SwapOnDisk    SwapType = "disk"
+)
+
+type SwapFile struct {
+	Path string            `json:"path,omitempty"`
+	Size resource.Quantity `json:"size,omitempty"`
+}
+
+type Partition struct {
+	PartLabel string `json:"partlabel,omitempty"`
+}
+
+type SwapZram struct {
+	Size resource.Quantity `json:"size,omitempty"`
+}
+
+type SwapDisk struct {
+	SwapPartition Partition `json:"partition,omitempty"`
+}
+
+type SwapSpec struct {
+	Priority int32 `json:"priority,omitempty"`
+
+	SwapType SwapType `json:"swapType,omitempty"`
+
+	// +optional
+	Disk *SwapDisk `json:"disk,omitempty"`
+
+	// +optional
+	File *SwapFile `json:"file,omitempty"`
+
+	// +optional
+	Zram *SwapZram `json:"zram,omitempty"`
+}
+
+type Swaps []SwapSpec
+
+// DriftPolicy is what the operator does with out-of-band changes to the
+// MachineConfigs it owns.
+// +kubebuilder:validation:Enum=Revert;Report
+type DriftPolicy string
+
+const (
+	// DriftPolicyRevert restores the MachineConfigs.
+	DriftPolicyRevert DriftPolicy = "Revert"
+	// DriftPolicyReport leaves the MachineConfigs and raises the Drifted
+	// condition.
+	DriftPolicyReport DriftPolicy = "Report"
+)
+
+// ManagedOOMMode is the systemd-oomd policy applied to a slice.
+// +kubebuilder:validation:Enum=auto;kill
+type ManagedOOMMode string
+
+const (
+	ManagedOOMAuto ManagedOOMMode = "auto"
+	ManagedOOMKill ManagedOOMMode = "kill"
+)
+
+// OomdSlice configures systemd-oomd for a single systemd slice.
+type OomdSlice struct {
+	// Name of the systemd slice, for example "kubepods.slice".
+	// +kubebuilder:validation:Pattern=`^[a-zA-Z0-9:_.-]+\.slice$`
+	Name string `json:"name"`
+
+	// ManagedOOMSwap sets ManagedOOMSwap= on the slice. With "kill",
+	// systemd-oomd kills the cgroup using the most swap once the swap usage
+	// exceeds SwapUsedLimit.
+	// +optional
+	ManagedOOMSwap ManagedOOMMode `json:"managedOOMSwap,omitempty"`
+}
+
+// OomdSpec tunes systemd-oomd for swap pressure on the selected nodes.
+type OomdSpec struct {
+	// SwapUsedLimit is the swap usage, as a percentage, above which
+	// systemd-oomd acts on slices with ManagedOOMSwap=kill. Defaults to the
+	// systemd default when empty.
+	// +kubebuilder:validation:Pattern=`^[0-9]+(\.[0-9]+)?%$`
+	// +optional
+	SwapUsedLimit string `json:"swapUsedLimit,omitempty"`
+
+	// DefaultMemoryPressureLimit is the memory pressure, as a percentage,
+	// above which systemd-oomd acts on slices with
+	// ManagedOOMMemoryPressure=kill. Defaults to the systemd default when empty.
+	// +kubebuilder:validation:Pattern=`^[0-9]+(\.[0-9]+)?%$`
+	// +optional
+	DefaultMemoryPressureLimit string `json:"defaultMemoryPressureLimit,omitempty"`
+
+	// Slices lists the per-slice ManagedOOMSwap policies.
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	Slices []OomdSlice `json:"slices,omitempty"`
+}
+
+// DedicatedPoolSpec describes a MachineConfigPool created and owned by the
+// operator for the nodes selected by spec.nodeSelector.
+type DedicatedPoolSpec struct {
+	// Name of the MachineConfigPool. The pool inherits the worker
+	// MachineConfigs and the swap MachineConfigs are labeled with its role.
+	// +kubebuilder:validation:MaxLength=63
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	// +required
+	Name string `json:"name"`
+}
+
+// RolloutSpec stages the rollout of the MachineConfigs across the selected
+// MachineConfigPools. A canary rollout is a single stage with the canary
+// pool.
+type RolloutSpec struct {
+	// Stages lists, in order, the pools rolled out before the others. A
+	// stage starts once the pools of the previous ones are updated, healthy
+	// and soaked. The selected pools not listed form a last stage.
+	// +listType=atomic
+	// +kubebuilder:validation:MinItems=1
+	// +required
+	Stages []RolloutStage `json:"stages"`
+
+	// SoakTime is how long the pools of a stage must stay updated and
+	// healthy before the next stage starts.
+	// +optional
+	SoakTime *metav1.Duration `json:"soakTime,omitempty"`
+
+	// HealthChecks are checked, in addition to the pools being updated and
+	// not degraded, before the next stage starts.
+	// +optional
+	HealthChecks *RolloutHealthChecks `json:"healthChecks,omitempty"`
+}
+
+// DeletedPoolPolicy is what happens to the MachineConfigs of a NodeSwap
+// once the MachineConfigPools they were rolled out to are deleted.
+// +kubebuilder:validation:Enum=Retain;Delete
+type DeletedPoolPolicy string
+
+const (
+	// DeletedPoolPolicyRetain keeps the MachineConfigs, which apply again to
+	// a pool recreated with the same role.
+	DeletedPoolPolicyRetain DeletedPoolPolicy = "Retain"
+	// DeletedPoolPolicyDelete removes the MachineConfigs.
+	DeletedPoolPolicyDelete DeletedPoolPolicy = "Delete"
+)
+
+// NodeDisruptionAction is how the nodes apply changes to the files and
+// systemd units of the MachineConfigs of a NodeSwap.
+// +kubebuilder:validation:Enum=Reboot;Restart;Reload;None
+type NodeDisruptionAction string
+
+const (
+	// NodeDisruptionActionReboot drains and reboots the nodes.
+	NodeDisruptionActionReboot NodeDisruptionAction = "Reboot"
+	// NodeDisruptionActionRestart restarts the changed units and the
+	// services reading the changed files.
+	NodeDisruptionActionRestart NodeDisruptionAction = "Restart"
+	// NodeDisruptionActionReload restarts the changed units and reloads the
+	// services reading the changed files.
+	NodeDisruptionActionReload NodeDisruptionAction = "Reload"
+	// NodeDisruptionActionNone only writes the changes to the nodes.
+	NodeDisruptionActionNone NodeDisruptionAction = "None"
+)
+
+// MaintenanceWindow is a recurring period during which MachineConfig
+// changes may be written.
+type MaintenanceWindow struct {
+	// Schedule is a five field cron schedule, such as "0 2 * * 6", of the
+	// start of the window.
+	// +kubebuilder:validation:MinLength=1
+	// +required
+	Schedule string `json:"schedule"`
+
+	// Duration is how long the window stays open after it starts.
+	// +required
+	Duration metav1.Duration `json:"duration"`
+
+	// TimeZone is the IANA time zone of the schedule, UTC when unset.
+	// +optional
+	TimeZone string `json:"timeZone,omitempty"`
+}
+
+// RolloutStage is a set of MachineConfigPools rolled out together.
+type RolloutStage struct {
+	// Pools are the names of the selected MachineConfigPools of the stage.
+	// +listType=set
+	// +kubebuilder:validation:MinItems=1
+	// +required
+	Pools []string `json:"pools"`
+}
+
+// RolloutHealthChecks are the checks a stage passes before the next one.
+type RolloutHealthChecks struct {
+	// NodesReady requires all the nodes of the pools of the stage to be
+	// Ready.
+	// +optional
+	NodesReady bool `json:"nodesReady,omitempty"`
+}
+
+// NodeSwapSpec defines the desired state of NodeSwap
+// +kubebuilder:validation:XValidation:rule="!has(self.dedicatedPool) || has(self.nodeSelector)",message="dedicatedPool requires nodeSelector"
+// +kubebuilder:validation:XValidation:rule="!has(self.rollout) || !has(self.machineConfigPoolSelector) || has(self.poolSelector)",message="rollout cannot stage pools selected by machineConfigPoolSelector"
+type NodeSwapSpec struct {
+	// The following markers will use OpenAPI v3 schema to validate the value
+	// More info: https://book.kubebuilder.io/reference/markers/crd-validation.html
+
+	// Label selector for Machines on which swap will be deployed.
+	//
+	// Deprecated: use poolSelector. The "key:value" selector is matched
+	// against the machineConfigSelector match labels of the pools and is
+	// only used when poolSelector is not set.
+	// +optional
+	MachineConfigPoolSelector string `json:"machineConfigPoolSelector,omitempty"`
+
+	// PoolSelector selects, by their labels, the MachineConfigPools on which
+	// swap will be deployed. It takes precedence over machineConfigPoolSelector.
+	// The selected pools share one set of MachineConfigs, so pools selecting
+	// MachineConfigs of different roles are rejected as an invalid spec.
+	// +optional
+	PoolSelector *metav1.LabelSelector `json:"poolSelector,omitempty"`
+
+	// MachineConfigPoolNames lists, by name, MachineConfigPools on which swap
+	// will be deployed in addition to the selected ones.
+	// +listType=set
+	// +optional
+	MachineConfigPoolNames []string `json:"machineConfigPoolNames,omitempty"`
+
+	// NodeSelector selects the nodes on which swap will be deployed. Swap is
+	// deployed on the MachineConfigPools whose nodes are all selected; pools
+	// of which only some nodes are selected block the rollout.
+	// +optional
+	NodeSelector *metav1.LabelSelector `json:"nodeSelector,omitempty"`
+
+	// DedicatedPool makes the operator create a MachineConfigPool for the
+	// nodes selected by nodeSelector, keep it in sync and remove it, moving
+	// the nodes back to the worker pool, when it is unset or the NodeSwap is
+	// deleted.
+	// +optional
+	DedicatedPool *DedicatedPoolSpec `json:"dedicatedPool,omitempty"`
+
+	// Precedence decides which NodeSwap is rolled out when several select
+	// the same MachineConfigPool or render MachineConfigs with the same
+	// name: the one with the highest precedence wins, then the oldest one.
+	// The others report a Conflict condition and are left unapplied.
+	// +optional
+	Precedence int32 `json:"precedence,omitempty"`
+
+	// Rollout stages the rollout across the selected pools. They are all
+	// rolled out at once when it is unset.
+	// +optional
+	Rollout *RolloutSpec `json:"rollout,omitempty"`
+
+	// DeletedPoolPolicy is what happens to the MachineConfigs once every
+	// MachineConfigPool they were rolled out to is deleted and the NodeSwap
+	// selects no pool anymore. While some pools are still selected, the
+	// MachineConfigs follow them.
+	// +kubebuilder:default=Retain
+	// +optional
+	DeletedPoolPolicy DeletedPoolPolicy `json:"deletedPoolPolicy,omitempty"`
+
+	// Paused pauses the selected MachineConfigPools, so that changes to the
+	// NodeSwap are rendered but not rolled out to the nodes until it is
+	// unset. Only the pools paused by the NodeSwap are resumed.
+	// +optional
+	Paused bool `json:"paused,omitempty"`
+
+	// AutoRollback restores the MachineConfigs of the last spec rolled out
+	// to every selected pool when a change drives one of the pools Degraded.
+	// The change is not retried until the spec changes again.
+	// +optional
+	AutoRollback bool `json:"autoRollback,omitempty"`
+
+	// RevisionHistoryLimit is the number of ControllerRevisions of the
+	// MachineConfigs rolled out for the NodeSwap to keep.
+	// +kubebuilder:default=10
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	RevisionHistoryLimit *int32 `json:"revisionHistoryLimit,omitempty"`
+
+	// RollbackToRevision is the name of a ControllerRevision of the NodeSwap
+	// whose MachineConfigs are applied instead of the ones rendered from the
+	// spec.
+	// +optional
+	RollbackToRevision string `json:"rollbackToRevision,omitempty"`
+
+	// DryRun renders the MachineConfigs and reports in status.dryRun what
+	// would be created, updated and deleted, without writing any
+	// MachineConfig or MachineConfigPool.
+	// +optional
+	DryRun bool `json:"dryRun,omitempty"`
+
+	// NodeDisruptionAction is how the nodes apply changes to the files and
+	// systemd units of the swap and systemd-oomd MachineConfigs. Except for
+	// Reboot, the operator manages their entries in the nodeDisruptionPolicy
+	// of the cluster MachineConfiguration, so that the nodes are not drained.
+	// Changes to files no known service reads, to kernel arguments and to
+	// the kubelet configuration still reboot the nodes.
+	// +kubebuilder:default=Reboot
+	// +optional
+	NodeDisruptionAction NodeDisruptionAction `json:"nodeDisruptionAction,omitempty"`
+
+	// MaintenanceWindows restrict when MachineConfigs are created, updated
+	// and deleted. Outside of them the changes are rendered and reported in
+	// status.pendingChanges. Changes are written at any time when unset.
+	// +listType=atomic
+	// +optional
+	MaintenanceWindows []MaintenanceWindow `json:"maintenanceWindows,omitempty"`
+
+	// DriftPolicy is what the operator does when a MachineConfig it owns is
+	// edited out of band: Revert restores it, Report leaves it as is, even
+	// through changes to the NodeSwap, and raises the Drifted condition.
+	// +kubebuilder:default=Revert
+	// +optional
+	DriftPolicy DriftPolicy `json:"driftPolicy,omitempty"`
+
+	Swaps Swaps `json:"swaps,omitempty"`
+
+	// Oomd enables and tunes systemd-oomd on the selected nodes. systemd-oomd
+	// is left untouched when unset.
+	// +optional
+	Oomd *OomdSpec `json:"oomd,omitempty"`
+
+	// +optional
+	LogLevel *int32 `json:"logLevel,omitempty"`
+}
+
+// NodeSwapStatus defines the observed state of NodeSwap.
+type NodeSwapStatus struct {
+	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
+	// Important: Run "make" to regenerate code after modifying this file
+
+	// For Kubernetes API conventions, see:
+	// https://github.com/kubernetes/community/blob/master/contributors/devel/sig-architecture/api-conventions.md#typical-status-properties
+
+	// conditions represent the current state of the NodeSwap resource.
+	// Each condition has a unique type and reflects the status of a specific aspect of the resource.
+	//
+	// Standard condition types include:
+	// - "Available": the resource is fully functional
+	// - "Progressing": the resource is being created or updated
+	// - "Degraded": the resource failed to reach or maintain its desired state
+	//
+	// The status of each condition is one of True, False, or Unknown.
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// observedGeneration is the generation of the spec last reconciled.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// pools reports the rollout on each selected MachineConfigPool.
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	Pools []PoolStatus `json:"pools,omitempty"`
+
+	// machineConfigs lists the MachineConfigs rendered for the NodeSwap.
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	MachineConfigs []RenderedMachineConfig `json:"machineConfigs,omitempty"`
+
+	// machineCount is the number of machines in the selected pools.
+	// +optional
+	MachineCount int32 `json:"machineCount,omitempty"`
+
+	// updatedMachineCount is the number of machines running a rendered
+	// configuration that includes the MachineConfigs of the NodeSwap.
+	// +optional
+	UpdatedMachineCount int32 `json:"updatedMachineCount,omitempty"`
+
+	// degradedMachineCount is the number of degraded machines in the
+	// selected pools.
+	// +optional
+	DegradedMachineCount int32 `json:"degradedMachineCount,omitempty"`
+
+	// nodes summarizes the rollout on the nodes of the selected pools, as
+	// reported by their MachineConfigNodes. It is not reported on clusters
+	// without MachineConfigNodes.
+	// +optional
+	Nodes NodeRolloutStatus `json:"nodes,omitempty,omitzero"`
+
+	// pausedPools lists the MachineConfigPools paused by the NodeSwap.
+	// +listType=set
+	// +optional
+	PausedPools []string `json:"pausedPools,omitempty"`
+
+	// lastAppliedSpec is the last spec rolled out to every selected pool,
+	// which autoRollback restores.
+	// +optional
+	LastAppliedSpec *NodeSwapSpec `json:"lastAppliedSpec,omitempty"`
+
+	// rollback records the last automatic rollback.
+	// +optional
+	Rollback *RollbackStatus `json:"rollback,omitempty"`
+
+	// currentRevision is the ControllerRevision of the MachineConfigs last
+	// rolled out to every selected pool.
+	// +optional
+	CurrentRevision string `json:"currentRevision,omitempty"`
+
+	// dryRun reports the changes spec.dryRun held back.
+	// +optional
+	DryRun *DryRunStatus `json:"dryRun,omitempty"`
+
+	// nodeDisruptionPolicy lists the entries of the nodeDisruptionPolicy of
+	// the cluster MachineConfiguration managed for the NodeSwap.
+	// +optional
+	NodeDisruptionPolicy *NodeDisruptionPolicyStatus `json:"nodeDisruptionPolicy,omitempty"`
+
+	// pendingChanges reports the changes held back until the next
+	// maintenance window.
+	// +optional
+	PendingChanges *DryRunStatus `json:"pendingChanges,omitempty"`
+
+	// maintenanceWindow is the maintenance window in progress, or the next
+	// one.
+	// +optional
+	MaintenanceWindow *MaintenanceWindowStatus `json:"maintenanceWindow,omitempty"`
+}
+
+// NodeDisruptionPolicyStatus lists nodeDisruptionPolicy entries.
+type NodeDisruptionPolicyStatus struct {
+	// files are the paths of the file entries.
+	// +listType=set
+	// +optional
+	Files []string `json:"files,omitempty"`
+
+	// units are the names of the systemd unit entries.
+	// +listType=set
+	// +optional
+	Units []string `json:"units,omitempty"`
+}
+
+// MaintenanceWindowStatus reports a maintenance window.
+type MaintenanceWindowStatus struct {
+	// open reports whether the window is in progress.
+	// +optional
+	Open bool `json:"open,omitempty"`
+
+	// start is when the window starts.
+	// +required
+	Start metav1.Time `json:"start"`
+
+	// end is when the window ends.
+	// +required
+	End metav1.Time `json:"end"`
+}
+
+// DryRunStatus reports the MachineConfigs a NodeSwap would write without
+// spec.dryRun or outside of a maintenance window.
+type DryRunStatus struct {
+	// configMap is the ConfigMap, in the namespace of the operator, holding
+	// the rendered MachineConfigs.
+	// +optional
+	ConfigMap string `json:"configMap,omitempty"`
+
+	// create lists the MachineConfigs that would be created.
+	// +listType=set
+	// +optional
+	Create []string `json:"create,omitempty"`
+
+	// update lists the MachineConfigs that would be updated.
+	// +listType=set
+	// +optional
+	Update []string `json:"update,omitempty"`
+
+	// delete lists the MachineConfigs that would be deleted.
+	// +listType=set
+	// +optional
+	Delete []string `json:"delete,omitempty"`
+
+	// createMachineConfigPool is the dedicated MachineConfigPool that would
+	// be created.
+	// +optional
+	CreateMachineConfigPool string `json:"createMachineConfigPool,omitempty"`
+}
+
+// RollbackStatus records an automatic rollback of a NodeSwap.
+type RollbackStatus struct {
+	// generation is the generation of the spec that was rolled back.
+	// +required
+	Generation int64 `json:"generation"`
+
+	// machineConfigPool is the pool the change drove Degraded.
+	// +required
+	MachineConfigPool string `json:"machineConfigPool"`
+
+	// node is the failing node, when known.
+	// +optional
+	Node string `json:"node,omitempty"`
+
+	// reason the node or the pool fails.
+	// +optional
+	Reason string `json:"reason,omitempty"`
+
+	// message explaining the failure.
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// time of the rollback.
+	// +required
+	Time metav1.Time `json:"time"`
+}
+
+// NodeRolloutStatus summarizes the rollout of a NodeSwap on the nodes.
+type NodeRolloutStatus struct {
+	// applied lists the nodes running a configuration that includes the
+	// MachineConfigs of the NodeSwap.
+	// +listType=set
+	// +optional
+	Applied []string `json:"applied,omitempty"`
+
+	// updating lists the nodes updating to a configuration that includes
+	// the MachineConfigs of the NodeSwap.
+	// +listType=set
+	// +optional
+	Updating []string `json:"updating,omitempty"`
+
+	// failed lists the nodes failing to update.
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	Failed []NodeFailure `json:"failed,omitempty"`
+}
+
+// NodeFailure reports why a node fails to update.
+type NodeFailure struct {
+	// name of the node.
+	// +required
+	Name string `json:"name"`
+
+	// reason of the NodeDegraded condition of the MachineConfigNode.
+	// +optional
+	Reason string `json:"reason,omitempty"`
+
+	// message of the NodeDegraded condition of the MachineConfigNode.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// PoolStatus reports the rollout of a NodeSwap on a MachineConfigPool.
+type PoolStatus struct {
+	// name of the MachineConfigPool.
+	// +required
+	Name string `json:"name"`
+
+	// renderedConfig is the rendered configuration the pool rolls out.
+	// +optional
+	RenderedConfig string `json:"renderedConfig,omitempty"`
+
+	// machineConfigsRendered tells whether renderedConfig includes all the
+	// MachineConfigs of the NodeSwap.
+	// +optional
+	MachineConfigsRendered bool `json:"machineConfigsRendered"`
+
+	// machineCount is the number of machines in the pool.
+	// +optional
+	MachineCount int32 `json:"machineCount"`
+
+	// updatedMachineCount is the number of machines running renderedConfig.
+	// +optional
+	UpdatedMachineCount int32 `json:"updatedMachineCount"`
+
+	// degradedMachineCount is the number of degraded machines in the pool.
+	// +optional
+	DegradedMachineCount int32 `json:"degradedMachineCount"`
+}
+
+// RenderedMachineConfig is a MachineConfig rendered for a NodeSwap.
+type RenderedMachineConfig struct {
+	// name of the MachineConfig.
+	// +required
+	Name string `json:"name"`
+
+	// hash is the SHA-256 of the MachineConfig spec.
+	// +required
+	Hash string `json:"hash"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="Machines",type=integer,JSONPath=".status.machineCount"
+// +kubebuilder:printcolumn:name="Updated",type=integer,JSONPath=".status.updatedMachineCount"
+// +kubebuilder:printcolumn:name="Degraded",type=integer,JSONPath=".status.degradedMachineCount"
+// +kubebuilder:printcolumn:name="Available",type=string,JSONPath=".status.conditions[?(@.type==\"Available\")].status"
+// +kubebuilder:printcolumn:name="Progressing",type=string,JSONPath=".status.conditions[?(@.type==\"Progressing\")].status"
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=".metadata.creationTimestamp"
+// +kubebuilder:storageversion
+
+// NodeSwap is the Schema for the nodeswaps API. It is cluster scoped, like
+// the MachineConfigs and MachineConfigPools it manages.
+type NodeSwap struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// +optional
+	metav1.ObjectMeta `json:"metadata,omitempty,omitzero"`
+
+	// +required
+	Spec NodeSwapSpec `json:"spec"`
+
+	// +optional
+	Status NodeSwapStatus `json:"status,omitempty,omitzero"`
+}
+
+// +kubebuilder:object:root=true
+
+// NodeSwapList contains a list of NodeSwap
+type NodeSwapList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []NodeSwap `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&NodeSwap{}, &NodeSwapList{})
+}
@@ -20,15 +20,22 @@ import (
 	"crypto/tls"
 	"flag"
 	"os"
+	// Embed the time zone database, the maintenance windows of the
+	// NodeSwaps are evaluated in their time zone and the base image has none.
+	_ "time/tzdata"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	"sigs.k8s.io/controller-runtime/pkg/metrics/filters"
@@ -36,8 +43,11 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	nodeswapv1alpha1 "github.com/openshift-virtualization/swap-operator/api/v1alpha1"
+	nodeswapv1beta1 "github.com/openshift-virtualization/swap-operator/api/v1beta1"
 	"github.com/openshift-virtualization/swap-operator/internal/controller"
+	configv1 "github.com/openshift/api/config/v1"
 	mcfgv1 "github.com/openshift/api/machineconfiguration/v1"
+	operatorv1 "github.com/openshift/api/operator/v1"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -52,8 +62,13 @@ func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 
 	utilruntime.Must(nodeswapv1alpha1.AddToScheme(scheme))
+	utilruntime.Must(nodeswapv1beta1.AddToScheme(scheme))
 
 	utilruntime.Must(mcfgv1.AddToScheme(scheme))
+
+	utilruntime.Must(configv1.AddToScheme(scheme))
+
+	utilruntime.Must(operatorv1.AddToScheme(scheme))
 	// +kubebuilder:scaffold:scheme
 }
 
@@ -66,6 +81,7 @@ func main() {
 	var probeAddr string
 	var secureMetrics bool
 	var enableHTTP2 bool
+	var maxConcurrentReconciles int
 	var tlsOpts []func(*tls.Config)
 	flag.StringVar(&metricsAddr, "metrics-bind-address", "0", "The address the metrics endpoint binds to. "+
 		"Use :8443 for HTTPS or :8080 for HTTP, or leave as 0 to disable the metrics service.")
@@ -85,6 +101,8 @@ func main() {
 	flag.StringVar(&metricsCertKey, "metrics-cert-key", "tls.key", "The name of the metrics server key file.")
 	flag.BoolVar(&enableHTTP2, "enable-http2", false,
 		"If set, HTTP/2 will be enabled for the metrics and webhook servers")
+	flag.IntVar(&maxConcurrentReconciles, "max-concurrent-reconciles", 1,
+		"The maximum number of NodeSwaps reconciled concurrently.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -166,8 +184,23 @@ func main() {
 		metricsServerOptions.KeyName = metricsCertKey
 	}
 
+	// The revision history and the dry runs of the NodeSwaps are stored in
+	// the namespace of the operator, only that namespace is cached.
+	namespace := os.Getenv("POD_NAMESPACE")
+	cacheOptions := cache.Options{}
+	if namespace != "" {
+		cacheOptions.ByObject = map[client.Object]cache.ByObject{
+			&appsv1.ControllerRevision{}: {Namespaces: map[string]cache.Config{namespace: {}}},
+			&corev1.ConfigMap{}:          {Namespaces: map[string]cache.Config{namespace: {}}},
+		}
+	} else {
+		setupLog.Info("POD_NAMESPACE is not set, the revision history of the NodeSwaps is disabled " +
+			"and dry runs are only reported in their status")
+	}
+
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme:                 scheme,
+		Cache:                  cacheOptions,
 		Metrics:                metricsServerOptions,
 		WebhookServer:          webhookServer,
 		HealthProbeBindAddress: probeAddr,
@@ -194,6 +227,10 @@ func main() {
 		Client:      mgr.GetClient(),
 		Scheme:      mgr.GetScheme(),
 		TemplateDir: templateDir, // Add this line
+
+		MaxConcurrentReconciles: maxConcurrentReconciles,
+		Namespace:               namespace,
+		Recorder:                mgr.GetEventRecorderFor("nodeswap-controller"),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "NodeSwap")
 		os.Exit(1)
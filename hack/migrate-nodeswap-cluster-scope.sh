@@ -0,0 +1,61 @@
+#!/usr/bin/env bash
+#
+# Migrates namespaced NodeSwaps to the cluster scoped NodeSwap CRD.
+#
+# The scope of a CRD cannot be changed in place, so this script saves the
+# NodeSwaps, replaces the CRD and recreates them as cluster scoped v1beta1
+# objects. The operator is stopped and the finalizers are dropped first so
+# that deleting the namespaced objects leaves the MachineConfigs, and swap on
+# the nodes, untouched.
+#
+# Usage: hack/migrate-nodeswap-cluster-scope.sh [CRD manifest]
+
+set -euo pipefail
+
+OC=${OC:-oc}
+OPERATOR_NAMESPACE=${OPERATOR_NAMESPACE:-swap-operator}
+OPERATOR_DEPLOYMENT=${OPERATOR_DEPLOYMENT:-swap-operator-controller-manager}
+CRD_NAME=nodeswaps.node-swap.openshift.io
+CRD_MANIFEST=${1:-config/crd/bases/node-swap.openshift.io_nodeswaps.yaml}
+BACKUP=${BACKUP:-nodeswaps-$(date +%Y%m%d%H%M%S).json}
+
+scope=$(${OC} get crd "${CRD_NAME}" -o jsonpath='{.spec.scope}')
+if [ "${scope}" = "Cluster" ]; then
+	echo "${CRD_NAME} is already cluster scoped, nothing to migrate"
+	exit 0
+fi
+
+echo "Saving NodeSwaps to ${BACKUP}"
+${OC} get "${CRD_NAME}" --all-namespaces -o json >"${BACKUP}"
+
+duplicates=$(jq -r '[.items[].metadata.name] | group_by(.) | map(select(length > 1) | .[0]) | .[]' "${BACKUP}")
+if [ -n "${duplicates}" ]; then
+	echo "NodeSwap names must be unique once cluster scoped, rename these first:" >&2
+	echo "${duplicates}" >&2
+	exit 1
+fi
+
+replicas=$(${OC} get deployment -n "${OPERATOR_NAMESPACE}" "${OPERATOR_DEPLOYMENT}" -o jsonpath='{.spec.replicas}')
+echo "Stopping the operator"
+${OC} scale deployment -n "${OPERATOR_NAMESPACE}" "${OPERATOR_DEPLOYMENT}" --replicas=0
+${OC} wait pod -n "${OPERATOR_NAMESPACE}" -l control-plane=controller-manager --for=delete --timeout=5m
+
+echo "Releasing the namespaced NodeSwaps"
+jq -r '.items[] | "\(.metadata.namespace) \(.metadata.name)"' "${BACKUP}" | while read -r namespace name; do
+	${OC} patch "${CRD_NAME}" -n "${namespace}" "${name}" --type=merge -p '{"metadata":{"finalizers":null}}'
+done
+
+echo "Replacing ${CRD_NAME}"
+${OC} delete crd "${CRD_NAME}" --wait
+${OC} apply -f "${CRD_MANIFEST}"
+${OC} wait crd "${CRD_NAME}" --for=condition=Established --timeout=1m
+
+echo "Recreating the NodeSwaps as cluster scoped objects"
+jq '.items[]
+	| .apiVersion = "node-swap.openshift.io/v1beta1"
+	| del(.metadata.namespace, .metadata.uid, .metadata.resourceVersion,
+		.metadata.generation, .metadata.creationTimestamp, .metadata.managedFields,
+		.metadata.finalizers, .status)' "${BACKUP}" | ${OC} create -f -
+
+echo "Restarting the operator"
+${OC} scale deployment -n "${OPERATOR_NAMESPACE}" "${OPERATOR_DEPLOYMENT}" --replicas="${replicas}"
@@ -0,0 +1,258 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"slices"
+	"time"
+
+	mcfgv1 "github.com/openshift/api/machineconfiguration/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	nodeswap "github.com/openshift-virtualization/swap-operator/api/v1beta1"
+	"github.com/openshift-virtualization/swap-operator/internal/renderconfig"
+)
+
+const (
+	// nodeSwapFinalizer holds the NodeSwap until its MachineConfigs are
+	// removed from the nodes and its dedicated pool is torn down.
+	nodeSwapFinalizer = "node-swap.openshift.io/finalizer"
+
+	// cleanupRequeueInterval is how often the pools are checked while they
+	// roll out the removal of the MachineConfigs.
+	cleanupRequeueInterval = 30 * time.Second
+)
+
+// ensureFinalizer adds the NodeSwap finalizer before anything is rolled out.
+func (r *NodeSwapReconciler) ensureFinalizer(s *reconcileState) error {
+	if !controllerutil.AddFinalizer(&s.desiredNodeSwap, nodeSwapFinalizer) {
+		return nil
+	}
+	if err := r.Update(s.ctx, &s.desiredNodeSwap); err != nil {
+		logf.FromContext(s.ctx).Error(err, "Failed to add finalizer")
+		return err
+	}
+
+	return nil
+}
+
+// removeFinalizer removes the NodeSwap finalizer if it is set.
+func (r *NodeSwapReconciler) removeFinalizer(s *reconcileState) error {
+	if !controllerutil.RemoveFinalizer(&s.desiredNodeSwap, nodeSwapFinalizer) {
+		return nil
+	}
+	if err := r.Update(s.ctx, &s.desiredNodeSwap); err != nil {
+		logf.FromContext(s.ctx).Error(err, "Failed to remove finalizer")
+		return err
+	}
+
+	return nil
+}
+
+// ReconcileDeletion removes the MachineConfigs of a NodeSwap being deleted,
+// waits for the pools to roll the removal out, tears down its dedicated
+// pool and then releases the finalizer.
+func (r *NodeSwapReconciler) ReconcileDeletion(s *reconcileState) (ctrl.Result, error) {
+	if !controllerutil.ContainsFinalizer(&s.desiredNodeSwap, nodeSwapFinalizer) {
+		return ctrl.Result{}, nil
+	}
+
+	// Paused pools would never roll the removal out.
+	if err := r.pausePools(s, nil); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// Removing swap reboots the nodes, whatever spec.nodeDisruptionAction.
+	if err := r.updateNodeDisruptionPolicy(s, nil, nil); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	names, err := r.releasedMachineConfigNames(s)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	for _, name := range names {
+		if err := r.deleteMachineConfig(s, name); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	settling, err := r.poolsSettling(s, names)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if settling {
+		return ctrl.Result{RequeueAfter: cleanupRequeueInterval}, nil
+	}
+
+	// Nodes leave the dedicated pool only once swap is removed from them,
+	// so the worker pool does not have to roll anything out.
+	pending, err := r.releaseOwnedPools(s, "")
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if pending {
+		return ctrl.Result{RequeueAfter: dedicatedPoolRequeueInterval}, nil
+	}
+
+	logf.FromContext(s.ctx).Info("NodeSwap cleanup complete, removing finalizer")
+	return ctrl.Result{}, r.removeFinalizer(s)
+}
+
+// ReconcileNoMatchingPools handles a NodeSwap selecting no
+// MachineConfigPool. When the pools it was rolled out to were deleted, their
+// MachineConfigs are removed with spec.deletedPoolPolicy Delete. It returns a
+// noMatchingPoolsError: the NodeSwap is reconciled again once a pool is
+// created.
+func (r *NodeSwapReconciler) ReconcileNoMatchingPools(s *reconcileState, pools []mcfgv1.MachineConfigPool) error {
+	err := &noMatchingPoolsError{deleted: deletedPools(s.desiredNodeSwap.Status.Pools, pools)}
+	if len(err.deleted) == 0 || s.desiredNodeSwap.Spec.DeletedPoolPolicy != nodeswap.DeletedPoolPolicyDelete ||
+		s.hold != "" {
+		return err
+	}
+
+	if policyErr := r.updateNodeDisruptionPolicy(s, nil, nil); policyErr != nil {
+		return policyErr
+	}
+	names, namesErr := r.releasedMachineConfigNames(s)
+	if namesErr != nil {
+		return namesErr
+	}
+	for _, name := range names {
+		if deleteErr := r.deleteMachineConfig(s, name); deleteErr != nil {
+			return deleteErr
+		}
+	}
+	logf.FromContext(s.ctx).Info("Removed the MachineConfigs of the deleted MachineConfigPools", "pools", err.deleted)
+	err.removed = true
+
+	return err
+}
+
+// releasedMachineConfigNames returns the MachineConfigs the NodeSwap may
+// have created that no other NodeSwap needs.
+func (r *NodeSwapReconciler) releasedMachineConfigNames(s *reconcileState) ([]string, error) {
+	names := []string{
+		renderconfig.OomdMachineConfigName(s.desiredNodeSwap.Name),
+		renderconfig.KubeletMachineConfigName(s.desiredNodeSwap.Name),
+		renderconfig.SwapKubeletCgroupsMCPrefix,
+	}
+	// The MachineConfigs may have been rolled back to the last applied spec
+	// or to a revision.
+	names = append(names, machineConfigNames(s.desiredNodeSwap.Status.MachineConfigs)...)
+	for _, spec := range []*nodeswap.NodeSwapSpec{&s.desiredNodeSwap.Spec, s.desiredNodeSwap.Status.LastAppliedSpec} {
+		if spec == nil {
+			continue
+		}
+		if configs, err := renderconfig.Create(s.desiredNodeSwap.Name, spec); err == nil {
+			for i := range configs {
+				names = append(names, configs[i].Name)
+			}
+		} else {
+			// Nothing was rendered for a spec that cannot be rendered.
+			logf.FromContext(s.ctx).Info("Unable to determine swap MachineConfigs", "error", err.Error())
+		}
+	}
+
+	nodeSwaps := &nodeswap.NodeSwapList{}
+	if err := r.List(s.ctx, nodeSwaps); err != nil {
+		logf.FromContext(s.ctx).Error(err, "Failed to list NodeSwaps")
+		return nil, err
+	}
+
+	needed := sets.New[string]()
+	for i := range nodeSwaps.Items {
+		other := &nodeSwaps.Items[i]
+		if other.Name == s.desiredNodeSwap.Name || !other.DeletionTimestamp.IsZero() {
+			continue
+		}
+		needed.Insert(neededMachineConfigNames(other.Name, renderedSpec(other))...)
+		// The kubelet MachineConfig shared before it included the NodeSwap
+		// name is needed until every NodeSwap has released it.
+		if slices.Contains(machineConfigNames(other.Status.MachineConfigs), renderconfig.SwapKubeletCgroupsMCPrefix) {
+			needed.Insert(renderconfig.SwapKubeletCgroupsMCPrefix)
+		}
+	}
+
+	return slices.DeleteFunc(names, needed.Has), nil
+}
+
+// neededMachineConfigNames returns the MachineConfigs rendered for the spec
+// of the named NodeSwap.
+func neededMachineConfigNames(nodeSwapName string, spec *nodeswap.NodeSwapSpec) []string {
+	names := []string{renderconfig.KubeletMachineConfigName(nodeSwapName)}
+	if configs, err := renderconfig.Create(nodeSwapName, spec); err == nil {
+		for i := range configs {
+			names = append(names, configs[i].Name)
+		}
+	}
+	if oomd, err := renderconfig.CreateOomd(nodeSwapName, spec); err == nil && oomd != nil {
+		names = append(names, oomd.Name)
+	}
+
+	return names
+}
+
+// poolsSettling reports whether a pool still renders one of the named
+// MachineConfigs or, for the pools of the NodeSwap, is rolling out a new
+// configuration. Updates of unrelated pools do not hold the NodeSwap back.
+func (r *NodeSwapReconciler) poolsSettling(s *reconcileState, names []string) (bool, error) {
+	mcpList := &mcfgv1.MachineConfigPoolList{}
+	if err := r.List(s.ctx, mcpList); err != nil {
+		logf.FromContext(s.ctx).Error(err, "Failed to list MachineConfigPools")
+		return false, err
+	}
+
+	for i := range mcpList.Items {
+		mcp := &mcpList.Items[i]
+		owned := slices.ContainsFunc(s.desiredNodeSwap.Status.Pools, func(pool nodeswap.PoolStatus) bool {
+			return pool.Name == mcp.Name
+		})
+		if referencesMachineConfigs(mcp.Spec.Configuration, names) ||
+			referencesMachineConfigs(mcp.Status.Configuration, names) ||
+			(owned && isMachineConfigPoolUpdating(mcp)) {
+			logf.FromContext(s.ctx).Info("Waiting for MachineConfigPool to settle", "name", mcp.Name)
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// referencesMachineConfigs reports whether the rendered configuration is
+// made of one of the named MachineConfigs.
+func referencesMachineConfigs(config mcfgv1.MachineConfigPoolStatusConfiguration, names []string) bool {
+	return slices.ContainsFunc(config.Source, func(source corev1.ObjectReference) bool {
+		return slices.Contains(names, source.Name)
+	})
+}
+
+// isMachineConfigPoolUpdating reports whether the pool is rolling out a
+// new configuration.
+func isMachineConfigPoolUpdating(mcp *mcfgv1.MachineConfigPool) bool {
+	for _, condition := range mcp.Status.Conditions {
+		if condition.Type == mcfgv1.MachineConfigPoolUpdating {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,162 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	mcfgv1 "github.com/openshift/api/machineconfiguration/v1"
+	corev1 "k8s.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	nodeswap "github.com/openshift-virtualization/swap-operator/api/v1beta1"
+)
+
+// conflictRequeueInterval is how often a NodeSwap losing a conflict checks
+// whether the conflict is resolved.
+const conflictRequeueInterval = time.Minute
+
+// conflictError reports that another NodeSwap takes precedence over the
+// reconciled one for a pool or a MachineConfig. Like prerequisitesError it
+// blocks the rollout without being a reconciliation failure.
+type conflictError struct {
+	other  string
+	reason string
+}
+
+func (e *conflictError) Error() string {
+	return fmt.Sprintf("conflicts with NodeSwap %s, which takes precedence: %s", e.other, e.reason)
+}
+
+// ReconcileConflicts makes sure no other NodeSwap taking precedence selects
+// the same pools. It runs before anything is written.
+func (r *NodeSwapReconciler) ReconcileConflicts(s *reconcileState) (ctrl.Result, error) {
+	nodeSwaps := &nodeswap.NodeSwapList{}
+	if err := r.List(s.ctx, nodeSwaps); err != nil {
+		logf.FromContext(s.ctx).Error(err, "Failed to list NodeSwaps")
+		return ctrl.Result{}, err
+	}
+
+	var others []*nodeswap.NodeSwap
+	needNodes := s.desiredNodeSwap.Spec.NodeSelector != nil
+	for i := range nodeSwaps.Items {
+		other := &nodeSwaps.Items[i]
+		if other.Name == s.desiredNodeSwap.Name || !other.DeletionTimestamp.IsZero() {
+			continue
+		}
+		others = append(others, other)
+		needNodes = needNodes || other.Spec.NodeSelector != nil
+	}
+	if len(others) == 0 {
+		return ctrl.Result{}, nil
+	}
+
+	mcpList := &mcfgv1.MachineConfigPoolList{}
+	if err := r.List(s.ctx, mcpList); err != nil {
+		logf.FromContext(s.ctx).Error(err, "Failed to list MachineConfigPools")
+		return ctrl.Result{}, err
+	}
+
+	var nodes []corev1.Node
+	if needNodes {
+		nodeList := &corev1.NodeList{}
+		if err := r.List(s.ctx, nodeList); err != nil {
+			logf.FromContext(s.ctx).Error(err, "Failed to list Nodes")
+			return ctrl.Result{}, err
+		}
+		nodes = nodeList.Items
+	}
+
+	if err := findConflict(&s.desiredNodeSwap, others, mcpList.Items, nodes); err != nil {
+		logf.FromContext(s.ctx).Info("NodeSwap conflicts with another one", "other", err.other, "reason", err.reason)
+		return ctrl.Result{RequeueAfter: conflictRequeueInterval}, err
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// findConflict returns the conflict with the first of others that takes
+// precedence over ns, if any. Two NodeSwaps conflict when they select a
+// common pool; the names of their MachineConfigs include the NodeSwap name,
+// so NodeSwaps of disjoint pools never render the same MachineConfig, except
+// the shared kubelet one.
+func findConflict(ns *nodeswap.NodeSwap, others []*nodeswap.NodeSwap,
+	pools []mcfgv1.MachineConfigPool, nodes []corev1.Node) *conflictError {
+	ownPools := selectedPoolNames(&ns.Spec, pools, nodes)
+
+	slices.SortFunc(others, func(a, b *nodeswap.NodeSwap) int {
+		if takesPrecedence(a, b) {
+			return -1
+		}
+		return 1
+	})
+
+	for _, other := range others {
+		if takesPrecedence(ns, other) {
+			continue
+		}
+
+		otherPools := selectedPoolNames(&other.Spec, pools, nodes)
+		if i := slices.IndexFunc(ownPools, func(name string) bool { return slices.Contains(otherPools, name) }); i >= 0 {
+			return &conflictError{
+				other:  other.Name,
+				reason: fmt.Sprintf("both select MachineConfigPool %s", ownPools[i]),
+			}
+		}
+	}
+
+	return nil
+}
+
+// takesPrecedence reports whether a wins over b: the highest precedence
+// wins, then the oldest NodeSwap, then the name for a deterministic order.
+func takesPrecedence(a, b *nodeswap.NodeSwap) bool {
+	if a.Spec.Precedence != b.Spec.Precedence {
+		return a.Spec.Precedence > b.Spec.Precedence
+	}
+	if !a.CreationTimestamp.Equal(&b.CreationTimestamp) {
+		return a.CreationTimestamp.Before(&b.CreationTimestamp)
+	}
+	return a.Name < b.Name
+}
+
+// selectedPoolNames returns the names of the pools spec selects. A spec
+// that cannot be resolved selects nothing; its own reconciliation reports
+// why.
+func selectedPoolNames(spec *nodeswap.NodeSwapSpec, pools []mcfgv1.MachineConfigPool, nodes []corev1.Node) []string {
+	selector, err := newPoolSelector(spec)
+	if err != nil {
+		return nil
+	}
+	selection, err := selectPools(spec, selector, pools, nodes)
+	if err != nil {
+		return nil
+	}
+
+	names := make([]string, 0, len(selection.Pools))
+	for _, mcp := range selection.Pools {
+		names = append(names, mcp.Name)
+	}
+	if spec.DedicatedPool != nil && !slices.Contains(names, spec.DedicatedPool.Name) {
+		names = append(names, spec.DedicatedPool.Name)
+	}
+
+	return names
+}
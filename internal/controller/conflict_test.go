@@ -0,0 +1,130 @@
+package controller
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	mcfgv1 "github.com/openshift/api/machineconfiguration/v1"
+	apiresource "k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	nodeswap "github.com/openshift-virtualization/swap-operator/api/v1beta1"
+)
+
+func TestFindConflict(t *testing.T) {
+	created := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	newNodeSwap := func(name string, age time.Duration, precedence int32, pools []string, swaps nodeswap.Swaps) *nodeswap.NodeSwap {
+		return &nodeswap.NodeSwap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              name,
+				CreationTimestamp: metav1.NewTime(created.Add(-age)),
+			},
+			Spec: nodeswap.NodeSwapSpec{
+				MachineConfigPoolNames: pools,
+				Precedence:             precedence,
+				Swaps:                  swaps,
+			},
+		}
+	}
+	fileSwap := nodeswap.Swaps{{
+		SwapType: nodeswap.FileBasedSwap,
+		File:     &nodeswap.SwapFile{Path: "/var/swap", Size: apiresource.MustParse("1Gi")},
+	}}
+	zramSwap := nodeswap.Swaps{{
+		SwapType: nodeswap.SwapOnZram,
+		Zram:     &nodeswap.SwapZram{Size: apiresource.MustParse("1Gi")},
+	}}
+	pools := []mcfgv1.MachineConfigPool{
+		*newTestPool("worker", nil, map[string]string{"role": "worker"}),
+		*newTestPool("infra", nil, map[string]string{"role": "infra"}),
+	}
+
+	tests := []struct {
+		name       string
+		ns         *nodeswap.NodeSwap
+		others     []*nodeswap.NodeSwap
+		wantOther  string
+		wantReason string
+	}{
+		{
+			name: "disjoint pools and MachineConfigs",
+			ns:   newNodeSwap("a", 0, 0, []string{"worker"}, fileSwap),
+			others: []*nodeswap.NodeSwap{
+				newNodeSwap("b", time.Hour, 0, []string{"infra"}, zramSwap),
+			},
+		},
+		{
+			name: "older NodeSwap selecting the same pool wins",
+			ns:   newNodeSwap("a", 0, 0, []string{"worker"}, zramSwap),
+			others: []*nodeswap.NodeSwap{
+				newNodeSwap("b", time.Hour, 0, []string{"worker", "infra"}, nil),
+			},
+			wantOther:  "b",
+			wantReason: "MachineConfigPool worker",
+		},
+		{
+			name: "the oldest NodeSwap does not conflict",
+			ns:   newNodeSwap("a", time.Hour, 0, []string{"worker"}, nil),
+			others: []*nodeswap.NodeSwap{
+				newNodeSwap("b", 0, 0, []string{"worker"}, nil),
+			},
+		},
+		{
+			name: "higher precedence wins over age",
+			ns:   newNodeSwap("a", time.Hour, 0, []string{"worker"}, nil),
+			others: []*nodeswap.NodeSwap{
+				newNodeSwap("b", 0, 10, []string{"worker"}, nil),
+			},
+			wantOther:  "b",
+			wantReason: "MachineConfigPool worker",
+		},
+		{
+			name: "same swaps on disjoint pools",
+			ns:   newNodeSwap("a", 0, 0, []string{"infra"}, fileSwap),
+			others: []*nodeswap.NodeSwap{
+				newNodeSwap("b", time.Hour, 0, []string{"worker"}, fileSwap),
+			},
+		},
+		{
+			name: "name breaks ties",
+			ns:   newNodeSwap("b", 0, 0, []string{"worker"}, nil),
+			others: []*nodeswap.NodeSwap{
+				newNodeSwap("a", 0, 0, []string{"worker"}, nil),
+			},
+			wantOther:  "a",
+			wantReason: "MachineConfigPool worker",
+		},
+		{
+			name: "reports the winner with the highest precedence",
+			ns:   newNodeSwap("a", 0, 0, []string{"worker"}, nil),
+			others: []*nodeswap.NodeSwap{
+				newNodeSwap("b", time.Hour, 1, []string{"worker"}, nil),
+				newNodeSwap("c", time.Hour, 5, []string{"worker"}, nil),
+			},
+			wantOther:  "c",
+			wantReason: "MachineConfigPool worker",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conflict := findConflict(tt.ns, tt.others, pools, nil)
+			if tt.wantOther == "" {
+				if conflict != nil {
+					t.Fatalf("unexpected conflict: %v", conflict)
+				}
+				return
+			}
+			if conflict == nil {
+				t.Fatal("expected a conflict, got nil")
+			}
+			if conflict.other != tt.wantOther {
+				t.Errorf("conflict with %s, want %s", conflict.other, tt.wantOther)
+			}
+			if !strings.Contains(conflict.reason, tt.wantReason) {
+				t.Errorf("conflict reason %q does not contain %q", conflict.reason, tt.wantReason)
+			}
+		})
+	}
+}
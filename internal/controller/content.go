@@ -0,0 +1,175 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"slices"
+
+	ign3types "github.com/coreos/ignition/v2/config/v3_5/types"
+	mcfgv1 "github.com/openshift/api/machineconfiguration/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/openshift-virtualization/swap-operator/internal/common"
+)
+
+// ReconcileRenderedContent finds the selected pools whose rendered
+// configuration includes the current content of the MachineConfigs of the
+// NodeSwap.
+func (r *NodeSwapReconciler) ReconcileRenderedContent(s *reconcileState) error {
+	var mcs []*mcfgv1.MachineConfig
+	for _, name := range machineConfigNames(s.machineConfigs) {
+		mc := &mcfgv1.MachineConfig{}
+		if err := r.Get(s.ctx, types.NamespacedName{Name: name}, mc); err != nil {
+			if apierrors.IsNotFound(err) {
+				// Not created yet, so not rendered either.
+				s.renderedPools = sets.New[string]()
+				return nil
+			}
+			logf.FromContext(s.ctx).Error(err, "Failed to get MachineConfig", "name", name)
+			return err
+		}
+		mcs = append(mcs, mc)
+	}
+
+	renderedPools, err := r.renderedContent(s, s.pools, mcs)
+	if err != nil {
+		return err
+	}
+	s.renderedPools = renderedPools
+
+	return nil
+}
+
+// renderedContent returns the pools whose rendered configuration includes
+// the content of the MachineConfigs. The names alone do not tell: a
+// MachineConfig updated in place keeps its name, and the pools keep
+// rendering its previous content until the machine-config operator renders
+// a new configuration.
+func (r *NodeSwapReconciler) renderedContent(s *reconcileState, pools []*mcfgv1.MachineConfigPool,
+	mcs []*mcfgv1.MachineConfig) (sets.Set[string], error) {
+	names := make([]string, 0, len(mcs))
+	for _, mc := range mcs {
+		names = append(names, mc.Name)
+	}
+
+	rendered := sets.New[string]()
+	for _, mcp := range pools {
+		if !includesMachineConfigs(mcp.Spec.Configuration, names) {
+			continue
+		}
+		config := &mcfgv1.MachineConfig{}
+		if err := r.Get(s.ctx, types.NamespacedName{Name: mcp.Spec.Configuration.Name}, config); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			logf.FromContext(s.ctx).Error(err, "Failed to get rendered MachineConfig", "name", mcp.Spec.Configuration.Name)
+			return nil, err
+		}
+		included, err := includesContent(config, mcs)
+		if err != nil {
+			logf.FromContext(s.ctx).Error(err, "Failed to compare rendered MachineConfig", "name", config.Name)
+			return nil, err
+		}
+		if included {
+			rendered.Insert(mcp.Name)
+		}
+	}
+
+	return rendered, nil
+}
+
+// includesContent reports whether the rendered MachineConfig carries the
+// files, systemd units and kernel arguments of all the MachineConfigs.
+func includesContent(rendered *mcfgv1.MachineConfig, mcs []*mcfgv1.MachineConfig) (bool, error) {
+	var config ign3types.Config
+	if len(rendered.Spec.Config.Raw) > 0 {
+		var err error
+		if config, err = common.ParseAndConvertConfig(rendered.Spec.Config.Raw); err != nil {
+			return false, fmt.Errorf("invalid Ignition config of MachineConfig %s: %w", rendered.Name, err)
+		}
+	}
+
+	for _, mc := range mcs {
+		if slices.ContainsFunc(mc.Spec.KernelArguments, func(arg string) bool {
+			return !slices.Contains(rendered.Spec.KernelArguments, arg)
+		}) {
+			return false, nil
+		}
+		if len(mc.Spec.Config.Raw) == 0 {
+			continue
+		}
+		own, err := common.ParseAndConvertConfig(mc.Spec.Config.Raw)
+		if err != nil {
+			return false, fmt.Errorf("invalid Ignition config of MachineConfig %s: %w", mc.Name, err)
+		}
+		for _, file := range own.Storage.Files {
+			if !slices.ContainsFunc(config.Storage.Files, func(other ign3types.File) bool {
+				return other.Path == file.Path && equality.Semantic.DeepEqual(other.Contents, file.Contents) &&
+					equality.Semantic.DeepEqual(other.Mode, file.Mode)
+			}) {
+				return false, nil
+			}
+		}
+		for _, unit := range own.Systemd.Units {
+			if !slices.ContainsFunc(config.Systemd.Units, func(other ign3types.Unit) bool {
+				return includesUnit(other, unit)
+			}) {
+				return false, nil
+			}
+		}
+	}
+
+	return true, nil
+}
+
+// includesUnit reports whether the rendered unit carries the contents, the
+// enablement and the dropins of the unit. Units only adding dropins leave the
+// contents and the enablement to other MachineConfigs, and the MCO merges the
+// dropins of all the MachineConfigs into the rendered unit.
+func includesUnit(rendered, unit ign3types.Unit) bool {
+	if rendered.Name != unit.Name ||
+		(unit.Contents != nil && !equality.Semantic.DeepEqual(rendered.Contents, unit.Contents)) ||
+		(unit.Enabled != nil && !equality.Semantic.DeepEqual(rendered.Enabled, unit.Enabled)) {
+		return false
+	}
+
+	return !slices.ContainsFunc(unit.Dropins, func(dropin ign3types.Dropin) bool {
+		return !slices.ContainsFunc(rendered.Dropins, func(other ign3types.Dropin) bool {
+			return other.Name == dropin.Name && equality.Semantic.DeepEqual(other.Contents, dropin.Contents)
+		})
+	})
+}
+
+// rolledOut reports whether all the machines of the pool run a rendered
+// configuration with the current content of the named MachineConfigs:
+// the configuration of its spec includes that content and the pool is
+// updated to it.
+func rolledOut(mcp *mcfgv1.MachineConfigPool, names []string, rendered sets.Set[string]) bool {
+	if !rendered.Has(mcp.Name) || mcp.Status.Configuration.Name != mcp.Spec.Configuration.Name ||
+		!includesMachineConfigs(mcp.Status.Configuration, names) {
+		return false
+	}
+	updated := findPoolCondition(mcp, mcfgv1.MachineConfigPoolUpdated)
+
+	return updated != nil && updated.Status == corev1.ConditionTrue
+}
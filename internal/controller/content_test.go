@@ -0,0 +1,142 @@
+package controller
+
+import (
+	"testing"
+
+	mcfgv1 "github.com/openshift/api/machineconfiguration/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+func newContentMachineConfig(name, config string, kernelArguments ...string) *mcfgv1.MachineConfig {
+	return &mcfgv1.MachineConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: mcfgv1.MachineConfigSpec{
+			Config:          runtime.RawExtension{Raw: []byte(config)},
+			KernelArguments: kernelArguments,
+		},
+	}
+}
+
+func TestIncludesContent(t *testing.T) {
+	swap := newContentMachineConfig("99-filebased-swap-worker-0", `{"ignition":{"version":"3.4.0"},`+
+		`"systemd":{"units":[{"name":"swap-file.service","enabled":true,"contents":"size=1G"}]}}`)
+	oomd := newContentMachineConfig("99-swap-oomd-worker", `{"ignition":{"version":"3.4.0"},`+
+		`"storage":{"files":[{"path":"/etc/systemd/oomd.conf.d/99-swap.conf","contents":{"source":"data:,a"}}]},`+
+		`"systemd":{"units":[{"name":"system.slice","dropins":[{"name":"99-swap-oomd.conf","contents":"swap=1"}]}]}}`,
+		"systemd.unified_cgroup_hierarchy=1")
+
+	tests := []struct {
+		name     string
+		rendered *mcfgv1.MachineConfig
+		want     bool
+	}{
+		{
+			name: "current content",
+			rendered: newContentMachineConfig("rendered-worker-2", `{"ignition":{"version":"3.4.0"},`+
+				`"storage":{"files":[{"path":"/etc/systemd/oomd.conf.d/99-swap.conf","contents":{"source":"data:,a"}},`+
+				`{"path":"/etc/other","contents":{"source":"data:,b"}}]},`+
+				`"systemd":{"units":[{"name":"swap-file.service","enabled":true,"contents":"size=1G"},{"name":"other.service"},`+
+				`{"name":"system.slice","contents":"[Slice]","dropins":[{"name":"10-other.conf","contents":"other=1"},`+
+				`{"name":"99-swap-oomd.conf","contents":"swap=1"}]}]}}`,
+				"quiet", "systemd.unified_cgroup_hierarchy=1"),
+			want: true,
+		},
+		{
+			name: "previous content of a unit",
+			rendered: newContentMachineConfig("rendered-worker-1", `{"ignition":{"version":"3.4.0"},`+
+				`"storage":{"files":[{"path":"/etc/systemd/oomd.conf.d/99-swap.conf","contents":{"source":"data:,a"}}]},`+
+				`"systemd":{"units":[{"name":"swap-file.service","enabled":true,"contents":"size=2G"},`+
+				`{"name":"system.slice","dropins":[{"name":"99-swap-oomd.conf","contents":"swap=1"}]}]}}`,
+				"systemd.unified_cgroup_hierarchy=1"),
+		},
+		{
+			name: "missing file",
+			rendered: newContentMachineConfig("rendered-worker-1", `{"ignition":{"version":"3.4.0"},`+
+				`"systemd":{"units":[{"name":"swap-file.service","enabled":true,"contents":"size=1G"},`+
+				`{"name":"system.slice","dropins":[{"name":"99-swap-oomd.conf","contents":"swap=1"}]}]}}`,
+				"systemd.unified_cgroup_hierarchy=1"),
+		},
+		{
+			name: "missing dropin",
+			rendered: newContentMachineConfig("rendered-worker-1", `{"ignition":{"version":"3.4.0"},`+
+				`"storage":{"files":[{"path":"/etc/systemd/oomd.conf.d/99-swap.conf","contents":{"source":"data:,a"}}]},`+
+				`"systemd":{"units":[{"name":"swap-file.service","enabled":true,"contents":"size=1G"},`+
+				`{"name":"system.slice","dropins":[{"name":"10-other.conf","contents":"other=1"}]}]}}`,
+				"systemd.unified_cgroup_hierarchy=1"),
+		},
+		{
+			name: "missing kernel argument",
+			rendered: newContentMachineConfig("rendered-worker-1", `{"ignition":{"version":"3.4.0"},`+
+				`"storage":{"files":[{"path":"/etc/systemd/oomd.conf.d/99-swap.conf","contents":{"source":"data:,a"}}]},`+
+				`"systemd":{"units":[{"name":"swap-file.service","enabled":true,"contents":"size=1G"},`+
+				`{"name":"system.slice","dropins":[{"name":"99-swap-oomd.conf","contents":"swap=1"}]}]}}`),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := includesContent(tt.rendered, []*mcfgv1.MachineConfig{swap, oomd})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("includesContent() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRolledOut(t *testing.T) {
+	names := []string{"99-filebased-swap-worker-0"}
+	pool := func(spec, status string, updated corev1.ConditionStatus) *mcfgv1.MachineConfigPool {
+		mcp := &mcfgv1.MachineConfigPool{ObjectMeta: metav1.ObjectMeta{Name: "worker"}}
+		mcp.Spec.Configuration.Name = spec
+		mcp.Status.Configuration.Name = status
+		mcp.Status.Configuration.Source = []corev1.ObjectReference{{Name: names[0]}}
+		mcp.Status.Conditions = []mcfgv1.MachineConfigPoolCondition{{
+			Type:   mcfgv1.MachineConfigPoolUpdated,
+			Status: updated,
+		}}
+		return mcp
+	}
+
+	tests := []struct {
+		name     string
+		mcp      *mcfgv1.MachineConfigPool
+		rendered sets.Set[string]
+		want     bool
+	}{
+		{
+			name:     "updated to the current content",
+			mcp:      pool("rendered-worker-2", "rendered-worker-2", corev1.ConditionTrue),
+			rendered: sets.New("worker"),
+			want:     true,
+		},
+		{
+			name:     "current content not rendered yet",
+			mcp:      pool("rendered-worker-1", "rendered-worker-1", corev1.ConditionTrue),
+			rendered: sets.New[string](),
+		},
+		{
+			name:     "machines updating",
+			mcp:      pool("rendered-worker-2", "rendered-worker-1", corev1.ConditionFalse),
+			rendered: sets.New("worker"),
+		},
+		{
+			name:     "pool not updated",
+			mcp:      pool("rendered-worker-2", "rendered-worker-2", corev1.ConditionFalse),
+			rendered: sets.New("worker"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rolledOut(tt.mcp, names, tt.rendered); got != tt.want {
+				t.Fatalf("rolledOut() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
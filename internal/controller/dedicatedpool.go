@@ -0,0 +1,205 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"maps"
+	"time"
+
+	mcfgv1 "github.com/openshift/api/machineconfiguration/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// machineConfigRoleLabel is the label pools select their MachineConfigs by.
+	machineConfigRoleLabel = "machineconfiguration.openshift.io/role"
+	// poolNameLabelPrefix is the prefix of the label the machine-config
+	// operator sets on its pools; custom pools conventionally carry it too.
+	poolNameLabelPrefix = "pools.operator.machineconfiguration.openshift.io/"
+
+	// dedicatedPoolRequeueInterval is how often the teardown of a dedicated
+	// pool is checked while its nodes move back to the worker pool.
+	dedicatedPoolRequeueInterval = 30 * time.Second
+)
+
+// ReconcileDedicatedPool creates or updates the MachineConfigPool requested
+// by spec.dedicatedPool and tears down the pools the NodeSwap owns but no
+// longer requests.
+func (r *NodeSwapReconciler) ReconcileDedicatedPool(s *reconcileState) (ctrl.Result, error) {
+	keep := ""
+	if s.desiredNodeSwap.Spec.DedicatedPool != nil {
+		keep = s.desiredNodeSwap.Spec.DedicatedPool.Name
+	}
+
+	pending, err := r.releaseOwnedPools(s, keep)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	result := ctrl.Result{}
+	if pending {
+		result.RequeueAfter = dedicatedPoolRequeueInterval
+	}
+
+	if s.desiredNodeSwap.Spec.DedicatedPool == nil {
+		return result, nil
+	}
+
+	pool := r.desiredDedicatedPool(s)
+	if err := controllerutil.SetControllerReference(&s.desiredNodeSwap, pool, r.Scheme); err != nil {
+		logf.FromContext(s.ctx).Error(err, "Failed to set controller reference", "name", pool.Name)
+		return ctrl.Result{}, err
+	}
+
+	return result, r.applyDedicatedPool(s, pool)
+}
+
+// releaseOwnedPools tears down the pools owned by the NodeSwap except keep.
+// It returns true while some of them are still being torn down.
+func (r *NodeSwapReconciler) releaseOwnedPools(s *reconcileState, keep string) (bool, error) {
+	ownedPools := &mcfgv1.MachineConfigPoolList{}
+	if err := r.List(s.ctx, ownedPools, client.MatchingLabels(ownerLabels(s))); err != nil {
+		logf.FromContext(s.ctx).Error(err, "Failed to list owned MachineConfigPools")
+		return false, err
+	}
+
+	pending := false
+	for i := range ownedPools.Items {
+		mcp := &ownedPools.Items[i]
+		if mcp.Name == keep {
+			continue
+		}
+		done, err := r.teardownDedicatedPool(s, mcp)
+		if err != nil {
+			return false, err
+		}
+		pending = pending || !done
+	}
+
+	return pending, nil
+}
+
+// desiredDedicatedPool returns the pool requested by spec.dedicatedPool. It
+// inherits the worker MachineConfigs and selects its own role, which the
+// swap MachineConfigs are labeled with.
+func (r *NodeSwapReconciler) desiredDedicatedPool(s *reconcileState) *mcfgv1.MachineConfigPool {
+	name := s.desiredNodeSwap.Spec.DedicatedPool.Name
+
+	poolLabels := ownerLabels(s)
+	poolLabels[poolNameLabelPrefix+name] = ""
+
+	return &mcfgv1.MachineConfigPool{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: poolLabels,
+		},
+		Spec: mcfgv1.MachineConfigPoolSpec{
+			MachineConfigSelector: &metav1.LabelSelector{
+				MatchExpressions: []metav1.LabelSelectorRequirement{{
+					Key:      machineConfigRoleLabel,
+					Operator: metav1.LabelSelectorOpIn,
+					Values:   []string{workerPoolName, name},
+				}},
+			},
+			NodeSelector: s.desiredNodeSwap.Spec.NodeSelector.DeepCopy(),
+		},
+	}
+}
+
+// applyDedicatedPool creates the pool, or updates its selectors and labels
+// when they differ from the desired ones. A pool with the same name that is
+// not owned by the NodeSwap is never taken over.
+func (r *NodeSwapReconciler) applyDedicatedPool(s *reconcileState, desired *mcfgv1.MachineConfigPool) error {
+	existing := &mcfgv1.MachineConfigPool{}
+	if err := r.Get(s.ctx, types.NamespacedName{Name: desired.Name}, existing); err != nil {
+		if !apierrors.IsNotFound(err) {
+			logf.FromContext(s.ctx).Error(err, "Failed to get MachineConfigPool", "name", desired.Name)
+			return err
+		}
+		if err := r.Create(s.ctx, desired); err != nil {
+			logf.FromContext(s.ctx).Error(err, "Failed to create MachineConfigPool", "name", desired.Name)
+			return err
+		}
+		logf.FromContext(s.ctx).Info("Created dedicated MachineConfigPool", "name", desired.Name)
+		return nil
+	}
+
+	if existing.Labels[ownerNameLabel] != s.desiredNodeSwap.Name {
+		return fmt.Errorf("MachineConfigPool %s already exists and is not owned by NodeSwap %s",
+			desired.Name, s.desiredNodeSwap.Name)
+	}
+
+	labels := maps.Clone(existing.Labels)
+	maps.Copy(labels, desired.Labels)
+
+	if equality.Semantic.DeepEqual(existing.Spec.MachineConfigSelector, desired.Spec.MachineConfigSelector) &&
+		equality.Semantic.DeepEqual(existing.Spec.NodeSelector, desired.Spec.NodeSelector) &&
+		equality.Semantic.DeepEqual(existing.OwnerReferences, desired.OwnerReferences) &&
+		maps.Equal(existing.Labels, labels) {
+		return nil
+	}
+
+	existing.Labels = labels
+	existing.OwnerReferences = desired.OwnerReferences
+	existing.Spec.MachineConfigSelector = desired.Spec.MachineConfigSelector
+	existing.Spec.NodeSelector = desired.Spec.NodeSelector
+	if err := r.Update(s.ctx, existing); err != nil {
+		logf.FromContext(s.ctx).Error(err, "Failed to update MachineConfigPool", "name", desired.Name)
+		return err
+	}
+	logf.FromContext(s.ctx).Info("Updated dedicated MachineConfigPool", "name", desired.Name)
+
+	return nil
+}
+
+// teardownDedicatedPool removes an owned pool without leaving nodes behind:
+// it first clears the node selector so that the nodes move back to the
+// worker pool, and deletes the pool once it has no machines left. It
+// returns true when the pool is gone.
+func (r *NodeSwapReconciler) teardownDedicatedPool(s *reconcileState, mcp *mcfgv1.MachineConfigPool) (bool, error) {
+	if mcp.Spec.NodeSelector != nil {
+		mcp.Spec.NodeSelector = nil
+		if err := r.Update(s.ctx, mcp); err != nil {
+			logf.FromContext(s.ctx).Error(err, "Failed to release nodes of MachineConfigPool", "name", mcp.Name)
+			return false, err
+		}
+		logf.FromContext(s.ctx).Info("Releasing nodes of dedicated MachineConfigPool", "name", mcp.Name)
+		return false, nil
+	}
+
+	if mcp.Status.ObservedGeneration < mcp.Generation || mcp.Status.MachineCount > 0 {
+		logf.FromContext(s.ctx).Info("Waiting for nodes to leave dedicated MachineConfigPool",
+			"name", mcp.Name, "machineCount", mcp.Status.MachineCount)
+		return false, nil
+	}
+
+	if err := r.Delete(s.ctx, mcp); client.IgnoreNotFound(err) != nil {
+		logf.FromContext(s.ctx).Error(err, "Failed to delete MachineConfigPool", "name", mcp.Name)
+		return false, err
+	}
+	logf.FromContext(s.ctx).Info("Deleted dedicated MachineConfigPool", "name", mcp.Name)
+
+	return true, nil
+}
@@ -0,0 +1,330 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"encoding/json"
+	goerrors "errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	mcfgv1 "github.com/openshift/api/machineconfiguration/v1"
+	operatorv1 "github.com/openshift/api/operator/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	nodeswap "github.com/openshift-virtualization/swap-operator/api/v1beta1"
+	"github.com/openshift-virtualization/swap-operator/internal/renderconfig"
+)
+
+const (
+	// machineConfigurationName is the name of the cluster MachineConfiguration.
+	machineConfigurationName = "cluster"
+	// nodeDisruptionPolicyAnnotation records on the cluster
+	// MachineConfiguration the nodeDisruptionPolicy entries managed for each
+	// NodeSwap, as a JSON object keyed by NodeSwap name.
+	nodeDisruptionPolicyAnnotation = "node-swap.openshift.io/node-disruption-policy"
+)
+
+// fileServices are the services reading the files of the MachineConfigs,
+// by path prefix.
+var fileServices = map[string]string{
+	"/etc/systemd/oomd.conf.d/": "systemd-oomd.service",
+}
+
+// ReconcileNodeDisruptionPolicy updates the nodeDisruptionPolicy of the
+// cluster MachineConfiguration for the files and units of the MachineConfigs
+// before they are written, so that the change is applied as
+// spec.nodeDisruptionAction says. Clusters without MachineConfiguration
+// reboot the nodes as before.
+func (r *NodeSwapReconciler) ReconcileNodeDisruptionPolicy(s *reconcileState, mcs []*mcfgv1.MachineConfig) error {
+	files, units, err := desiredNodeDisruptionPolicy(s.desiredNodeSwap.Spec.NodeDisruptionAction, mcs)
+	if err != nil {
+		logf.FromContext(s.ctx).Error(err, "Failed to determine node disruption policy")
+		return err
+	}
+
+	return r.updateNodeDisruptionPolicy(s, files, units)
+}
+
+// updateNodeDisruptionPolicy sets the entries of the NodeSwap in the
+// nodeDisruptionPolicy of the cluster MachineConfiguration and removes the
+// ones it no longer needs.
+func (r *NodeSwapReconciler) updateNodeDisruptionPolicy(s *reconcileState, files []operatorv1.NodeDisruptionPolicySpecFile,
+	units []operatorv1.NodeDisruptionPolicySpecUnit) error {
+	s.nodeDisruptionPolicy = nil
+
+	machineConfiguration := &operatorv1.MachineConfiguration{}
+	if err := r.Get(s.ctx, types.NamespacedName{Name: machineConfigurationName}, machineConfiguration); err != nil {
+		if meta.IsNoMatchError(err) || apierrors.IsNotFound(err) {
+			if len(files)+len(units) > 0 {
+				logf.FromContext(s.ctx).Info("No cluster MachineConfiguration, changes reboot the nodes")
+			}
+			return nil
+		}
+		logf.FromContext(s.ctx).Error(err, "Failed to get MachineConfiguration")
+		return err
+	}
+
+	managed, changed, err := mergeNodeDisruptionPolicy(machineConfiguration, s.desiredNodeSwap.Name, files, units)
+	var conflictErr *conflictError
+	if goerrors.As(err, &conflictErr) {
+		logf.FromContext(s.ctx).Info("Node disruption policy conflicts with another NodeSwap",
+			"nodeSwap", conflictErr.other, "reason", conflictErr.reason)
+		return err
+	} else if err != nil {
+		logf.FromContext(s.ctx).Error(err, "Failed to merge node disruption policy")
+		return err
+	}
+	if changed {
+		if err := r.Update(s.ctx, machineConfiguration); err != nil {
+			logf.FromContext(s.ctx).Error(err, "Failed to update MachineConfiguration node disruption policy")
+			return err
+		}
+		logf.FromContext(s.ctx).Info("Updated node disruption policy", "files", managed.Files, "units", managed.Units)
+	}
+	if len(managed.Files)+len(managed.Units) > 0 {
+		s.nodeDisruptionPolicy = managed
+	}
+
+	return nil
+}
+
+// desiredNodeDisruptionPolicy returns the nodeDisruptionPolicy entries of
+// the files and units of the MachineConfigs for the action. Files no known
+// service reads get no entry, and neither do the kubelet MachineConfigs.
+func desiredNodeDisruptionPolicy(action nodeswap.NodeDisruptionAction, mcs []*mcfgv1.MachineConfig) (
+	[]operatorv1.NodeDisruptionPolicySpecFile, []operatorv1.NodeDisruptionPolicySpecUnit, error) {
+	if action == "" || action == nodeswap.NodeDisruptionActionReboot {
+		return nil, nil, nil
+	}
+
+	keys := map[string]bool{}
+	for _, mc := range mcs {
+		if strings.HasPrefix(mc.Name, renderconfig.SwapKubeletCgroupsMCPrefix) {
+			continue
+		}
+		entries, err := ignitionEntries(mc.Spec.Config.Raw)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to parse MachineConfig %s: %w", mc.Name, err)
+		}
+		for key := range entries {
+			keys[key] = true
+		}
+	}
+
+	var files []operatorv1.NodeDisruptionPolicySpecFile
+	var units []operatorv1.NodeDisruptionPolicySpecUnit
+	for _, key := range slices.Sorted(maps.Keys(keys)) {
+		if path, ok := strings.CutPrefix(key, "file "); ok {
+			if service := fileService(path); service != "" {
+				files = append(files, operatorv1.NodeDisruptionPolicySpecFile{
+					Path:    path,
+					Actions: fileActions(action, service),
+				})
+			}
+		} else if name, ok := strings.CutPrefix(key, "unit "); ok {
+			units = append(units, operatorv1.NodeDisruptionPolicySpecUnit{
+				Name:    operatorv1.NodeDisruptionPolicyServiceName(name),
+				Actions: unitActions(action, name),
+			})
+		}
+	}
+
+	return files, units, nil
+}
+
+// fileService returns the service reading the file, if known.
+func fileService(path string) string {
+	for prefix, service := range fileServices {
+		if strings.HasPrefix(path, prefix) {
+			return service
+		}
+	}
+	return ""
+}
+
+func fileActions(action nodeswap.NodeDisruptionAction, service string) []operatorv1.NodeDisruptionPolicySpecAction {
+	name := operatorv1.NodeDisruptionPolicyServiceName(service)
+	switch action {
+	case nodeswap.NodeDisruptionActionRestart:
+		return []operatorv1.NodeDisruptionPolicySpecAction{{
+			Type:    operatorv1.RestartSpecAction,
+			Restart: &operatorv1.RestartService{ServiceName: name},
+		}}
+	case nodeswap.NodeDisruptionActionReload:
+		return []operatorv1.NodeDisruptionPolicySpecAction{{
+			Type:   operatorv1.ReloadSpecAction,
+			Reload: &operatorv1.ReloadService{ServiceName: name},
+		}}
+	default:
+		return []operatorv1.NodeDisruptionPolicySpecAction{{Type: operatorv1.NoneSpecAction}}
+	}
+}
+
+// unitActions returns the actions for a change to a unit: systemd reloads
+// its definition and, but for slices, restarts it. Units cannot reload their
+// own definition, so Reload restarts them as well.
+func unitActions(action nodeswap.NodeDisruptionAction, unit string) []operatorv1.NodeDisruptionPolicySpecAction {
+	if action == nodeswap.NodeDisruptionActionNone {
+		return []operatorv1.NodeDisruptionPolicySpecAction{{Type: operatorv1.NoneSpecAction}}
+	}
+
+	actions := []operatorv1.NodeDisruptionPolicySpecAction{{Type: operatorv1.DaemonReloadSpecAction}}
+	if !strings.HasSuffix(unit, ".slice") {
+		actions = append(actions, operatorv1.NodeDisruptionPolicySpecAction{
+			Type:    operatorv1.RestartSpecAction,
+			Restart: &operatorv1.RestartService{ServiceName: operatorv1.NodeDisruptionPolicyServiceName(unit)},
+		})
+	}
+
+	return actions
+}
+
+// mergeNodeDisruptionPolicy sets the entries of the owner NodeSwap in the
+// nodeDisruptionPolicy of the MachineConfiguration and removes the ones it
+// managed and no longer needs. Entries set by an administrator are left
+// alone. NodeSwaps share the entries of the units and files they have in
+// common, such as slices, as long as they agree on the actions; the entry
+// of another NodeSwap with other actions is a conflictError. It returns the
+// entries managed for the owner and whether the MachineConfiguration
+// changed.
+func mergeNodeDisruptionPolicy(machineConfiguration *operatorv1.MachineConfiguration, owner string,
+	files []operatorv1.NodeDisruptionPolicySpecFile, units []operatorv1.NodeDisruptionPolicySpecUnit) (
+	*nodeswap.NodeDisruptionPolicyStatus, bool, error) {
+	owners := map[string]nodeswap.NodeDisruptionPolicyStatus{}
+	last := machineConfiguration.Annotations[nodeDisruptionPolicyAnnotation]
+	if last != "" {
+		if err := json.Unmarshal([]byte(last), &owners); err != nil {
+			return nil, false, fmt.Errorf("invalid %s annotation: %w", nodeDisruptionPolicyAnnotation, err)
+		}
+	}
+	previous := owners[owner]
+	otherFiles, otherUnits := map[string]string{}, map[string]string{}
+	for _, name := range slices.Sorted(maps.Keys(owners)) {
+		if name == owner {
+			continue
+		}
+		for _, path := range owners[name].Files {
+			if _, ok := otherFiles[path]; !ok {
+				otherFiles[path] = name
+			}
+		}
+		for _, unit := range owners[name].Units {
+			if _, ok := otherUnits[unit]; !ok {
+				otherUnits[unit] = name
+			}
+		}
+	}
+
+	policy := &machineConfiguration.Spec.NodeDisruptionPolicy
+	managed := &nodeswap.NodeDisruptionPolicyStatus{}
+	policyFiles, managedFiles, filesChanged, conflict := mergePolicyEntries(slices.Clone(policy.Files), files,
+		previous.Files, otherFiles, func(file operatorv1.NodeDisruptionPolicySpecFile) string { return file.Path })
+	if conflict != "" {
+		return nil, false, &conflictError{
+			other:  otherFiles[conflict],
+			reason: fmt.Sprintf("its nodeDisruptionPolicy entry of file %s has other actions", conflict),
+		}
+	}
+	policyUnits, managedUnits, unitsChanged, conflict := mergePolicyEntries(slices.Clone(policy.Units), units,
+		previous.Units, otherUnits, func(unit operatorv1.NodeDisruptionPolicySpecUnit) string { return string(unit.Name) })
+	if conflict != "" {
+		return nil, false, &conflictError{
+			other:  otherUnits[conflict],
+			reason: fmt.Sprintf("its nodeDisruptionPolicy entry of unit %s has other actions", conflict),
+		}
+	}
+	policy.Files, managed.Files = policyFiles, managedFiles
+	policy.Units, managed.Units = policyUnits, managedUnits
+
+	if len(managed.Files)+len(managed.Units) > 0 {
+		owners[owner] = *managed
+	} else {
+		delete(owners, owner)
+	}
+	annotation := ""
+	if len(owners) > 0 {
+		raw, err := json.Marshal(owners)
+		if err != nil {
+			return nil, false, err
+		}
+		annotation = string(raw)
+	}
+	if annotation != last {
+		if annotation == "" {
+			delete(machineConfiguration.Annotations, nodeDisruptionPolicyAnnotation)
+		} else {
+			if machineConfiguration.Annotations == nil {
+				machineConfiguration.Annotations = map[string]string{}
+			}
+			machineConfiguration.Annotations[nodeDisruptionPolicyAnnotation] = annotation
+		}
+	}
+
+	return managed, filesChanged || unitsChanged || annotation != last, nil
+}
+
+// mergePolicyEntries removes the previously managed entries that are not
+// desired anymore and that no other NodeSwap of others, keyed by entry,
+// manages. It adds the desired entries, or updates them when managed by no
+// other NodeSwap. It returns the entries, the keys of the managed ones,
+// whether they changed and the key of the first desired entry another
+// NodeSwap manages with other actions, if any.
+func mergePolicyEntries[T any](entries, desired []T, previous []string, others map[string]string,
+	key func(T) string) ([]T, []string, bool, string) {
+	changed := false
+	entries = slices.DeleteFunc(entries, func(entry T) bool {
+		_, shared := others[key(entry)]
+		stale := slices.Contains(previous, key(entry)) && !shared &&
+			!slices.ContainsFunc(desired, func(d T) bool { return key(d) == key(entry) })
+		changed = changed || stale
+		return stale
+	})
+
+	var managed []string
+	for _, entry := range desired {
+		i := slices.IndexFunc(entries, func(e T) bool { return key(e) == key(entry) })
+		_, shared := others[key(entry)]
+		switch {
+		case i < 0:
+			entries = append(entries, entry)
+			changed = true
+		case equality.Semantic.DeepEqual(entries[i], entry):
+			if !shared && !slices.Contains(previous, key(entry)) {
+				// Set by an administrator.
+				continue
+			}
+		case shared:
+			return nil, nil, false, key(entry)
+		case !slices.Contains(previous, key(entry)):
+			// Set by an administrator.
+			continue
+		default:
+			entries[i] = entry
+			changed = true
+		}
+		managed = append(managed, key(entry))
+	}
+
+	return entries, managed, changed, ""
+}
@@ -0,0 +1,137 @@
+package controller
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	mcfgv1 "github.com/openshift/api/machineconfiguration/v1"
+	operatorv1 "github.com/openshift/api/operator/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	nodeswap "github.com/openshift-virtualization/swap-operator/api/v1beta1"
+)
+
+func TestDesiredNodeDisruptionPolicy(t *testing.T) {
+	mc := func(name, config string) *mcfgv1.MachineConfig {
+		mc := &mcfgv1.MachineConfig{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		mc.Spec.Config = runtime.RawExtension{Raw: []byte(config)}
+		return mc
+	}
+	mcs := []*mcfgv1.MachineConfig{
+		mc("99-swap-oomd", `{"storage":{"files":[{"path":"/etc/systemd/oomd.conf.d/99-swap.conf"}]},`+
+			`"systemd":{"units":[{"name":"systemd-oomd.service"},{"name":"kubepods.slice"}]}}`),
+		mc("99-swap-kubelet-cgroups", `{"storage":{"files":[{"path":"/etc/openshift/kubelet.conf.d/90-swap.conf"}]}}`),
+		mc("99-filebased-swap-0", `{"storage":{"files":[{"path":"/etc/unknown.conf"}]}}`),
+	}
+
+	files, units, err := desiredNodeDisruptionPolicy(nodeswap.NodeDisruptionActionReboot, mcs)
+	if err != nil || files != nil || units != nil {
+		t.Errorf("desiredNodeDisruptionPolicy(Reboot) = %v, %v, %v", files, units, err)
+	}
+
+	files, units, err = desiredNodeDisruptionPolicy(nodeswap.NodeDisruptionActionReload, mcs)
+	if err != nil {
+		t.Fatalf("desiredNodeDisruptionPolicy() error = %v", err)
+	}
+	if len(files) != 1 || files[0].Path != "/etc/systemd/oomd.conf.d/99-swap.conf" ||
+		files[0].Actions[0].Type != operatorv1.ReloadSpecAction ||
+		files[0].Actions[0].Reload.ServiceName != "systemd-oomd.service" {
+		t.Errorf("desiredNodeDisruptionPolicy() files = %+v", files)
+	}
+	if len(units) != 2 || units[0].Name != "kubepods.slice" || units[1].Name != "systemd-oomd.service" {
+		t.Fatalf("desiredNodeDisruptionPolicy() units = %+v", units)
+	}
+	if len(units[0].Actions) != 1 || units[0].Actions[0].Type != operatorv1.DaemonReloadSpecAction {
+		t.Errorf("desiredNodeDisruptionPolicy() slice actions = %+v", units[0].Actions)
+	}
+	if len(units[1].Actions) != 2 || units[1].Actions[1].Type != operatorv1.RestartSpecAction {
+		t.Errorf("desiredNodeDisruptionPolicy() service actions = %+v", units[1].Actions)
+	}
+
+	_, units, _ = desiredNodeDisruptionPolicy(nodeswap.NodeDisruptionActionNone, mcs)
+	if units[1].Actions[0].Type != operatorv1.NoneSpecAction {
+		t.Errorf("desiredNodeDisruptionPolicy(None) service actions = %+v", units[1].Actions)
+	}
+}
+
+func TestMergeNodeDisruptionPolicy(t *testing.T) {
+	unit := func(name string, action operatorv1.NodeDisruptionPolicySpecActionType) operatorv1.NodeDisruptionPolicySpecUnit {
+		return operatorv1.NodeDisruptionPolicySpecUnit{
+			Name:    operatorv1.NodeDisruptionPolicyServiceName(name),
+			Actions: []operatorv1.NodeDisruptionPolicySpecAction{{Type: action}},
+		}
+	}
+	unitNames := func(mc *operatorv1.MachineConfiguration) []string {
+		var names []string
+		for _, unit := range mc.Spec.NodeDisruptionPolicy.Units {
+			names = append(names, string(unit.Name)+"="+string(unit.Actions[0].Type))
+		}
+		return names
+	}
+
+	mc := &operatorv1.MachineConfiguration{ObjectMeta: metav1.ObjectMeta{Name: machineConfigurationName}}
+	mc.Spec.NodeDisruptionPolicy.Units = []operatorv1.NodeDisruptionPolicySpecUnit{unit("admin.service", operatorv1.RebootSpecAction)}
+
+	merge := func(owner string, units ...operatorv1.NodeDisruptionPolicySpecUnit) (*nodeswap.NodeDisruptionPolicyStatus, bool) {
+		t.Helper()
+		managed, changed, err := mergeNodeDisruptionPolicy(mc, owner, nil, units)
+		if err != nil {
+			t.Fatalf("mergeNodeDisruptionPolicy() error = %v", err)
+		}
+		return managed, changed
+	}
+
+	managed, changed := merge("swap", unit("swap.service", operatorv1.NoneSpecAction), unit("admin.service", operatorv1.NoneSpecAction))
+	if !changed || !slices.Equal(managed.Units, []string{"swap.service"}) {
+		t.Errorf("adding entries: managed %v, changed %v", managed.Units, changed)
+	}
+	if got := unitNames(mc); !slices.Equal(got, []string{"admin.service=Reboot", "swap.service=None"}) {
+		t.Errorf("adding entries: units %v", got)
+	}
+
+	if _, changed := merge("swap", unit("swap.service", operatorv1.NoneSpecAction)); changed {
+		t.Errorf("same entries: changed")
+	}
+
+	// Another NodeSwap shares the entry with the same actions only.
+	_, _, err := mergeNodeDisruptionPolicy(mc, "other", nil,
+		[]operatorv1.NodeDisruptionPolicySpecUnit{unit("swap.service", operatorv1.DaemonReloadSpecAction)})
+	var conflictErr *conflictError
+	if !errors.As(err, &conflictErr) || conflictErr.other != "swap" {
+		t.Errorf("entry of another NodeSwap with other actions: error %v", err)
+	}
+	if managed, changed := merge("other", unit("swap.service", operatorv1.NoneSpecAction)); !changed ||
+		!slices.Equal(managed.Units, []string{"swap.service"}) {
+		t.Errorf("entry of another NodeSwap with the same actions: managed %v, changed %v", managed.Units, changed)
+	}
+	_, _, err = mergeNodeDisruptionPolicy(mc, "swap", nil,
+		[]operatorv1.NodeDisruptionPolicySpecUnit{unit("swap.service", operatorv1.DaemonReloadSpecAction)})
+	if !errors.As(err, &conflictErr) || conflictErr.other != "other" {
+		t.Errorf("updating a shared entry: error %v", err)
+	}
+	if _, changed := merge("other"); !changed {
+		t.Errorf("releasing a shared entry: not changed")
+	}
+	if got := unitNames(mc); !slices.Equal(got, []string{"admin.service=Reboot", "swap.service=None"}) {
+		t.Errorf("releasing a shared entry: units %v", got)
+	}
+
+	if _, changed := merge("swap", unit("swap.service", operatorv1.DaemonReloadSpecAction)); !changed {
+		t.Errorf("updating entries: not changed")
+	}
+	if got := unitNames(mc); !slices.Equal(got, []string{"admin.service=Reboot", "swap.service=DaemonReload"}) {
+		t.Errorf("updating entries: units %v", got)
+	}
+
+	if _, changed := merge("swap"); !changed {
+		t.Errorf("removing entries: not changed")
+	}
+	if got := unitNames(mc); !slices.Equal(got, []string{"admin.service=Reboot"}) {
+		t.Errorf("removing entries: units %v", got)
+	}
+	if _, ok := mc.Annotations[nodeDisruptionPolicyAnnotation]; ok {
+		t.Errorf("removing entries: annotation %s left", mc.Annotations[nodeDisruptionPolicyAnnotation])
+	}
+}
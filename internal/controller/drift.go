@@ -0,0 +1,157 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	mcfgv1 "github.com/openshift/api/machineconfiguration/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// machineConfigHashAnnotation is the hash of the spec of a MachineConfig as
+// the operator last wrote it, which tells out-of-band changes apart.
+const machineConfigHashAnnotation = "node-swap.openshift.io/config-hash"
+
+// setMachineConfigHash annotates the MachineConfig with the hash of its spec.
+func setMachineConfigHash(mc *mcfgv1.MachineConfig) error {
+	hash, err := machineConfigHash(mc)
+	if err != nil {
+		return err
+	}
+	if mc.Annotations == nil {
+		mc.Annotations = map[string]string{}
+	}
+	mc.Annotations[machineConfigHashAnnotation] = hash
+
+	return nil
+}
+
+// machineConfigDrifted reports whether the spec of the MachineConfig changed
+// since the operator wrote it. MachineConfigs written before the hash was
+// recorded never drift.
+func machineConfigDrifted(mc *mcfgv1.MachineConfig) (bool, error) {
+	written, ok := mc.Annotations[machineConfigHashAnnotation]
+	if !ok {
+		return false, nil
+	}
+	hash, err := machineConfigHash(mc)
+	if err != nil {
+		return false, err
+	}
+
+	return hash != written, nil
+}
+
+// machineConfigDiff summarizes how the Ignition files and systemd units of
+// the MachineConfig changed from the ones of another one, falling back to
+// the rest of the spec.
+func machineConfigDiff(from, to *mcfgv1.MachineConfig) string {
+	fromEntries, fromErr := ignitionEntries(from.Spec.Config.Raw)
+	toEntries, toErr := ignitionEntries(to.Spec.Config.Raw)
+	if fromErr != nil || toErr != nil {
+		return "Ignition config changed"
+	}
+
+	var changes []string
+	for _, key := range sets.List(sets.KeySet(fromEntries).Union(sets.KeySet(toEntries))) {
+		before, inFrom := fromEntries[key]
+		after, inTo := toEntries[key]
+		switch {
+		case !inFrom:
+			changes = append(changes, key+" added")
+		case !inTo:
+			changes = append(changes, key+" removed")
+		case before != after:
+			changes = append(changes, key+" changed")
+		}
+	}
+	if len(changes) == 0 {
+		return "spec changed"
+	}
+
+	return strings.Join(changes, ", ")
+}
+
+// ignitionEntries returns the files and the systemd units, dropins included,
+// of an Ignition config, keyed by what they are and their path or name.
+func ignitionEntries(raw []byte) (map[string]string, error) {
+	entries := map[string]string{}
+	if len(raw) == 0 {
+		return entries, nil
+	}
+
+	var config struct {
+		Storage struct {
+			Files []json.RawMessage `json:"files"`
+		} `json:"storage"`
+		Systemd struct {
+			Units []json.RawMessage `json:"units"`
+		} `json:"systemd"`
+	}
+	if err := json.Unmarshal(raw, &config); err != nil {
+		return nil, err
+	}
+
+	for _, file := range config.Storage.Files {
+		var entry struct {
+			Path string `json:"path"`
+		}
+		if err := json.Unmarshal(file, &entry); err != nil {
+			return nil, err
+		}
+		normalized, err := normalizeJSON(file)
+		if err != nil {
+			return nil, err
+		}
+		entries[fmt.Sprintf("file %s", entry.Path)] = normalized
+	}
+	for _, unit := range config.Systemd.Units {
+		var entry struct {
+			Name string `json:"name"`
+		}
+		if err := json.Unmarshal(unit, &entry); err != nil {
+			return nil, err
+		}
+		normalized, err := normalizeJSON(unit)
+		if err != nil {
+			return nil, err
+		}
+		entries[fmt.Sprintf("unit %s", entry.Name)] = normalized
+	}
+
+	return entries, nil
+}
+
+// normalizeJSON re-encodes a JSON document with its keys sorted.
+func normalizeJSON(raw []byte) (string, error) {
+	var value any
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return "", err
+	}
+	normalized, err := json.Marshal(value)
+
+	return string(normalized), err
+}
+
+// driftSummary joins the drift of the MachineConfigs for a condition message.
+func driftSummary(drift []string) string {
+	return strings.Join(slices.Sorted(slices.Values(drift)), "; ")
+}
@@ -0,0 +1,76 @@
+package controller
+
+import (
+	"testing"
+
+	mcfgv1 "github.com/openshift/api/machineconfiguration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestMachineConfigDrift(t *testing.T) {
+	mc := func(config string) *mcfgv1.MachineConfig {
+		mc := &mcfgv1.MachineConfig{ObjectMeta: metav1.ObjectMeta{Name: "99-filebased-swap-0"}}
+		mc.Spec.Config = runtime.RawExtension{Raw: []byte(config)}
+		return mc
+	}
+	const written = `{"ignition":{"version":"3.5.0"},` +
+		`"storage":{"files":[{"path":"/etc/swap.conf","contents":{"source":"data:,a"}}]},` +
+		`"systemd":{"units":[{"name":"swap.service","enabled":true,"contents":"[Unit]"}]}}`
+
+	desired := mc(written)
+	if err := setMachineConfigHash(desired); err != nil {
+		t.Fatalf("setMachineConfigHash() error = %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		config      string
+		wantDrifted bool
+		wantDiff    string
+	}{
+		{
+			name:   "reformatted",
+			config: `{"systemd":{"units":[{"contents":"[Unit]","enabled":true,"name":"swap.service"}]},"ignition":{"version":"3.5.0"},"storage":{"files":[{"contents":{"source":"data:,a"},"path":"/etc/swap.conf"}]}}`,
+		},
+		{
+			name: "file and units edited",
+			config: `{"ignition":{"version":"3.5.0"},` +
+				`"storage":{"files":[{"path":"/etc/swap.conf","contents":{"source":"data:,b"}}]},` +
+				`"systemd":{"units":[{"name":"debug.service","enabled":true}]}}`,
+			wantDrifted: true,
+			wantDiff:    "file /etc/swap.conf changed, unit debug.service added, unit swap.service removed",
+		},
+		{
+			name:        "ignition version edited",
+			config:      `{"ignition":{"version":"3.4.0"},"storage":{"files":[{"path":"/etc/swap.conf","contents":{"source":"data:,a"}}]},"systemd":{"units":[{"name":"swap.service","enabled":true,"contents":"[Unit]"}]}}`,
+			wantDrifted: true,
+			wantDiff:    "spec changed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			existing := mc(tt.config)
+			existing.Annotations = desired.Annotations
+
+			drifted, err := machineConfigDrifted(existing)
+			if err != nil {
+				t.Fatalf("machineConfigDrifted() error = %v", err)
+			}
+			if drifted != tt.wantDrifted {
+				t.Fatalf("machineConfigDrifted() = %v, want %v", drifted, tt.wantDrifted)
+			}
+			if !drifted {
+				return
+			}
+			if diff := machineConfigDiff(desired, existing); diff != tt.wantDiff {
+				t.Errorf("machineConfigDiff() = %q, want %q", diff, tt.wantDiff)
+			}
+		})
+	}
+
+	if drifted, _ := machineConfigDrifted(mc(`{}`)); drifted {
+		t.Errorf("machineConfigDrifted() = true without a recorded hash")
+	}
+}
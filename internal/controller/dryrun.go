@@ -0,0 +1,193 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"maps"
+
+	mcfgv1 "github.com/openshift/api/machineconfiguration/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/yaml"
+
+	nodeswap "github.com/openshift-virtualization/swap-operator/api/v1beta1"
+)
+
+// ReconcileDryRun renders the MachineConfigs of the NodeSwap and reports
+// what a reconciliation would create, update and delete, without writing any
+// of them. The MachineConfigs rendered by spec.dryRun are stored in a
+// ConfigMap when the reconciler has a namespace to store it in.
+func (r *NodeSwapReconciler) ReconcileDryRun(s *reconcileState) error {
+	desired, err := r.desiredMachineConfigs(s)
+	if err != nil {
+		return err
+	}
+
+	kubelet, err := r.desiredKubeletMachineConfig(s)
+	if err != nil {
+		return err
+	}
+	desired = append(desired, kubelet)
+
+	plan := &nodeswap.DryRunStatus{CreateMachineConfigPool: s.pendingPool}
+	for _, mc := range desired {
+		if err := s.recordMachineConfig(mc); err != nil {
+			return err
+		}
+		if err := r.setOwnerReference(s, mc); err != nil {
+			return err
+		}
+
+		existing := &mcfgv1.MachineConfig{}
+		if err := r.Get(s.ctx, types.NamespacedName{Name: mc.Name}, existing); err != nil {
+			if !apierrors.IsNotFound(err) {
+				logf.FromContext(s.ctx).Error(err, "Failed to get machine config", "name", mc.Name)
+				return err
+			}
+			plan.Create = append(plan.Create, mc.Name)
+			continue
+		}
+		if changed, err := r.mergeMachineConfig(s, existing, mc); err != nil {
+			return err
+		} else if changed {
+			plan.Update = append(plan.Update, mc.Name)
+		}
+	}
+
+	for _, name := range staleMachineConfigNames(s, desired) {
+		existing := &mcfgv1.MachineConfig{}
+		if err := r.Get(s.ctx, types.NamespacedName{Name: name}, existing); err != nil {
+			if !apierrors.IsNotFound(err) {
+				logf.FromContext(s.ctx).Error(err, "Failed to get machine config", "name", name)
+				return err
+			}
+			continue
+		}
+		plan.Delete = append(plan.Delete, name)
+	}
+
+	if s.hold != reasonDryRun {
+		if err := r.cleanupDryRun(s); err != nil {
+			return err
+		}
+	} else if r.Namespace != "" {
+		configMap, err := r.dryRunConfigMap(s, desired)
+		if err != nil {
+			return err
+		}
+		if err := r.applyConfigMap(s, configMap); err != nil {
+			return err
+		}
+		plan.ConfigMap = configMap.Name
+	}
+
+	logf.FromContext(s.ctx).Info("Holding MachineConfigs back", "reason", s.hold,
+		"create", plan.Create, "update", plan.Update, "delete", plan.Delete,
+		"createMachineConfigPool", plan.CreateMachineConfigPool)
+	s.pending = plan
+
+	return nil
+}
+
+// hasChanges reports whether the plan creates, updates or deletes any
+// MachineConfig, or creates the dedicated pool.
+func hasChanges(plan *nodeswap.DryRunStatus) bool {
+	return plan != nil && (len(plan.Create)+len(plan.Update)+len(plan.Delete) > 0 || plan.CreateMachineConfigPool != "")
+}
+
+// cleanupDryRun deletes the ConfigMap of the last dry run once spec.dryRun is
+// unset.
+func (r *NodeSwapReconciler) cleanupDryRun(s *reconcileState) error {
+	last := s.desiredNodeSwap.Status.DryRun
+	if last == nil || last.ConfigMap == "" || r.Namespace == "" {
+		return nil
+	}
+
+	configMap := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: last.ConfigMap, Namespace: r.Namespace}}
+	if err := r.Delete(s.ctx, configMap); client.IgnoreNotFound(err) != nil {
+		logf.FromContext(s.ctx).Error(err, "Failed to delete dry run ConfigMap", "name", configMap.Name)
+		return err
+	}
+
+	return nil
+}
+
+// dryRunConfigMap returns the ConfigMap holding the MachineConfigs rendered by
+// a dry run, one YAML document per MachineConfig.
+func (r *NodeSwapReconciler) dryRunConfigMap(s *reconcileState, mcs []*mcfgv1.MachineConfig) (*corev1.ConfigMap, error) {
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      s.desiredNodeSwap.Name + "-dry-run",
+			Namespace: r.Namespace,
+			Labels:    ownerLabels(s),
+		},
+		Data: map[string]string{},
+	}
+	for _, mc := range mcs {
+		rendered := &mcfgv1.MachineConfig{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: mcfgv1.GroupVersion.String(),
+				Kind:       "MachineConfig",
+			},
+			ObjectMeta: metav1.ObjectMeta{Name: mc.Name, Labels: mc.Labels},
+			Spec:       mc.Spec,
+		}
+		data, err := yaml.Marshal(rendered)
+		if err != nil {
+			logf.FromContext(s.ctx).Error(err, "Failed to marshal MachineConfig", "name", mc.Name)
+			return nil, err
+		}
+		configMap.Data[mc.Name+".yaml"] = string(data)
+	}
+
+	if err := r.setOwnerReference(s, configMap); err != nil {
+		return nil, err
+	}
+
+	return configMap, nil
+}
+
+// applyConfigMap creates the ConfigMap or updates its data.
+func (r *NodeSwapReconciler) applyConfigMap(s *reconcileState, desired *corev1.ConfigMap) error {
+	existing := &corev1.ConfigMap{}
+	if err := r.Get(s.ctx, client.ObjectKeyFromObject(desired), existing); err != nil {
+		if !apierrors.IsNotFound(err) {
+			logf.FromContext(s.ctx).Error(err, "Failed to get ConfigMap", "name", desired.Name)
+			return err
+		}
+		if err := r.Create(s.ctx, desired); err != nil {
+			logf.FromContext(s.ctx).Error(err, "Failed to create ConfigMap", "name", desired.Name)
+			return err
+		}
+		return nil
+	}
+
+	if maps.Equal(existing.Data, desired.Data) {
+		return nil
+	}
+	existing.Data = desired.Data
+	if err := r.Update(s.ctx, existing); err != nil {
+		logf.FromContext(s.ctx).Error(err, "Failed to update ConfigMap", "name", desired.Name)
+		return err
+	}
+
+	return nil
+}
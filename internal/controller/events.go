@@ -0,0 +1,124 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	nodeswap "github.com/openshift-virtualization/swap-operator/api/v1beta1"
+)
+
+const (
+	eventReasonMachineConfigCreated = "MachineConfigCreated"
+	eventReasonMachineConfigUpdated = "MachineConfigUpdated"
+	eventReasonMachineConfigDeleted = "MachineConfigDeleted"
+	eventReasonPoolMatched          = "MachineConfigPoolMatched"
+	eventReasonPoolUnmatched        = "MachineConfigPoolUnmatched"
+	eventReasonRolloutStarted       = "RolloutStarted"
+	eventReasonRolloutCompleted     = "RolloutCompleted"
+)
+
+// statusEvent is an event recorded for a change of the status.
+type statusEvent struct {
+	eventType string
+	reason    string
+	message   string
+}
+
+// event records an event on the NodeSwap, when the reconciler has a
+// recorder.
+func (r *NodeSwapReconciler) event(s *reconcileState, eventType, reason, messageFmt string, args ...any) {
+	if r.Recorder == nil {
+		return
+	}
+	r.Recorder.Eventf(&s.desiredNodeSwap, eventType, reason, messageFmt, args...)
+}
+
+// recordStatusEvents records the events of the changes from the previous
+// status of the NodeSwap.
+func (r *NodeSwapReconciler) recordStatusEvents(s *reconcileState, previous *nodeswap.NodeSwapStatus) {
+	for _, event := range statusEvents(previous, &s.desiredNodeSwap.Status) {
+		r.event(s, event.eventType, event.reason, "%s", event.message)
+	}
+}
+
+// statusEvents returns the events of the changes between two statuses of a
+// NodeSwap. Events are only returned for transitions, so that
+// reconciliations leaving the status as is record none.
+func statusEvents(previous, current *nodeswap.NodeSwapStatus) []statusEvent {
+	var events []statusEvent
+
+	poolNames := func(status *nodeswap.NodeSwapStatus) sets.Set[string] {
+		names := sets.New[string]()
+		for _, pool := range status.Pools {
+			names.Insert(pool.Name)
+		}
+		return names
+	}
+	before, after := poolNames(previous), poolNames(current)
+	for _, name := range sets.List(after.Difference(before)) {
+		events = append(events, statusEvent{corev1.EventTypeNormal, eventReasonPoolMatched,
+			fmt.Sprintf("MachineConfigPool %s is selected", name)})
+	}
+	for _, name := range sets.List(before.Difference(after)) {
+		events = append(events, statusEvent{corev1.EventTypeNormal, eventReasonPoolUnmatched,
+			fmt.Sprintf("MachineConfigPool %s is no longer selected", name)})
+	}
+
+	// The rollout messages count the machines, only the transitions of the
+	// status are reported.
+	if progressing := meta.FindStatusCondition(current.Conditions, typeProgressingNodeSwap); progressing != nil &&
+		progressing.Status == metav1.ConditionTrue &&
+		!meta.IsStatusConditionTrue(previous.Conditions, typeProgressingNodeSwap) {
+		events = append(events, statusEvent{corev1.EventTypeNormal, eventReasonRolloutStarted, progressing.Message})
+	}
+	if available := meta.FindStatusCondition(current.Conditions, typeAvailableNodeSwap); available != nil &&
+		available.Status == metav1.ConditionTrue &&
+		!meta.IsStatusConditionTrue(previous.Conditions, typeAvailableNodeSwap) {
+		events = append(events, statusEvent{corev1.EventTypeNormal, eventReasonRolloutCompleted, available.Message})
+	}
+
+	if condition := conditionChanged(previous, current, typePrerequisitesMetNodeSwap, metav1.ConditionFalse); condition != nil {
+		events = append(events, statusEvent{corev1.EventTypeWarning, reasonPrerequisitesNotMet, condition.Message})
+	}
+	if condition := conditionChanged(previous, current, typeConflictNodeSwap, metav1.ConditionTrue); condition != nil {
+		events = append(events, statusEvent{corev1.EventTypeWarning, reasonConflictingNodeSwap, condition.Message})
+	}
+
+	return events
+}
+
+// conditionChanged returns the condition of the current status when it has
+// the given status and had another status or message before.
+func conditionChanged(previous, current *nodeswap.NodeSwapStatus, conditionType string,
+	status metav1.ConditionStatus) *metav1.Condition {
+	condition := meta.FindStatusCondition(current.Conditions, conditionType)
+	if condition == nil || condition.Status != status {
+		return nil
+	}
+	if last := meta.FindStatusCondition(previous.Conditions, conditionType); last != nil &&
+		last.Status == status && last.Message == condition.Message {
+		return nil
+	}
+
+	return condition
+}
@@ -0,0 +1,83 @@
+package controller
+
+import (
+	"slices"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	nodeswap "github.com/openshift-virtualization/swap-operator/api/v1beta1"
+)
+
+func TestStatusEvents(t *testing.T) {
+	status := func(pools []string, conditions ...metav1.Condition) *nodeswap.NodeSwapStatus {
+		status := &nodeswap.NodeSwapStatus{Conditions: conditions}
+		for _, pool := range pools {
+			status.Pools = append(status.Pools, nodeswap.PoolStatus{Name: pool})
+		}
+		return status
+	}
+	condition := func(conditionType string, status metav1.ConditionStatus, message string) metav1.Condition {
+		return metav1.Condition{Type: conditionType, Status: status, Message: message}
+	}
+
+	tests := []struct {
+		name     string
+		previous *nodeswap.NodeSwapStatus
+		current  *nodeswap.NodeSwapStatus
+		want     []string
+	}{
+		{
+			name:     "pools matched and unmatched",
+			previous: status([]string{"worker", "infra"}),
+			current:  status([]string{"worker", "swap"}),
+			want:     []string{"MachineConfigPoolMatched", "MachineConfigPoolUnmatched"},
+		},
+		{
+			name:     "rollout started",
+			previous: status(nil, condition(typeProgressingNodeSwap, metav1.ConditionFalse, "")),
+			current:  status(nil, condition(typeProgressingNodeSwap, metav1.ConditionTrue, "0 of 3 machines updated")),
+			want:     []string{"RolloutStarted"},
+		},
+		{
+			name:     "rollout progressing",
+			previous: status(nil, condition(typeProgressingNodeSwap, metav1.ConditionTrue, "0 of 3 machines updated")),
+			current:  status(nil, condition(typeProgressingNodeSwap, metav1.ConditionTrue, "1 of 3 machines updated")),
+		},
+		{
+			name:     "rollout completed",
+			previous: status(nil, condition(typeAvailableNodeSwap, metav1.ConditionFalse, "")),
+			current:  status(nil, condition(typeAvailableNodeSwap, metav1.ConditionTrue, "rolled out")),
+			want:     []string{"RolloutCompleted"},
+		},
+		{
+			name:     "prerequisites not met",
+			previous: status(nil),
+			current:  status(nil, condition(typePrerequisitesMetNodeSwap, metav1.ConditionFalse, "cgroup v1")),
+			want:     []string{reasonPrerequisitesNotMet},
+		},
+		{
+			name:     "same conflict",
+			previous: status(nil, condition(typeConflictNodeSwap, metav1.ConditionTrue, "other")),
+			current:  status(nil, condition(typeConflictNodeSwap, metav1.ConditionTrue, "other")),
+		},
+		{
+			name:     "another conflict",
+			previous: status(nil, condition(typeConflictNodeSwap, metav1.ConditionTrue, "other")),
+			current:  status(nil, condition(typeConflictNodeSwap, metav1.ConditionTrue, "another")),
+			want:     []string{reasonConflictingNodeSwap},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, event := range statusEvents(tt.previous, tt.current) {
+				got = append(got, event.reason)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("statusEvents() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
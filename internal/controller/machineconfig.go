@@ -0,0 +1,301 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	goerrors "errors"
+	"fmt"
+	"maps"
+	"path/filepath"
+	"slices"
+
+	mcfgv1 "github.com/openshift/api/machineconfiguration/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	nodeswap "github.com/openshift-virtualization/swap-operator/api/v1beta1"
+	"github.com/openshift-virtualization/swap-operator/internal/renderconfig"
+	"github.com/openshift-virtualization/swap-operator/internal/template"
+)
+
+// ReconcileMachineConfigs renders the swap and systemd-oomd MachineConfigs
+// for the desired NodeSwap, or the spec it was rolled back to, and creates
+// or updates them. With spec.rollbackToRevision, the MachineConfigs of the
+// revision are applied instead.
+func (r *NodeSwapReconciler) ReconcileMachineConfigs(s *reconcileState) (ctrl.Result, error) {
+	desired, err := r.desiredMachineConfigs(s)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// The nodes look the policy up when they apply the change.
+	if err := r.ReconcileNodeDisruptionPolicy(s, desired); err != nil {
+		// The other NodeSwaps are not watched, check on the conflict
+		// again as for the conflicts on the pools.
+		var conflictErr *conflictError
+		if goerrors.As(err, &conflictErr) {
+			return ctrl.Result{RequeueAfter: conflictRequeueInterval}, err
+		}
+		return ctrl.Result{}, err
+	}
+
+	for _, mc := range desired {
+		if err := r.applyMachineConfig(s, mc); err != nil {
+			return ctrl.Result{}, err
+		}
+		if err := s.recordMachineConfig(mc); err != nil {
+			return ctrl.Result{}, err
+		}
+		s.rendered = append(s.rendered, mc)
+	}
+
+	for _, name := range staleMachineConfigNames(s, desired) {
+		if err := r.deleteMachineConfig(s, name); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// desiredMachineConfigs returns the swap and systemd-oomd MachineConfigs of
+// the NodeSwap, labeled for the selected pools.
+func (r *NodeSwapReconciler) desiredMachineConfigs(s *reconcileState) ([]*mcfgv1.MachineConfig, error) {
+	if s.desiredNodeSwap.Spec.RollbackToRevision != "" {
+		return r.revisionMachineConfigs(s)
+	}
+
+	desired, err := r.specMachineConfigs(s, renderedSpec(&s.desiredNodeSwap))
+	if err != nil {
+		return nil, err
+	}
+	for _, mc := range desired {
+		maps.Copy(mc.Labels, s.mcLabels)
+		maps.Copy(mc.Labels, ownerLabels(s))
+		if err := setMachineConfigHash(mc); err != nil {
+			return nil, err
+		}
+	}
+
+	return desired, nil
+}
+
+// specMachineConfigs renders the swap and systemd-oomd MachineConfigs of
+// the spec, without the labels of the NodeSwap.
+func (r *NodeSwapReconciler) specMachineConfigs(s *reconcileState,
+	spec *nodeswap.NodeSwapSpec) ([]*mcfgv1.MachineConfig, error) {
+	configs, err := renderconfig.Create(s.desiredNodeSwap.Name, spec)
+	if err != nil {
+		logf.FromContext(s.ctx).Error(err, "Failed to create render config")
+		return nil, err
+	}
+	oomdConfig, err := renderconfig.CreateOomd(s.desiredNodeSwap.Name, spec)
+	if err != nil {
+		logf.FromContext(s.ctx).Error(err, "Failed to create oomd render config")
+		return nil, err
+	}
+	if oomdConfig != nil {
+		configs = append(configs, *oomdConfig)
+	}
+
+	mcs := make([]*mcfgv1.MachineConfig, 0, len(configs))
+	for i := range configs {
+		mc, err := r.renderMachineConfig(s, &configs[i])
+		if err != nil {
+			logf.FromContext(s.ctx).Error(err, "Failed to render machine config", "name", configs[i].Name)
+			return nil, err
+		}
+		mcs = append(mcs, mc)
+	}
+
+	return mcs, nil
+}
+
+// staleMachineConfigNames returns the MachineConfigs of the NodeSwap that are
+// not desired anymore: the systemd-oomd one once spec.oomd is unset, the
+// ones recorded in the status that are not rendered anymore, such as those
+// named before the MachineConfigs included the NodeSwap name, and the ones a
+// revision rolled back to does not include.
+func staleMachineConfigNames(s *reconcileState, desired []*mcfgv1.MachineConfig) []string {
+	stale := sets.New(machineConfigNames(s.desiredNodeSwap.Status.MachineConfigs)...)
+	if renderedSpec(&s.desiredNodeSwap).Oomd == nil {
+		stale.Insert(renderconfig.OomdMachineConfigName(s.desiredNodeSwap.Name))
+	}
+	if s.desiredNodeSwap.Spec.RollbackToRevision != "" {
+		if names, err := renderedMachineConfigNames(s.desiredNodeSwap.Name, &s.desiredNodeSwap.Spec); err == nil {
+			stale.Insert(names...)
+		}
+	}
+	stale.Delete(renderconfig.SwapKubeletCgroupsMCPrefix)
+	stale.Delete(renderconfig.KubeletMachineConfigName(s.desiredNodeSwap.Name))
+	for _, mc := range desired {
+		stale.Delete(mc.Name)
+	}
+
+	return sets.List(stale)
+}
+
+// checkTemplates makes sure every config can be rendered: swap types
+// without templates, such as zram and disk swaps, are an invalid spec rather
+// than MachineConfigs silently left out.
+func (r *NodeSwapReconciler) checkTemplates(configs []renderconfig.RenderConfig) error {
+	for i := range configs {
+		if _, err := r.templatePath(&configs[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// templatePath returns the worker template directory of the config.
+func (r *NodeSwapReconciler) templatePath(config *renderconfig.RenderConfig) (string, error) {
+	path := filepath.Join(r.TemplateDir, "worker", config.TemplateName)
+	exists, err := template.HasTemplates(path)
+	if err != nil {
+		return "", &template.RenderError{Err: err}
+	}
+	if !exists {
+		return "", &renderconfig.InvalidSpecError{
+			Err: fmt.Errorf("MachineConfig %s is not supported, there are no %s templates", config.Name, config.TemplateName),
+		}
+	}
+
+	return path, nil
+}
+
+// renderMachineConfig renders the worker templates of config.TemplateName
+// into a MachineConfig named config.Name.
+func (r *NodeSwapReconciler) renderMachineConfig(s *reconcileState, config *renderconfig.RenderConfig) (*mcfgv1.MachineConfig, error) {
+	fullTemplatePath, err := r.templatePath(config)
+	if err != nil {
+		return nil, err
+	}
+
+	mc, err := template.GenerateMachineConfigForName(config, "worker", config.TemplateName, r.TemplateDir, fullTemplatePath)
+	if err != nil {
+		return nil, err
+	}
+	mc.Name = config.Name
+
+	return mc, nil
+}
+
+// applyMachineConfig creates the MachineConfig, or updates its spec, labels
+// and owner references when they differ from the desired ones.
+func (r *NodeSwapReconciler) applyMachineConfig(s *reconcileState, desired *mcfgv1.MachineConfig) error {
+	if err := r.setOwnerReference(s, desired); err != nil {
+		return err
+	}
+
+	existing := &mcfgv1.MachineConfig{}
+	if err := r.Get(s.ctx, types.NamespacedName{Name: desired.Name}, existing); err != nil {
+		if !apierrors.IsNotFound(err) {
+			logf.FromContext(s.ctx).Error(err, "Failed to get machine config", "name", desired.Name)
+			return err
+		}
+		if err := r.Create(s.ctx, desired); err != nil {
+			logf.FromContext(s.ctx).Error(err, "Failed to create machine config", "name", desired.Name)
+			return err
+		}
+		logf.FromContext(s.ctx).Info("Created machine config", "name", desired.Name)
+		r.event(s, corev1.EventTypeNormal, eventReasonMachineConfigCreated, "Created MachineConfig %s", desired.Name)
+		return nil
+	}
+
+	changed, err := r.mergeMachineConfig(s, existing, desired)
+	if err != nil || !changed {
+		return err
+	}
+	if err := r.Update(s.ctx, existing); err != nil {
+		logf.FromContext(s.ctx).Error(err, "Failed to update machine config", "name", desired.Name)
+		return err
+	}
+	logf.FromContext(s.ctx).Info("Updated machine config", "name", desired.Name)
+	r.event(s, corev1.EventTypeNormal, eventReasonMachineConfigUpdated, "Updated MachineConfig %s", desired.Name)
+
+	return nil
+}
+
+// mergeMachineConfig sets the spec, labels, annotations and owner references
+// of desired on existing, and reports whether it changed. Out-of-band
+// changes to existing are recorded, and left alone with the Report drift
+// policy.
+func (r *NodeSwapReconciler) mergeMachineConfig(s *reconcileState, existing, desired *mcfgv1.MachineConfig) (bool, error) {
+	drifted, err := machineConfigDrifted(existing)
+	if err != nil {
+		logf.FromContext(s.ctx).Error(err, "Failed to hash machine config", "name", existing.Name)
+		return false, err
+	}
+	if drifted {
+		diff := machineConfigDiff(desired, existing)
+		logf.FromContext(s.ctx).Info("Machine config changed out of band", "name", existing.Name, "diff", diff)
+		s.drift = append(s.drift, fmt.Sprintf("MachineConfig %s: %s", existing.Name, diff))
+		if s.desiredNodeSwap.Spec.DriftPolicy == nodeswap.DriftPolicyReport {
+			return false, nil
+		}
+	}
+
+	labels := maps.Clone(existing.Labels)
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	maps.Copy(labels, desired.Labels)
+	annotations := maps.Clone(existing.Annotations)
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	maps.Copy(annotations, desired.Annotations)
+
+	owners := slices.Clone(existing.OwnerReferences)
+	if err := r.setOwnerReference(s, existing); err != nil {
+		return false, err
+	}
+
+	if equality.Semantic.DeepEqual(existing.Spec, desired.Spec) && maps.Equal(existing.Labels, labels) &&
+		maps.Equal(existing.Annotations, annotations) && equality.Semantic.DeepEqual(existing.OwnerReferences, owners) {
+		return false, nil
+	}
+
+	existing.Spec = desired.Spec
+	existing.Labels = labels
+	existing.Annotations = annotations
+
+	return true, nil
+}
+
+// deleteMachineConfig deletes the named MachineConfig if it exists.
+func (r *NodeSwapReconciler) deleteMachineConfig(s *reconcileState, name string) error {
+	mc := &mcfgv1.MachineConfig{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	if err := r.Delete(s.ctx, mc); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		logf.FromContext(s.ctx).Error(err, "Failed to delete machine config", "name", name)
+		return err
+	}
+	logf.FromContext(s.ctx).Info("Deleted machine config", "name", name)
+	r.event(s, corev1.EventTypeNormal, eventReasonMachineConfigDeleted, "Deleted MachineConfig %s", name)
+
+	return nil
+}
@@ -0,0 +1,30 @@
+package controller
+
+import (
+	"reflect"
+	"testing"
+
+	mcfgv1 "github.com/openshift/api/machineconfiguration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	nodeswap "github.com/openshift-virtualization/swap-operator/api/v1beta1"
+	"github.com/openshift-virtualization/swap-operator/internal/renderconfig"
+)
+
+func TestStaleMachineConfigNames(t *testing.T) {
+	s := &reconcileState{}
+	s.desiredNodeSwap.Name = "worker"
+	s.desiredNodeSwap.Status.MachineConfigs = []nodeswap.RenderedMachineConfig{
+		{Name: "99-filebased-swap-0"},
+		{Name: "99-filebased-swap-worker-0"},
+		{Name: renderconfig.SwapKubeletCgroupsMCPrefix},
+		{Name: "99-swap-kubelet-cgroups-worker"},
+	}
+	desired := []*mcfgv1.MachineConfig{{ObjectMeta: metav1.ObjectMeta{Name: "99-filebased-swap-worker-0"}}}
+
+	got := staleMachineConfigNames(s, desired)
+	want := []string{"99-filebased-swap-0", "99-swap-oomd-worker"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("staleMachineConfigNames() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"slices"
+	"strings"
+
+	mcfgv1 "github.com/openshift/api/machineconfiguration/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	nodeswap "github.com/openshift-virtualization/swap-operator/api/v1beta1"
+)
+
+// ReconcileNodeRollout summarizes the rollout on the nodes of the selected
+// pools from their MachineConfigNodes. MachineConfigNodes are not watched:
+// pools report the changes of their machine counts, which is enough to keep
+// the summary current.
+func (r *NodeSwapReconciler) ReconcileNodeRollout(s *reconcileState) error {
+	mcnList := &mcfgv1.MachineConfigNodeList{}
+	if err := r.List(s.ctx, mcnList); err != nil {
+		if meta.IsNoMatchError(err) {
+			logf.FromContext(s.ctx).V(1).Info("MachineConfigNodes are not available, skipping the node rollout")
+			return nil
+		}
+		logf.FromContext(s.ctx).Error(err, "Failed to list MachineConfigNodes")
+		return err
+	}
+
+	s.nodes = summarizeNodeRollout(s.pools, machineConfigNames(s.machineConfigs), mcnList.Items)
+
+	return nil
+}
+
+// summarizeNodeRollout sorts the nodes of the pools by the state of the
+// rollout of the named MachineConfigs. A node has them applied once it runs
+// a rendered configuration of its pool that includes them; nodes whose pool
+// has not rendered them yet are left out.
+func summarizeNodeRollout(pools []*mcfgv1.MachineConfigPool, names []string,
+	mcns []mcfgv1.MachineConfigNode) nodeswap.NodeRolloutStatus {
+	// The rendered configurations including the MachineConfigs, by pool.
+	rendered := map[string]sets.Set[string]{}
+	for _, mcp := range pools {
+		configs := sets.New[string]()
+		for _, config := range []mcfgv1.MachineConfigPoolStatusConfiguration{mcp.Spec.Configuration, mcp.Status.Configuration} {
+			if includesMachineConfigs(config, names) {
+				configs.Insert(config.Name)
+			}
+		}
+		rendered[mcp.Name] = configs
+	}
+
+	var status nodeswap.NodeRolloutStatus
+	for i := range mcns {
+		mcn := &mcns[i]
+		configs, ok := rendered[mcn.Spec.Pool.Name]
+		if !ok {
+			continue
+		}
+
+		if degraded := meta.FindStatusCondition(mcn.Status.Conditions, string(mcfgv1.MachineConfigNodeNodeDegraded)); degraded != nil &&
+			degraded.Status == metav1.ConditionTrue {
+			status.Failed = append(status.Failed, nodeswap.NodeFailure{
+				Name:    mcn.Name,
+				Reason:  degraded.Reason,
+				Message: degraded.Message,
+			})
+			continue
+		}
+
+		var current string
+		if mcn.Status.ConfigVersion != nil {
+			current = mcn.Status.ConfigVersion.Current
+		}
+		switch {
+		case configs.Has(current):
+			status.Applied = append(status.Applied, mcn.Name)
+		case configs.Has(mcn.Spec.ConfigVersion.Desired):
+			status.Updating = append(status.Updating, mcn.Name)
+		}
+	}
+
+	slices.Sort(status.Applied)
+	slices.Sort(status.Updating)
+	slices.SortFunc(status.Failed, func(a, b nodeswap.NodeFailure) int { return strings.Compare(a.Name, b.Name) })
+
+	return status
+}
@@ -0,0 +1,60 @@
+package controller
+
+import (
+	"reflect"
+	"testing"
+
+	mcfgv1 "github.com/openshift/api/machineconfiguration/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	nodeswap "github.com/openshift-virtualization/swap-operator/api/v1beta1"
+)
+
+func TestSummarizeNodeRollout(t *testing.T) {
+	names := []string{"99-filebased-swap-0"}
+	withSwap := []corev1.ObjectReference{{Name: "00-worker"}, {Name: "99-filebased-swap-0"}}
+
+	worker := &mcfgv1.MachineConfigPool{ObjectMeta: metav1.ObjectMeta{Name: "worker"}}
+	worker.Spec.Configuration.Name = "rendered-worker-2"
+	worker.Spec.Configuration.Source = withSwap
+	worker.Status.Configuration.Name = "rendered-worker-1"
+	worker.Status.Configuration.Source = []corev1.ObjectReference{{Name: "00-worker"}}
+
+	// A pool that has not rendered the MachineConfigs yet.
+	infra := &mcfgv1.MachineConfigPool{ObjectMeta: metav1.ObjectMeta{Name: "infra"}}
+	infra.Spec.Configuration.Name = "rendered-infra-1"
+
+	mcn := func(name, pool, current, desired string, conditions ...metav1.Condition) mcfgv1.MachineConfigNode {
+		node := mcfgv1.MachineConfigNode{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		node.Spec.Pool.Name = pool
+		node.Spec.ConfigVersion.Desired = desired
+		node.Status.ConfigVersion = &mcfgv1.MachineConfigNodeStatusMachineConfigVersion{Current: current, Desired: desired}
+		node.Status.Conditions = conditions
+		return node
+	}
+	mcns := []mcfgv1.MachineConfigNode{
+		mcn("worker-2", "worker", "rendered-worker-2", "rendered-worker-2"),
+		mcn("worker-0", "worker", "rendered-worker-1", "rendered-worker-2"),
+		mcn("worker-1", "worker", "rendered-worker-1", "rendered-worker-2", metav1.Condition{
+			Type:    string(mcfgv1.MachineConfigNodeNodeDegraded),
+			Status:  metav1.ConditionTrue,
+			Reason:  "NodeDegraded",
+			Message: "failed to enable swap",
+		}),
+		mcn("infra-0", "infra", "rendered-infra-1", "rendered-infra-1"),
+		mcn("master-0", "master", "rendered-master-1", "rendered-master-1"),
+	}
+
+	got := summarizeNodeRollout([]*mcfgv1.MachineConfigPool{worker, infra}, names, mcns)
+	want := nodeswap.NodeRolloutStatus{
+		Applied:  []string{"worker-2"},
+		Updating: []string{"worker-0"},
+		Failed: []nodeswap.NodeFailure{
+			{Name: "worker-1", Reason: "NodeDegraded", Message: "failed to enable swap"},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("summarizeNodeRollout() = %+v, want %+v", got, want)
+	}
+}
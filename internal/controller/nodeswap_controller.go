@@ -18,44 +18,174 @@ package controller
 
 import (
 	"context"
-	"encoding/base64"
+	goerrors "errors"
 	"fmt"
-	"path/filepath"
+	"maps"
+	"slices"
 	"strings"
+	"time"
 
+	configv1 "github.com/openshift/api/config/v1"
 	mcfgv1 "github.com/openshift/api/machineconfiguration/v1"
-	"go.yaml.in/yaml/v2"
 	corev1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/api/errors"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
-	nodeswap "github.com/openshift-virtualization/swap-operator/api/v1alpha1"
+	nodeswap "github.com/openshift-virtualization/swap-operator/api/v1beta1"
 	"github.com/openshift-virtualization/swap-operator/internal/renderconfig"
 	"github.com/openshift-virtualization/swap-operator/internal/template"
 )
 
 const (
-	typeAvailableNodeSwap   = "Availabe"
+	typeAvailableNodeSwap   = "Available"
 	typeProgressingNodeSwap = "Progressing"
 	typeDegradedNodeSwap    = "Degraded"
+	// typePrerequisitesMetNodeSwap reports whether the cluster satisfies the
+	// prerequisites checked before any MachineConfig is rolled out.
+	typePrerequisitesMetNodeSwap = "PrerequisitesMet"
+	// typeConflictNodeSwap reports whether another NodeSwap taking
+	// precedence selects the same pools or MachineConfigs.
+	typeConflictNodeSwap = "Conflict"
+	// typeRolledBackNodeSwap reports whether the current generation was
+	// rolled back by spec.autoRollback.
+	typeRolledBackNodeSwap = "RolledBack"
+	// typeDriftedNodeSwap reports whether MachineConfigs of the NodeSwap
+	// were changed out of band.
+	typeDriftedNodeSwap = "Drifted"
+	// legacyTypeAvailableNodeSwap is the misspelled Available condition set
+	// by earlier releases, removed from the status when it is updated.
+	legacyTypeAvailableNodeSwap = "Availabe"
+
+	reasonPrerequisitesNotMet = "PrerequisitesNotMet"
+	// reasonNoMatchingPools is set while the NodeSwap selects no
+	// MachineConfigPool.
+	reasonNoMatchingPools = "NoMatchingPools"
+	// reasonPoolsPartiallyCovered is set when spec.nodeSelector selects only
+	// some of the nodes of a MachineConfigPool.
+	reasonPoolsPartiallyCovered = "PoolsPartiallyCovered"
+	// reasonConflictingNodeSwap is set when another NodeSwap takes precedence.
+	reasonConflictingNodeSwap = "ConflictingNodeSwap"
+	// reasonRollingOut is set while a pool has not rendered the
+	// MachineConfigs into the configuration of all its machines.
+	reasonRollingOut = "RollingOut"
+	// reasonRolledOut is set once every pool has rolled the MachineConfigs out.
+	reasonRolledOut = "RolledOut"
+	// reasonStagedRollout is set while a stage of spec.rollout waits for the
+	// previous ones.
+	reasonStagedRollout = "StagedRollout"
+	// reasonPoolDegraded is set when a selected pool is degraded.
+	reasonPoolDegraded = "MachineConfigPoolDegraded"
+	// reasonPaused is set while spec.paused holds the selected pools.
+	reasonPaused = "Paused"
+	// reasonRolledBack is set once spec.autoRollback rolled the current
+	// generation back.
+	reasonRolledBack = "RolledBack"
+	// reasonDryRun is set while spec.dryRun holds the MachineConfigs back.
+	reasonDryRun = "DryRun"
+	// reasonOutsideMaintenanceWindow is set while changes to the
+	// MachineConfigs wait for the next maintenance window.
+	reasonOutsideMaintenanceWindow = "OutsideMaintenanceWindow"
+	// reasonWaitingForUpgrade is set while changes to the MachineConfigs
+	// wait for the cluster upgrade in progress to complete.
+	reasonWaitingForUpgrade = "WaitingForUpgrade"
+	// reasonOutOfBandChanges is set while MachineConfigs changed out of band
+	// are left alone.
+	reasonOutOfBandChanges = "OutOfBandChanges"
+	// reasonDriftReverted is set once out-of-band changes were reverted.
+	reasonDriftReverted = "DriftReverted"
+
+	// The reasons of the reconciliation failures, see errorReason.
+	reasonReconciliationFailed = "ReconciliationFailed"
+	reasonInvalidSpec          = "InvalidSpec"
+	reasonRenderFailed         = "RenderFailed"
+	reasonPoolNotFound         = "PoolNotFound"
+	reasonAPIError             = "APIError"
 )
 
 type NodeSwapReconciler struct {
 	client.Client
-	Scheme          *runtime.Scheme
-	TemplateDir     string
-	config          []renderconfig.RenderConfig
+	Scheme      *runtime.Scheme
+	TemplateDir string
+
+	// MaxConcurrentReconciles is the maximum number of NodeSwaps reconciled
+	// concurrently, 1 when unset.
+	MaxConcurrentReconciles int
+
+	// Namespace is the namespace of the operator, where the revision history
+	// of the NodeSwaps is stored. The history is disabled when unset.
+	Namespace string
+
+	// Recorder records the events of the NodeSwaps. No events are recorded
+	// when unset.
+	Recorder record.EventRecorder
+}
+
+// reconcileState is the state of a single reconciliation. It is passed
+// through the Reconcile* methods rather than kept on the reconciler, which
+// is shared by concurrent reconciliations of different NodeSwaps.
+type reconcileState struct {
 	ctx             context.Context
 	desiredNodeSwap nodeswap.NodeSwap
-	mcpReady        bool
+	config          []renderconfig.RenderConfig
+	// mcLabels are the labels that make the selected pools pick up the
+	// operator's MachineConfigs.
+	mcLabels map[string]string
+	// pools are the selected MachineConfigPools.
+	pools []*mcfgv1.MachineConfigPool
+	// machineConfigs are the MachineConfigs rendered for the NodeSwap.
+	machineConfigs []nodeswap.RenderedMachineConfig
+	// nodes summarizes the rollout on the nodes of the selected pools.
+	nodes nodeswap.NodeRolloutStatus
+	// rolloutWaiting explains why the next stage of a staged rollout has
+	// not started.
+	rolloutWaiting string
+	// heldPools are the names of the pools a staged rollout holds back
+	// until their stage starts.
+	heldPools []string
+	// pausedPools are the names of the pools paused by the NodeSwap.
+	pausedPools []string
+	// renderedPools are the selected pools whose rendered configuration
+	// includes the current content of the MachineConfigs.
+	renderedPools sets.Set[string]
+	// rendered are the swap and systemd-oomd MachineConfigs applied for the
+	// NodeSwap.
+	rendered []*mcfgv1.MachineConfig
+	// currentRevision is the ControllerRevision of the rendered
+	// MachineConfigs, once every selected pool rolled them out.
+	currentRevision string
+	// hold is the reason the MachineConfigs are not written, reasonDryRun,
+	// reasonWaitingForUpgrade or reasonOutsideMaintenanceWindow, or empty
+	// when they are.
+	hold string
+	// clusterVersion is the ClusterVersion, if any.
+	clusterVersion *configv1.ClusterVersion
+	// upgradeVersion is the version of the cluster upgrade holding the
+	// MachineConfigs back.
+	upgradeVersion string
+	// pending reports the changes held back.
+	pending *nodeswap.DryRunStatus
+	// pendingPool is the dedicated pool held back with the MachineConfigs.
+	pendingPool string
+	// nodeDisruptionPolicy lists the nodeDisruptionPolicy entries managed
+	// for the NodeSwap.
+	nodeDisruptionPolicy *nodeswap.NodeDisruptionPolicyStatus
+	// maintenanceWindow is the maintenance window in progress or the next
+	// one, nil without maintenance windows.
+	maintenanceWindow *nodeswap.MaintenanceWindowStatus
+	// drift summarizes the out-of-band changes to the MachineConfigs.
+	drift []string
 }
 
 // +kubebuilder:rbac:groups=node-swap.openshift.io,resources=nodeswaps,verbs=get;list;watch;create;update;patch;delete
@@ -63,22 +193,26 @@ type NodeSwapReconciler struct {
 // +kubebuilder:rbac:groups=node-swap.openshift.io,resources=nodeswaps/finalizers,verbs=update
 // +kubebuilder:rbac:groups=machineconfiguration.openshift.io,resources=machineconfigs,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=machineconfiguration.openshift.io,resources=machineconfigs/status,verbs=get;list;watch
-// +kubebuilder:rbac:groups=machineconfiguration.openshift.io,resources=machineconfigpools,verbs=get;list;watch
-
-// Reconcile is part of the main kubernetes reconciliation loop which aims to
-// move the current state of the cluster closer to the desired state.
-// TODO(user): Modify the Reconcile function to compare the state specified by
-// the NodeSwap object against the actual cluster state, and then
-// perform operations to make the cluster state reflect the state specified by
-// the user.
-//
-// For more details, check Reconcile and its Result here:
-// - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.22.1/pkg/reconcile
+// +kubebuilder:rbac:groups=machineconfiguration.openshift.io,resources=machineconfigpools,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=machineconfiguration.openshift.io,resources=machineconfignodes,verbs=get;list;watch
+// +kubebuilder:rbac:groups=config.openshift.io,resources=nodes;clusterversions;featuregates,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=nodes,verbs=get;list;watch
+// +kubebuilder:rbac:groups=apps,resources=controllerrevisions,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups=operator.openshift.io,resources=machineconfigurations,verbs=get;list;watch;update;patch
+
+// Reconcile renders the MachineConfigs of the NodeSwap and applies them to
+// the selected MachineConfigPools, or removes them once the NodeSwap is
+// deleted. The outcome is always reported in the status, whether the spec
+// was reconciled or not. Unmet prerequisites, conflicts and pools that
+// cannot be targeted block the rollout without being retried with backoff,
+// and an invalid spec is not retried until it is edited.
 func (r *NodeSwapReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	_ = logf.FromContext(ctx)
-	r.ctx = ctx
+	s := &reconcileState{ctx: ctx}
 
-	if err := r.Get(ctx, req.NamespacedName, &r.desiredNodeSwap); err != nil {
+	if err := r.Get(ctx, req.NamespacedName, &s.desiredNodeSwap); err != nil {
 		if apierrors.IsNotFound(err) {
 			logf.FromContext(ctx).Info("NodeSwap resource not found. Ignoring since object must be deleted")
 			return ctrl.Result{}, nil
@@ -87,11 +221,15 @@ func (r *NodeSwapReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 		return ctrl.Result{}, err
 	}
 
+	if !s.desiredNodeSwap.DeletionTimestamp.IsZero() {
+		return r.ReconcileDeletion(s)
+	}
+
 	// Reconcile the spec and capture any errors
-	_, reconcileErr := r.ReconcileSpec()
+	result, reconcileErr := r.ReconcileSpec(s)
 
 	// Always update status with the result (success or failure)
-	if _, statusErr := r.ReconcileStatus(reconcileErr); statusErr != nil {
+	if _, statusErr := r.ReconcileStatus(s, reconcileErr); statusErr != nil {
 		logf.FromContext(ctx).Error(statusErr, "Failed to update status")
 		// Return both errors if status update fails
 		if reconcileErr != nil {
@@ -100,186 +238,557 @@ func (r *NodeSwapReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 		return ctrl.Result{}, statusErr
 	}
 
+	// Unmet prerequisites are reported through the status and re-checked
+	// periodically rather than retried with backoff.
+	var prereqErr *prerequisitesError
+	if goerrors.As(reconcileErr, &prereqErr) {
+		return result, nil
+	}
+	// Node labels are not watched, so partially covered pools are
+	// re-checked periodically as well.
+	var coverageErr *poolCoverageError
+	if goerrors.As(reconcileErr, &coverageErr) {
+		return ctrl.Result{RequeueAfter: prerequisitesRequeueInterval}, nil
+	}
+	var conflictErr *conflictError
+	if goerrors.As(reconcileErr, &conflictErr) {
+		return result, nil
+	}
+	// MachineConfigPools are watched, the NodeSwap is reconciled again once
+	// one matches.
+	var noPoolsErr *noMatchingPoolsError
+	if goerrors.As(reconcileErr, &noPoolsErr) {
+		return result, nil
+	}
+	// Retrying an invalid spec fails the same way until it is edited.
+	if errorReason(reconcileErr) == reasonInvalidSpec {
+		return result, reconcile.TerminalError(reconcileErr)
+	}
+
 	// Return the original reconcile error (status was updated successfully)
-	return ctrl.Result{}, reconcileErr
+	return result, reconcileErr
+}
+
+// errorReason returns the condition reason of a reconciliation failure.
+func errorReason(err error) string {
+	var invalidSpecErr *renderconfig.InvalidSpecError
+	var specErr *specError
+	var renderErr *template.RenderError
+	var poolErr *poolNotFoundError
+	var prereqErr *prerequisitesError
+	var conflictErr *conflictError
+	var coverageErr *poolCoverageError
+	var noPoolsErr *noMatchingPoolsError
+	var statusErr apierrors.APIStatus
+	switch {
+	case goerrors.As(err, &invalidSpecErr), goerrors.As(err, &specErr):
+		return reasonInvalidSpec
+	case goerrors.As(err, &renderErr):
+		return reasonRenderFailed
+	case goerrors.As(err, &poolErr):
+		return reasonPoolNotFound
+	case goerrors.As(err, &prereqErr):
+		return reasonPrerequisitesNotMet
+	case goerrors.As(err, &conflictErr):
+		return reasonConflictingNodeSwap
+	case goerrors.As(err, &coverageErr):
+		return reasonPoolsPartiallyCovered
+	case goerrors.As(err, &noPoolsErr):
+		return reasonNoMatchingPools
+	case goerrors.As(err, &statusErr), meta.IsNoMatchError(err):
+		return reasonAPIError
+	default:
+		return reasonReconciliationFailed
+	}
+}
+
+// blockedRolloutMessages are the Progressing messages of the failures that
+// block the rollout until the cluster or the spec changes.
+var blockedRolloutMessages = map[string]string{
+	reasonPrerequisitesNotMet:   "Rollout blocked until the cluster prerequisites are met",
+	reasonConflictingNodeSwap:   "Rollout blocked until the conflict is resolved",
+	reasonPoolsPartiallyCovered: "Rollout blocked until the node selector covers whole MachineConfigPools",
+	reasonNoMatchingPools:       "Rollout blocked until a MachineConfigPool matches",
 }
 
-func (r *NodeSwapReconciler) ReconcileStatus(reconcileErr error) (ctrl.Result, error) {
+// setFailureConditions reports a reconciliation failure on the Degraded,
+// Progressing and Available conditions, with the reason of the error.
+func setFailureConditions(conditions *[]metav1.Condition, err error) {
+	reason := errorReason(err)
+	degradedMessage := fmt.Sprintf("Failed to reconcile: %v", err)
+	progressingMessage := "Reconciliation failed"
+	if blocked, ok := blockedRolloutMessages[reason]; ok {
+		degradedMessage = err.Error()
+		progressingMessage = blocked
+	}
+
+	meta.SetStatusCondition(conditions, metav1.Condition{
+		Type:    typeDegradedNodeSwap,
+		Status:  metav1.ConditionTrue,
+		Reason:  reason,
+		Message: degradedMessage,
+	})
+	meta.SetStatusCondition(conditions, metav1.Condition{
+		Type:    typeProgressingNodeSwap,
+		Status:  metav1.ConditionFalse,
+		Reason:  reason,
+		Message: progressingMessage,
+	})
+	meta.SetStatusCondition(conditions, metav1.Condition{
+		Type:    typeAvailableNodeSwap,
+		Status:  metav1.ConditionFalse,
+		Reason:  reason,
+		Message: "",
+	})
+}
+
+func (r *NodeSwapReconciler) ReconcileStatus(s *reconcileState, reconcileErr error) (ctrl.Result, error) {
+	previous := s.desiredNodeSwap.Status.DeepCopy()
+	meta.RemoveStatusCondition(&s.desiredNodeSwap.Status.Conditions, legacyTypeAvailableNodeSwap)
+
+	var prereqErr *prerequisitesError
+	var conflictErr *conflictError
+	var noPoolsErr *noMatchingPoolsError
 	if reconcileErr != nil {
-		meta.SetStatusCondition(&r.desiredNodeSwap.Status.Conditions, metav1.Condition{
-			Type:    typeDegradedNodeSwap,
-			Status:  metav1.ConditionTrue,
-			Reason:  "ReconciliationFailed",
-			Message: fmt.Sprintf("Failed to reconcile: %v", reconcileErr),
-		})
-		meta.SetStatusCondition(&r.desiredNodeSwap.Status.Conditions, metav1.Condition{
-			Type:    typeProgressingNodeSwap,
-			Status:  metav1.ConditionFalse,
-			Reason:  "ReconciliationFailed",
-			Message: "Reconciliation failed",
-		})
-		meta.SetStatusCondition(&r.desiredNodeSwap.Status.Conditions, metav1.Condition{
-			Type:    typeAvailableNodeSwap,
+		setFailureConditions(&s.desiredNodeSwap.Status.Conditions, reconcileErr)
+	}
+	if goerrors.As(reconcileErr, &prereqErr) {
+		meta.SetStatusCondition(&s.desiredNodeSwap.Status.Conditions, metav1.Condition{
+			Type:    typePrerequisitesMetNodeSwap,
 			Status:  metav1.ConditionFalse,
-			Reason:  "ReconciliationFailed",
-			Message: "",
+			Reason:  reasonPrerequisitesNotMet,
+			Message: prereqErr.Error(),
 		})
-	} else if len(r.desiredNodeSwap.Status.Conditions) == 0 {
-		meta.SetStatusCondition(&r.desiredNodeSwap.Status.Conditions, metav1.Condition{
-			Type:    typeProgressingNodeSwap,
+	} else if goerrors.As(reconcileErr, &conflictErr) {
+		meta.SetStatusCondition(&s.desiredNodeSwap.Status.Conditions, metav1.Condition{
+			Type:    typeConflictNodeSwap,
 			Status:  metav1.ConditionTrue,
-			Reason:  "Reconciling",
-			Message: "NodeSwap is reconciling",
+			Reason:  reasonConflictingNodeSwap,
+			Message: conflictErr.Error(),
 		})
-	} else {
-		meta.SetStatusCondition(&r.desiredNodeSwap.Status.Conditions, metav1.Condition{
-			Type:    typeAvailableNodeSwap,
+	} else if goerrors.As(reconcileErr, &noPoolsErr) {
+		// The deleted pools are kept in the status until their
+		// MachineConfigs are removed.
+		if noPoolsErr.removed {
+			setRolloutStatus(&s.desiredNodeSwap.Status, nil, nil, nil, nodeswap.NodeRolloutStatus{})
+		}
+	} else if reconcileErr == nil {
+		meta.SetStatusCondition(&s.desiredNodeSwap.Status.Conditions, metav1.Condition{
+			Type:    typePrerequisitesMetNodeSwap,
 			Status:  metav1.ConditionTrue,
-			Reason:  "ReconciliationSucceeded",
-			Message: "NodeSwap successfully reconciled",
-		})
-		meta.SetStatusCondition(&r.desiredNodeSwap.Status.Conditions, metav1.Condition{
-			Type:    typeDegradedNodeSwap,
-			Status:  metav1.ConditionFalse,
-			Reason:  "ReconciliationSucceeded",
+			Reason:  "PrerequisitesMet",
 			Message: "",
 		})
-		meta.SetStatusCondition(&r.desiredNodeSwap.Status.Conditions, metav1.Condition{
-			Type:    typeProgressingNodeSwap,
+		meta.SetStatusCondition(&s.desiredNodeSwap.Status.Conditions, metav1.Condition{
+			Type:    typeConflictNodeSwap,
 			Status:  metav1.ConditionFalse,
-			Reason:  "ReconciliationSucceeded",
+			Reason:  "NoConflict",
 			Message: "",
 		})
+		if s.hold == reasonDryRun {
+			message := fmt.Sprintf("Dry run: %d MachineConfigs to create, %d to update, %d to delete",
+				len(s.pending.Create), len(s.pending.Update), len(s.pending.Delete))
+			for _, conditionType := range []string{typeProgressingNodeSwap, typeAvailableNodeSwap} {
+				meta.SetStatusCondition(&s.desiredNodeSwap.Status.Conditions, metav1.Condition{
+					Type:    conditionType,
+					Status:  metav1.ConditionFalse,
+					Reason:  reasonDryRun,
+					Message: message,
+				})
+			}
+		} else {
+			for _, condition := range rolloutConditions(s.pools, s.machineConfigs, s.renderedPools) {
+				meta.SetStatusCondition(&s.desiredNodeSwap.Status.Conditions, condition)
+			}
+		}
+		if s.rolloutWaiting != "" {
+			meta.SetStatusCondition(&s.desiredNodeSwap.Status.Conditions, metav1.Condition{
+				Type:    typeProgressingNodeSwap,
+				Status:  metav1.ConditionTrue,
+				Reason:  reasonStagedRollout,
+				Message: "Staged rollout " + s.rolloutWaiting,
+			})
+		}
+		if s.hold == reasonOutsideMaintenanceWindow && hasChanges(s.pending) {
+			meta.SetStatusCondition(&s.desiredNodeSwap.Status.Conditions, metav1.Condition{
+				Type:   typeProgressingNodeSwap,
+				Status: metav1.ConditionFalse,
+				Reason: reasonOutsideMaintenanceWindow,
+				Message: fmt.Sprintf("%d MachineConfigs to create, %d to update, %d to delete in the maintenance window starting at %s",
+					len(s.pending.Create), len(s.pending.Update), len(s.pending.Delete),
+					s.maintenanceWindow.Start.UTC().Format(time.RFC3339)),
+			})
+		}
+		if s.hold == reasonWaitingForUpgrade && hasChanges(s.pending) {
+			meta.SetStatusCondition(&s.desiredNodeSwap.Status.Conditions, metav1.Condition{
+				Type:   typeProgressingNodeSwap,
+				Status: metav1.ConditionFalse,
+				Reason: reasonWaitingForUpgrade,
+				Message: fmt.Sprintf("%d MachineConfigs to create, %d to update, %d to delete once the cluster upgrade to %s completes",
+					len(s.pending.Create), len(s.pending.Update), len(s.pending.Delete), s.upgradeVersion),
+			})
+		}
+		if s.desiredNodeSwap.Spec.Paused {
+			meta.SetStatusCondition(&s.desiredNodeSwap.Status.Conditions, metav1.Condition{
+				Type:    typeProgressingNodeSwap,
+				Status:  metav1.ConditionFalse,
+				Reason:  reasonPaused,
+				Message: "Rollout paused until spec.paused is unset",
+			})
+		}
+		switch {
+		case len(s.drift) > 0 && (s.desiredNodeSwap.Spec.DriftPolicy == nodeswap.DriftPolicyReport || s.hold != ""):
+			meta.SetStatusCondition(&s.desiredNodeSwap.Status.Conditions, metav1.Condition{
+				Type:    typeDriftedNodeSwap,
+				Status:  metav1.ConditionTrue,
+				Reason:  reasonOutOfBandChanges,
+				Message: driftSummary(s.drift),
+			})
+		case len(s.drift) > 0:
+			meta.SetStatusCondition(&s.desiredNodeSwap.Status.Conditions, metav1.Condition{
+				Type:    typeDriftedNodeSwap,
+				Status:  metav1.ConditionFalse,
+				Reason:  reasonDriftReverted,
+				Message: "Reverted " + driftSummary(s.drift),
+			})
+		case !meta.IsStatusConditionFalse(s.desiredNodeSwap.Status.Conditions, typeDriftedNodeSwap):
+			meta.SetStatusCondition(&s.desiredNodeSwap.Status.Conditions, metav1.Condition{
+				Type:    typeDriftedNodeSwap,
+				Status:  metav1.ConditionFalse,
+				Reason:  "NoDrift",
+				Message: "",
+			})
+		}
+		if available := meta.FindStatusCondition(s.desiredNodeSwap.Status.Conditions, typeAvailableNodeSwap); available != nil &&
+			available.Status == metav1.ConditionTrue && !rolledBack(&s.desiredNodeSwap) &&
+			s.desiredNodeSwap.Spec.RollbackToRevision == "" {
+			s.desiredNodeSwap.Status.LastAppliedSpec = s.desiredNodeSwap.Spec.DeepCopy()
+		}
+	}
+
+	if rolledBack(&s.desiredNodeSwap) {
+		rollback := s.desiredNodeSwap.Status.Rollback
+		message := fmt.Sprintf("Rolled back generation %d, MachineConfigPool %s is degraded", rollback.Generation,
+			rollback.MachineConfigPool)
+		if rollback.Node != "" {
+			message += fmt.Sprintf(", node %s failed", rollback.Node)
+		}
+		message += fmt.Sprintf(": %s: %s", rollback.Reason, rollback.Message)
+		meta.SetStatusCondition(&s.desiredNodeSwap.Status.Conditions, metav1.Condition{
+			Type:    typeRolledBackNodeSwap,
+			Status:  metav1.ConditionTrue,
+			Reason:  reasonRolledBack,
+			Message: message,
+		})
+	} else {
+		meta.RemoveStatusCondition(&s.desiredNodeSwap.Status.Conditions, typeRolledBackNodeSwap)
 	}
 
-	if err := r.Status().Update(r.ctx, &r.desiredNodeSwap); err != nil {
-		logf.FromContext(r.ctx).Error(err, "Failed to update NodeSwap status")
+	// The rollout is only known once the spec was reconciled, keep the last
+	// reported one otherwise.
+	if reconcileErr == nil {
+		setRolloutStatus(&s.desiredNodeSwap.Status, s.pools, s.machineConfigs, s.renderedPools, s.nodes)
+		s.desiredNodeSwap.Status.PausedPools = s.pausedPools
+		s.desiredNodeSwap.Status.DryRun, s.desiredNodeSwap.Status.PendingChanges = nil, nil
+		switch {
+		case s.hold == reasonDryRun:
+			s.desiredNodeSwap.Status.DryRun = s.pending
+		case hasChanges(s.pending):
+			s.desiredNodeSwap.Status.PendingChanges = s.pending
+		}
+		s.desiredNodeSwap.Status.MaintenanceWindow = s.maintenanceWindow
+		if s.hold == "" {
+			s.desiredNodeSwap.Status.NodeDisruptionPolicy = s.nodeDisruptionPolicy
+		}
+		if s.currentRevision != "" {
+			s.desiredNodeSwap.Status.CurrentRevision = s.currentRevision
+		}
+	}
+	s.desiredNodeSwap.Status.ObservedGeneration = s.desiredNodeSwap.Generation
+
+	if err := r.Status().Update(s.ctx, &s.desiredNodeSwap); err != nil {
+		logf.FromContext(s.ctx).Error(err, "Failed to update NodeSwap status")
 		return ctrl.Result{}, err
 	}
+	r.recordStatusEvents(s, previous)
 
 	return ctrl.Result{}, nil
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *NodeSwapReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &nodeswap.NodeSwap{},
+		poolNameIndex, indexPoolNames); err != nil {
+		return err
+	}
+
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&nodeswap.NodeSwap{}).
-		Watches(&mcfgv1.MachineConfigPool{}, &handler.EnqueueRequestForObject{}).
-		Owns(&mcfgv1.MachineConfig{}).
+		Watches(&mcfgv1.MachineConfigPool{}, poolEventHandler(r.poolRequests),
+			builder.WithPredicates(machineConfigPoolPredicate())).
+		Watches(&mcfgv1.MachineConfig{}, handler.EnqueueRequestsFromMapFunc(ownerRequests)).
+		Watches(&configv1.ClusterVersion{}, handler.EnqueueRequestsFromMapFunc(r.clusterVersionRequests),
+			builder.WithPredicates(clusterVersionPredicate())).
+		WithOptions(controller.Options{MaxConcurrentReconciles: r.MaxConcurrentReconciles}).
 		Named("nodeswap").
 		Complete(r)
 }
 
-func (r *NodeSwapReconciler) ReconcileKubeletCgroups() (ctrl.Result, error) {
-	var kubeletMachineConfig mcfgv1.MachineConfig
-	if err := r.Get(r.ctx,
-		types.NamespacedName{Name: renderconfig.SwapKubeletCgroupsMCPrefix},
-		&kubeletMachineConfig); err != nil {
-		if apierrors.IsNotFound(err) {
-			fullTemplatePath := filepath.Join(r.TemplateDir, "worker", "99-swap-kubelet-cgroups")
-			mc, err := template.GenerateMachineConfigForName(
-				&renderconfig.RenderConfig{},
-				"worker",
-				"99-swap-kubelet-cgroups",
-				r.TemplateDir,
-				fullTemplatePath,
-			)
-			if err != nil {
-				logf.FromContext(r.ctx).Error(err, "Failed to render kubelet machine config")
-				return ctrl.Result{}, err
-			}
+// ReconcileKubeletCgroups creates or updates the kubelet MachineConfig of
+// the NodeSwap, labeled for the pools the MachineConfigs are rolled out to,
+// and releases the kubelet MachineConfig NodeSwaps used to share.
+func (r *NodeSwapReconciler) ReconcileKubeletCgroups(s *reconcileState) (ctrl.Result, error) {
+	mc, err := r.desiredKubeletMachineConfig(s)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := r.applyMachineConfig(s, mc); err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := s.recordMachineConfig(mc); err != nil {
+		return ctrl.Result{}, err
+	}
 
-			mcBytes, err := yaml.Marshal(mc)
-			if err != nil {
-				logf.FromContext(r.ctx).Error(err, "Failed to marshal MachineConfig")
-			} else {
-				mcBase64 := base64.StdEncoding.EncodeToString(mcBytes)
-				logf.FromContext(r.ctx).Info("Generated MachineConfig", "base64", mcBase64)
-			}
+	return ctrl.Result{}, r.releaseSharedKubeletMachineConfig(s)
+}
 
-			if mc.ObjectMeta.Labels == nil {
-				mc.ObjectMeta.Labels = map[string]string{}
-			}
+// desiredKubeletMachineConfig returns the kubelet MachineConfig of the
+// NodeSwap, labeled for the selected pools.
+func (r *NodeSwapReconciler) desiredKubeletMachineConfig(s *reconcileState) (*mcfgv1.MachineConfig, error) {
+	config := renderconfig.CreateKubelet(s.desiredNodeSwap.Name)
+	mc, err := r.renderMachineConfig(s, &config)
+	if err != nil {
+		logf.FromContext(s.ctx).Error(err, "Failed to render kubelet machine config")
+		return nil, err
+	}
 
-			key, value, err := parseLabelSelector(r.desiredNodeSwap.Spec.MachineConfigPoolSelector)
-			if err != nil {
-				logf.FromContext(r.ctx).Error(err, "Failed to parse label selector")
-				return ctrl.Result{}, err
-			}
-			mc.ObjectMeta.Labels[key] = value
-
-			if err := r.Create(r.ctx, mc); errors.IsAlreadyExists(err) {
-				logf.FromContext(r.ctx).Info("Kubelet machine config already exists")
-				return ctrl.Result{}, nil
-			} else if err != nil {
-				logf.FromContext(r.ctx).Error(err, "Failed to create kubelet machine config")
-				return ctrl.Result{}, err
-			}
+	if mc.Labels == nil {
+		mc.Labels = map[string]string{}
+	}
+	maps.Copy(mc.Labels, s.mcLabels)
+	maps.Copy(mc.Labels, ownerLabels(s))
+	if err := setMachineConfigHash(mc); err != nil {
+		return nil, err
+	}
 
-			return ctrl.Result{}, nil
+	return mc, nil
+}
+
+// releaseSharedKubeletMachineConfig removes the NodeSwap from the owners of
+// the kubelet MachineConfig NodeSwaps used to share, labeled for the roles of
+// the first of them only, and deletes it once no NodeSwap owns it anymore.
+func (r *NodeSwapReconciler) releaseSharedKubeletMachineConfig(s *reconcileState) error {
+	shared := &mcfgv1.MachineConfig{}
+	if err := r.Get(s.ctx, types.NamespacedName{Name: renderconfig.SwapKubeletCgroupsMCPrefix}, shared); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
 		}
-		logf.FromContext(r.ctx).Error(err, "Failed to get kubelet machine config")
-		return ctrl.Result{}, err
+		logf.FromContext(s.ctx).Error(err, "Failed to get shared kubelet machine config")
+		return err
 	}
 
-	return ctrl.Result{}, nil
+	owners := slices.DeleteFunc(slices.Clone(shared.OwnerReferences), func(ref metav1.OwnerReference) bool {
+		return ref.Kind == "NodeSwap" && ref.Name == s.desiredNodeSwap.Name
+	})
+	if len(owners) == len(shared.OwnerReferences) {
+		return nil
+	}
+	if len(owners) == 0 {
+		return r.deleteMachineConfig(s, shared.Name)
+	}
+
+	shared.OwnerReferences = owners
+	if err := r.Update(s.ctx, shared); err != nil {
+		logf.FromContext(s.ctx).Error(err, "Failed to release shared kubelet machine config")
+		return err
+	}
+	logf.FromContext(s.ctx).Info("Released shared kubelet machine config", "name", shared.Name)
+
+	return nil
 }
 
-func (r *NodeSwapReconciler) ReconcileSpec() (ctrl.Result, error) {
-	config, err := renderconfig.Create(&r.desiredNodeSwap.Spec)
+func (r *NodeSwapReconciler) ReconcileSpec(s *reconcileState) (ctrl.Result, error) {
+	if err := r.ensureFinalizer(s); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if result, err := r.ReconcilePrerequisites(s); err != nil {
+		return result, err
+	}
+
+	if result, err := r.ReconcileConflicts(s); err != nil {
+		return result, err
+	}
+
+	if s.desiredNodeSwap.Spec.DryRun {
+		s.hold = reasonDryRun
+	}
+	r.ReconcileClusterUpgrade(s)
+	windowResult, err := r.ReconcileMaintenanceWindow(s)
+	if err != nil {
+		return windowResult, err
+	}
+
+	// Holding the MachineConfigs back holds the dedicated pool back as well.
+	var poolResult ctrl.Result
+	if s.hold == "" {
+		result, err := r.ReconcileDedicatedPool(s)
+		if err != nil {
+			return result, err
+		}
+		poolResult = result
+	}
+
+	// Render the spec early, so that an invalid one changes nothing.
+	configs, err := renderconfig.Create(s.desiredNodeSwap.Name, &s.desiredNodeSwap.Spec)
 	if err != nil {
-		logf.FromContext(r.ctx).Error(err, "Failed to create render config")
+		logf.FromContext(s.ctx).Error(err, "Failed to create render config")
+		return ctrl.Result{}, err
+	}
+	if err := r.checkTemplates(configs); err != nil {
+		logf.FromContext(s.ctx).Error(err, "Failed to find templates")
 		return ctrl.Result{}, err
 	}
-	r.config = config
 
 	// List all MachineConfigPools
 	mcpList := &mcfgv1.MachineConfigPoolList{}
-	if err := r.List(r.ctx, mcpList); err != nil {
-		logf.FromContext(r.ctx).Error(err, "Failed to list MachineConfigPools")
+	if err := r.List(s.ctx, mcpList); err != nil {
+		logf.FromContext(s.ctx).Error(err, "Failed to list MachineConfigPools")
 		return ctrl.Result{}, err
 	}
 
-	// Parse the desired label selector
-	labelKey, labelValue, err := parseLabelSelector(r.desiredNodeSwap.Spec.MachineConfigPoolSelector)
+	// Build the desired pool selector
+	selector, err := newPoolSelector(&s.desiredNodeSwap.Spec)
 	if err != nil {
-		logf.FromContext(r.ctx).Error(err, "Failed to parse label selector")
+		logf.FromContext(s.ctx).Error(err, "Failed to parse label selector")
 		return ctrl.Result{}, err
 	}
 
-	// Filter MachineConfigPools that match the selector
-	var matchingMCPs []*mcfgv1.MachineConfigPool
-	var updatedMCPs, notUpdatedMCPs []*mcfgv1.MachineConfigPool
-
-	for i := range mcpList.Items {
-		mcp := &mcpList.Items[i]
-		if mcp.Spec.MachineConfigSelector != nil &&
-			mcp.Spec.MachineConfigSelector.MatchLabels != nil {
-			if value, exists :=
-				mcp.Spec.MachineConfigSelector.MatchLabels[labelKey]; exists && value == labelValue {
-				matchingMCPs = append(matchingMCPs, mcp)
-
-				if isMachineConfigPoolUpdated(mcp) {
-					updatedMCPs = append(updatedMCPs, mcp)
-					logf.FromContext(r.ctx).Info("MachineConfigPool is updated",
-						"name", mcp.Name)
-				} else {
-					notUpdatedMCPs = append(notUpdatedMCPs, mcp)
-					logf.FromContext(r.ctx).Info("MachineConfigPool is not yet updated",
-						"name", mcp.Name)
-				}
-			}
+	// Nodes are only needed to resolve the pools covering the node selector
+	var nodes []corev1.Node
+	if s.desiredNodeSwap.Spec.NodeSelector != nil {
+		nodeList := &corev1.NodeList{}
+		if err := r.List(s.ctx, nodeList); err != nil {
+			logf.FromContext(s.ctx).Error(err, "Failed to list Nodes")
+			return ctrl.Result{}, err
 		}
+		nodes = nodeList.Items
+	}
+
+	// While the NodeSwap is held, its dedicated pool is not created yet:
+	// select the pools as if it were, so that the nodes it takes are not
+	// reported as partially covering their pool.
+	pools := mcpList.Items
+	if dedicated := s.desiredNodeSwap.Spec.DedicatedPool; s.hold != "" && dedicated != nil &&
+		!slices.ContainsFunc(pools, func(mcp mcfgv1.MachineConfigPool) bool { return mcp.Name == dedicated.Name }) {
+		pools = append(slices.Clone(pools), *r.desiredDedicatedPool(s))
+		s.pendingPool = dedicated.Name
+	}
+
+	selection, err := selectPools(&s.desiredNodeSwap.Spec, selector, pools, nodes)
+	if err != nil {
+		logf.FromContext(s.ctx).Error(err, "Failed to select MachineConfigPools")
+		return ctrl.Result{}, err
 	}
+	if len(selection.PartiallyCovered) > 0 {
+		err := &poolCoverageError{pools: selection.PartiallyCovered}
+		logf.FromContext(s.ctx).Info("Node selector partially covers MachineConfigPools",
+			"pools", selection.PartiallyCovered)
+		return ctrl.Result{}, err
+	}
+	if len(selection.Pools) == 0 {
+		logf.FromContext(s.ctx).Info("No MachineConfigPool matches the NodeSwap")
+		return ctrl.Result{}, r.ReconcileNoMatchingPools(s, mcpList.Items)
+	}
+
+	names := make([]string, 0, len(selection.Pools))
+	for _, mcp := range selection.Pools {
+		names = append(names, mcp.Name)
+	}
+	logf.FromContext(s.ctx).Info("Selected MachineConfigPools", "names", names)
 
-	logf.FromContext(r.ctx).Info("MachineConfigPool filtering complete",
-		"matchingCount", len(matchingMCPs),
-		"updatedCount", len(updatedMCPs),
-		"notUpdatedCount", len(notUpdatedMCPs))
+	// Reject pools that cannot share the MachineConfigs before any of them
+	// is paused or staged.
+	if _, err := machineConfigLabels(selector, selection.Pools); err != nil {
+		logf.FromContext(s.ctx).Error(err, "Failed to determine MachineConfig labels")
+		return ctrl.Result{}, err
+	}
+
+	s.pools = selection.Pools
 
-	r.mcpReady = len(notUpdatedMCPs) == 0
+	// Pause the pools before the MachineConfigs change, so that the change
+	// is only rolled out once they are resumed. The pools held back by the
+	// staged rollout stay paused until the rollout is planned again.
+	s.heldPools = s.desiredNodeSwap.Status.PausedPools
+	if !s.desiredNodeSwap.Spec.DryRun {
+		if err := r.ReconcilePause(s); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
 
-	return r.ReconcileKubeletCgroups()
+	if err := r.ReconcileRollback(s); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	rolloutPools, rolloutResult, err := r.ReconcileRollout(s, nodes)
+	if err != nil {
+		return rolloutResult, err
+	}
+	if !s.desiredNodeSwap.Spec.DryRun {
+		if err := r.ReconcilePause(s); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	s.mcLabels, err = machineConfigLabels(selector, rolloutPools)
+	if err != nil {
+		logf.FromContext(s.ctx).Error(err, "Failed to determine MachineConfig labels")
+		return ctrl.Result{}, err
+	}
+
+	if s.hold != "" {
+		if err := r.ReconcileDryRun(s); err != nil {
+			return ctrl.Result{}, err
+		}
+		return windowResult, r.ReconcileRenderedContent(s)
+	}
+
+	if result, err := r.ReconcileKubeletCgroups(s); err != nil {
+		return result, err
+	}
+
+	result, err := r.ReconcileMachineConfigs(s)
+	if err != nil || !result.IsZero() {
+		return result, err
+	}
+
+	if err := r.ReconcileRenderedContent(s); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if err := r.ReconcileNodeRollout(s); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if err := r.ReconcileRevisions(s); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if err := r.cleanupDryRun(s); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// Keep checking on the teardown of pools no longer requested, on the
+	// next stage of the rollout and on the end of the maintenance window.
+	return earliestRequeue(earliestRequeue(poolResult, rolloutResult), windowResult), nil
+}
+
+// earliestRequeue returns the result requeuing the soonest.
+func earliestRequeue(a, b ctrl.Result) ctrl.Result {
+	if a.RequeueAfter == 0 || (b.RequeueAfter != 0 && b.RequeueAfter < a.RequeueAfter) {
+		return b
+	}
+	return a
 }
 
 // parseLabelSelector parses a label selector string in the format "key:" or "key:value"
@@ -303,26 +812,3 @@ func parseLabelSelector(selector string) (string, string, error) {
 
 	return key, value, nil
 }
-
-// isMachineConfigPoolUpdated checks if a MachineConfigPool is fully updated.
-// Returns true if the pool's Updated condition is True, and both Updating and Degraded are False.
-func isMachineConfigPoolUpdated(mcp *mcfgv1.MachineConfigPool) bool {
-	var updated, updating, degraded bool
-
-	for _, condition := range mcp.Status.Conditions {
-		switch condition.Type {
-		case mcfgv1.MachineConfigPoolUpdated:
-			updated = condition.Status == corev1.ConditionTrue
-		case mcfgv1.MachineConfigPoolUpdating:
-			updating = condition.Status == corev1.ConditionTrue
-		case mcfgv1.MachineConfigPoolDegraded:
-			degraded = condition.Status == corev1.ConditionTrue
-		}
-	}
-
-	// Pool is considered updated if:
-	// - Updated condition is True
-	// - Updating condition is False (or not found)
-	// - Degraded condition is False (or not found)
-	return updated && !updating && !degraded
-}
@@ -18,17 +18,31 @@ package controller
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
 
+	ign3types "github.com/coreos/ignition/v2/config/v3_5/types"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	apiresource "k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
-	nodeswapv1alpha1 "github.com/openshift-virtualization/swap-operator/api/v1alpha1"
+	nodeswapv1beta1 "github.com/openshift-virtualization/swap-operator/api/v1beta1"
+	"github.com/openshift-virtualization/swap-operator/internal/common"
+	"github.com/openshift-virtualization/swap-operator/internal/renderconfig"
+	configv1 "github.com/openshift/api/config/v1"
+	mcfgv1 "github.com/openshift/api/machineconfiguration/v1"
 )
 
 var _ = Describe("NodeSwap Controller", func() {
@@ -38,22 +52,20 @@ var _ = Describe("NodeSwap Controller", func() {
 		ctx := context.Background()
 
 		typeNamespacedName := types.NamespacedName{
-			Name:      resourceName,
-			Namespace: "default", // TODO(user):Modify as needed
+			Name: resourceName,
 		}
-		nodeswap := &nodeswapv1alpha1.NodeSwap{}
+		nodeswap := &nodeswapv1beta1.NodeSwap{}
 
 		BeforeEach(func() {
 			By("creating the custom resource for the Kind NodeSwap")
 			err := k8sClient.Get(ctx, typeNamespacedName, nodeswap)
 			if err != nil && errors.IsNotFound(err) {
-				resource := &nodeswapv1alpha1.NodeSwap{
+				resource := &nodeswapv1beta1.NodeSwap{
 					ObjectMeta: metav1.ObjectMeta{
-						Name:      resourceName,
-						Namespace: "default",
+						Name: resourceName,
 					},
-					Spec: nodeswapv1alpha1.NodeSwapSpec{
-						MachineConfigPoolSelector: "node-role.kubernetes.io/role:worker",
+					Spec: nodeswapv1beta1.NodeSwapSpec{
+						MachineConfigPoolSelector: "node-role.kubernetes.io/role:test",
 					},
 				}
 				Expect(k8sClient.Create(ctx, resource)).To(Succeed())
@@ -62,14 +74,24 @@ var _ = Describe("NodeSwap Controller", func() {
 
 		AfterEach(func() {
 			// TODO(user): Cleanup logic after each test, like removing the resource instance.
-			resource := &nodeswapv1alpha1.NodeSwap{}
+			resource := &nodeswapv1beta1.NodeSwap{}
 			err := k8sClient.Get(ctx, typeNamespacedName, resource)
 			Expect(err).NotTo(HaveOccurred())
 
 			By("Cleanup the specific resource instance NodeSwap")
-			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+			deleteNodeSwap(ctx, resource)
 		})
 		It("should successfully reconcile the resource", func() {
+			By("Reporting the misspelled Available condition of earlier releases")
+			resource := &nodeswapv1beta1.NodeSwap{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			meta.SetStatusCondition(&resource.Status.Conditions, metav1.Condition{
+				Type:   legacyTypeAvailableNodeSwap,
+				Status: metav1.ConditionTrue,
+				Reason: "Reconciled",
+			})
+			Expect(k8sClient.Status().Update(ctx, resource)).To(Succeed())
+
 			By("Reconciling the created resource")
 			controllerReconciler := &NodeSwapReconciler{
 				Client: k8sClient,
@@ -80,23 +102,24 @@ var _ = Describe("NodeSwap Controller", func() {
 				NamespacedName: typeNamespacedName,
 			})
 			Expect(err).NotTo(HaveOccurred())
-			// TODO(user): Add more specific assertions depending on your controller's reconciliation logic.
-			// Example: If you expect a certain status condition after reconciliation, verify it here.
+
+			By("Verifying that the misspelled condition was removed")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(meta.FindStatusCondition(resource.Status.Conditions, legacyTypeAvailableNodeSwap)).To(BeNil())
+			Expect(meta.FindStatusCondition(resource.Status.Conditions, typeAvailableNodeSwap)).NotTo(BeNil())
 		})
 		It("should update status conditions when reconciliation fails", func() {
 			By("Creating a NodeSwap with invalid configuration")
 			invalidResourceName := "test-invalid-resource"
 			invalidTypeNamespacedName := types.NamespacedName{
-				Name:      invalidResourceName,
-				Namespace: "default",
+				Name: invalidResourceName,
 			}
 
-			invalidResource := &nodeswapv1alpha1.NodeSwap{
+			invalidResource := &nodeswapv1beta1.NodeSwap{
 				ObjectMeta: metav1.ObjectMeta{
-					Name:      invalidResourceName,
-					Namespace: "default",
+					Name: invalidResourceName,
 				},
-				Spec: nodeswapv1alpha1.NodeSwapSpec{
+				Spec: nodeswapv1beta1.NodeSwapSpec{
 					// Invalid selector format to trigger error
 					MachineConfigPoolSelector: "invalid-selector-no-colon",
 				},
@@ -118,14 +141,14 @@ var _ = Describe("NodeSwap Controller", func() {
 			Expect(err).To(HaveOccurred())
 
 			By("Verifying that status conditions reflect the error")
-			updatedResource := &nodeswapv1alpha1.NodeSwap{}
+			updatedResource := &nodeswapv1beta1.NodeSwap{}
 			Expect(k8sClient.Get(ctx, invalidTypeNamespacedName, updatedResource)).To(Succeed())
 
 			// Check that Degraded condition is True
 			degradedCondition := meta.FindStatusCondition(updatedResource.Status.Conditions, "Degraded")
 			Expect(degradedCondition).NotTo(BeNil())
 			Expect(degradedCondition.Status).To(Equal(metav1.ConditionTrue))
-			Expect(degradedCondition.Reason).To(Equal("ReconciliationFailed"))
+			Expect(degradedCondition.Reason).To(Equal(reasonInvalidSpec))
 			Expect(degradedCondition.Message).To(ContainSubstring("Failed to reconcile"))
 
 			// Check that Progressing condition is False
@@ -134,12 +157,1500 @@ var _ = Describe("NodeSwap Controller", func() {
 			Expect(progressingCondition.Status).To(Equal(metav1.ConditionFalse))
 
 			// Check that Available condition is False
-			availableCondition := meta.FindStatusCondition(updatedResource.Status.Conditions, "Availabe")
+			availableCondition := meta.FindStatusCondition(updatedResource.Status.Conditions, "Available")
 			Expect(availableCondition).NotTo(BeNil())
 			Expect(availableCondition.Status).To(Equal(metav1.ConditionFalse))
 
 			By("Cleaning up the invalid resource")
-			Expect(k8sClient.Delete(ctx, invalidResource)).To(Succeed())
+			deleteNodeSwap(ctx, invalidResource)
+		})
+		It("should reject a swap type without templates", func() {
+			By("Creating a NodeSwap with a zram swap")
+			zramName := types.NamespacedName{Name: "test-zram-resource"}
+			createPool(ctx, "worker", map[string]string{"node-role.kubernetes.io/role": "worker"})
+			zramResource := &nodeswapv1beta1.NodeSwap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: zramName.Name,
+				},
+				Spec: nodeswapv1beta1.NodeSwapSpec{
+					MachineConfigPoolSelector: "node-role.kubernetes.io/role:worker",
+					Swaps: nodeswapv1beta1.Swaps{{
+						SwapType: nodeswapv1beta1.SwapOnZram,
+						Zram:     &nodeswapv1beta1.SwapZram{Size: apiresource.MustParse("1Gi")},
+					}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, zramResource)).To(Succeed())
+			DeferCleanup(func() {
+				deleteNodeSwap(ctx, zramResource)
+			})
+
+			By("Reconciling the resource")
+			controllerReconciler := &NodeSwapReconciler{
+				Client:      k8sClient,
+				Scheme:      k8sClient.Scheme(),
+				TemplateDir: "../../templates",
+			}
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: zramName})
+			Expect(err).To(HaveOccurred())
+
+			By("Verifying the spec is reported invalid and nothing is rendered")
+			Expect(k8sClient.Get(ctx, zramName, zramResource)).To(Succeed())
+			degradedCondition := meta.FindStatusCondition(zramResource.Status.Conditions, typeDegradedNodeSwap)
+			Expect(degradedCondition).NotTo(BeNil())
+			Expect(degradedCondition.Status).To(Equal(metav1.ConditionTrue))
+			Expect(degradedCondition.Reason).To(Equal(reasonInvalidSpec))
+			Expect(degradedCondition.Message).To(ContainSubstring("no 99-zrambased-swap templates"))
+
+			mcs := &mcfgv1.MachineConfigList{}
+			Expect(k8sClient.List(ctx, mcs)).To(Succeed())
+			Expect(mcs.Items).To(BeEmpty())
+		})
+		It("should render the swap and systemd-oomd MachineConfigs", func() {
+			By("Creating a NodeSwap with a swap file and systemd-oomd enabled")
+			oomdName := types.NamespacedName{Name: "test-oomd-resource"}
+			swapMCName := "99-filebased-swap-test-oomd-resource-0"
+			oomdMCName := renderconfig.OomdMachineConfigName(oomdName.Name)
+			createPool(ctx, "worker", map[string]string{"node-role.kubernetes.io/role": "worker"})
+			oomdResource := &nodeswapv1beta1.NodeSwap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: oomdName.Name,
+				},
+				Spec: nodeswapv1beta1.NodeSwapSpec{
+					MachineConfigPoolSelector: "node-role.kubernetes.io/role:worker",
+					Swaps: nodeswapv1beta1.Swaps{{
+						SwapType: nodeswapv1beta1.FileBasedSwap,
+						File: &nodeswapv1beta1.SwapFile{
+							Path: "/var/swap",
+							Size: apiresource.MustParse("1Gi"),
+						},
+					}},
+					Oomd: &nodeswapv1beta1.OomdSpec{
+						SwapUsedLimit: "90%",
+						Slices: []nodeswapv1beta1.OomdSlice{
+							{Name: "kubepods.slice", ManagedOOMSwap: nodeswapv1beta1.ManagedOOMKill},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, oomdResource)).To(Succeed())
+			DeferCleanup(func() {
+				deleteNodeSwap(ctx, oomdResource)
+				for _, name := range []string{swapMCName, oomdMCName} {
+					mc := &mcfgv1.MachineConfig{ObjectMeta: metav1.ObjectMeta{Name: name}}
+					Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, mc))).To(Succeed())
+				}
+			})
+
+			recorder := record.NewFakeRecorder(100)
+			controllerReconciler := &NodeSwapReconciler{
+				Client:      k8sClient,
+				Scheme:      k8sClient.Scheme(),
+				TemplateDir: "../../templates",
+				Recorder:    recorder,
+			}
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: oomdName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(recordedEvents(recorder)).To(ContainElements(
+				"Normal MachineConfigCreated Created MachineConfig "+swapMCName,
+				"Normal MachineConfigCreated Created MachineConfig "+oomdMCName))
+
+			By("Verifying that reconciling again records no events")
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: oomdName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(recordedEvents(recorder)).To(BeEmpty())
+
+			By("Verifying that the MachineConfigs carry the pool selector label and their owner")
+			for _, name := range []string{swapMCName, oomdMCName} {
+				mc := &mcfgv1.MachineConfig{}
+				Expect(k8sClient.Get(ctx, types.NamespacedName{Name: name}, mc)).To(Succeed())
+				Expect(mc.Labels).To(HaveKeyWithValue("node-role.kubernetes.io/role", "worker"))
+				Expect(mc.Labels).To(HaveKeyWithValue(ownerNameLabel, oomdName.Name))
+				Expect(ownerRequests(ctx, mc)).To(ConsistOf(reconcile.Request{NamespacedName: oomdName}))
+			}
+			kubeletMC := &mcfgv1.MachineConfig{}
+			kubeletMCName := renderconfig.KubeletMachineConfigName(oomdName.Name)
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: kubeletMCName}, kubeletMC)).To(Succeed())
+			Expect(kubeletMC.Labels).To(HaveKeyWithValue("node-role.kubernetes.io/role", "worker"))
+			Expect(ownerRequests(ctx, kubeletMC)).To(ConsistOf(reconcile.Request{NamespacedName: oomdName}))
+
+			By("Verifying that the status reports the rendered MachineConfigs")
+			Expect(k8sClient.Get(ctx, oomdName, oomdResource)).To(Succeed())
+			Expect(oomdResource.Status.ObservedGeneration).To(Equal(oomdResource.Generation))
+			var rendered []string
+			for _, mc := range oomdResource.Status.MachineConfigs {
+				Expect(mc.Hash).To(HaveLen(64))
+				rendered = append(rendered, mc.Name)
+			}
+			Expect(rendered).To(ConsistOf(swapMCName, oomdMCName, kubeletMCName))
+
+			By("Reverting an out-of-band change to a MachineConfig")
+			swapMC := &mcfgv1.MachineConfig{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: swapMCName}, swapMC)).To(Succeed())
+			Expect(swapMC.Annotations).To(HaveKey(machineConfigHashAnnotation))
+			swapMC.Spec.KernelArguments = []string{"debug"}
+			Expect(k8sClient.Update(ctx, swapMC)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: oomdName})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: swapMCName}, swapMC)).To(Succeed())
+			Expect(swapMC.Spec.KernelArguments).To(BeEmpty())
+			Expect(k8sClient.Get(ctx, oomdName, oomdResource)).To(Succeed())
+			drifted := meta.FindStatusCondition(oomdResource.Status.Conditions, typeDriftedNodeSwap)
+			Expect(drifted).NotTo(BeNil())
+			Expect(drifted.Status).To(Equal(metav1.ConditionFalse))
+			Expect(drifted.Reason).To(Equal(reasonDriftReverted))
+			Expect(recordedEvents(recorder)).To(ContainElement("Normal MachineConfigUpdated Updated MachineConfig " + swapMCName))
+
+			By("Disabling systemd-oomd")
+			Expect(k8sClient.Get(ctx, oomdName, oomdResource)).To(Succeed())
+			oomdResource.Spec.Oomd = nil
+			Expect(k8sClient.Update(ctx, oomdResource)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: oomdName})
+			Expect(err).NotTo(HaveOccurred())
+
+			err = k8sClient.Get(ctx, types.NamespacedName{Name: oomdMCName}, &mcfgv1.MachineConfig{})
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+			Expect(recordedEvents(recorder)).To(ContainElement("Normal MachineConfigDeleted Deleted MachineConfig " + oomdMCName))
+		})
+		It("should only report the MachineConfigs of a dry run", func() {
+			By("Creating a NodeSwap with spec.dryRun")
+			dryRunName := types.NamespacedName{Name: "test-dry-run-resource"}
+			swapMCName := "99-filebased-swap-test-dry-run-resource-0"
+			createPool(ctx, "worker", map[string]string{"node-role.kubernetes.io/role": "worker"})
+			dryRunResource := &nodeswapv1beta1.NodeSwap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: dryRunName.Name,
+				},
+				Spec: nodeswapv1beta1.NodeSwapSpec{
+					MachineConfigPoolSelector: "node-role.kubernetes.io/role:worker",
+					DryRun:                    true,
+					Swaps: nodeswapv1beta1.Swaps{{
+						SwapType: nodeswapv1beta1.FileBasedSwap,
+						File: &nodeswapv1beta1.SwapFile{
+							Path: "/var/swap",
+							Size: apiresource.MustParse("1Gi"),
+						},
+					}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, dryRunResource)).To(Succeed())
+			DeferCleanup(func() {
+				deleteNodeSwap(ctx, dryRunResource)
+				mc := &mcfgv1.MachineConfig{ObjectMeta: metav1.ObjectMeta{Name: swapMCName}}
+				Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, mc))).To(Succeed())
+			})
+
+			controllerReconciler := &NodeSwapReconciler{
+				Client:      k8sClient,
+				Scheme:      k8sClient.Scheme(),
+				TemplateDir: "../../templates",
+				Namespace:   "default",
+			}
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: dryRunName})
+			Expect(err).NotTo(HaveOccurred())
+
+			By("Verifying that no MachineConfig was written")
+			err = k8sClient.Get(ctx, types.NamespacedName{Name: swapMCName}, &mcfgv1.MachineConfig{})
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+
+			By("Verifying that the plan and the rendered MachineConfigs are reported")
+			Expect(k8sClient.Get(ctx, dryRunName, dryRunResource)).To(Succeed())
+			Expect(dryRunResource.Status.DryRun).NotTo(BeNil())
+			Expect(dryRunResource.Status.DryRun.Create).To(ContainElement(swapMCName))
+			progressing := meta.FindStatusCondition(dryRunResource.Status.Conditions, typeProgressingNodeSwap)
+			Expect(progressing).NotTo(BeNil())
+			Expect(progressing.Reason).To(Equal(reasonDryRun))
+
+			configMap := &corev1.ConfigMap{}
+			configMapName := types.NamespacedName{Namespace: "default", Name: dryRunResource.Status.DryRun.ConfigMap}
+			Expect(k8sClient.Get(ctx, configMapName, configMap)).To(Succeed())
+			Expect(configMap.Data).To(HaveKey(swapMCName + ".yaml"))
+
+			By("Unsetting spec.dryRun")
+			dryRunResource.Spec.DryRun = false
+			Expect(k8sClient.Update(ctx, dryRunResource)).To(Succeed())
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: dryRunName})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: swapMCName}, &mcfgv1.MachineConfig{})).To(Succeed())
+			err = k8sClient.Get(ctx, configMapName, &corev1.ConfigMap{})
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+			Expect(k8sClient.Get(ctx, dryRunName, dryRunResource)).To(Succeed())
+			Expect(dryRunResource.Status.DryRun).To(BeNil())
+		})
+		It("should report the dedicated pool of a dry run", func() {
+			By("Creating a NodeSwap with spec.dryRun and a dedicated pool for part of the workers")
+			dryRunName := types.NamespacedName{Name: "test-dry-run-dedicated-resource"}
+			swapMCName := "99-filebased-swap-test-dry-run-dedicated-resource-0"
+			createWorkerNodes(ctx, "bigmem", "small")
+			dryRunResource := &nodeswapv1beta1.NodeSwap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: dryRunName.Name,
+				},
+				Spec: nodeswapv1beta1.NodeSwapSpec{
+					NodeSelector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{"hw": "bigmem"},
+					},
+					DedicatedPool: &nodeswapv1beta1.DedicatedPoolSpec{Name: "swap-dry-run-dedicated"},
+					DryRun:        true,
+					Swaps: nodeswapv1beta1.Swaps{{
+						SwapType: nodeswapv1beta1.FileBasedSwap,
+						File: &nodeswapv1beta1.SwapFile{
+							Path: "/var/swap",
+							Size: apiresource.MustParse("1Gi"),
+						},
+					}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, dryRunResource)).To(Succeed())
+			DeferCleanup(func() {
+				deleteNodeSwap(ctx, dryRunResource)
+			})
+
+			controllerReconciler := &NodeSwapReconciler{
+				Client:      k8sClient,
+				Scheme:      k8sClient.Scheme(),
+				TemplateDir: "../../templates",
+			}
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: dryRunName})
+			Expect(err).NotTo(HaveOccurred())
+
+			By("Verifying that neither the pool nor the MachineConfigs were written")
+			err = k8sClient.Get(ctx, types.NamespacedName{Name: "swap-dry-run-dedicated"}, &mcfgv1.MachineConfigPool{})
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+			err = k8sClient.Get(ctx, types.NamespacedName{Name: swapMCName}, &mcfgv1.MachineConfig{})
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+
+			By("Verifying that the plan reports the pool and its MachineConfigs")
+			Expect(k8sClient.Get(ctx, dryRunName, dryRunResource)).To(Succeed())
+			Expect(dryRunResource.Status.DryRun).NotTo(BeNil())
+			Expect(dryRunResource.Status.DryRun.CreateMachineConfigPool).To(Equal("swap-dry-run-dedicated"))
+			Expect(dryRunResource.Status.DryRun.Create).To(ContainElement(swapMCName))
+			progressing := meta.FindStatusCondition(dryRunResource.Status.Conditions, typeProgressingNodeSwap)
+			Expect(progressing).NotTo(BeNil())
+			Expect(progressing.Reason).To(Equal(reasonDryRun))
+			Expect(dryRunResource.Status.Pools).To(ConsistOf(HaveField("Name", "swap-dry-run-dedicated")))
+		})
+		It("should hold the MachineConfigs back outside of the maintenance windows", func() {
+			By("Creating a NodeSwap whose maintenance window starts in 12 hours")
+			windowName := types.NamespacedName{Name: "test-window-resource"}
+			swapMCName := "99-filebased-swap-test-window-resource-0"
+			start := time.Now().UTC().Add(12 * time.Hour)
+			createPool(ctx, "worker", map[string]string{"node-role.kubernetes.io/role": "worker"})
+			windowResource := &nodeswapv1beta1.NodeSwap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: windowName.Name,
+				},
+				Spec: nodeswapv1beta1.NodeSwapSpec{
+					MachineConfigPoolSelector: "node-role.kubernetes.io/role:worker",
+					MaintenanceWindows: []nodeswapv1beta1.MaintenanceWindow{{
+						Schedule: fmt.Sprintf("%d %d * * *", start.Minute(), start.Hour()),
+						Duration: metav1.Duration{Duration: time.Hour},
+					}},
+					Swaps: nodeswapv1beta1.Swaps{{
+						SwapType: nodeswapv1beta1.FileBasedSwap,
+						File: &nodeswapv1beta1.SwapFile{
+							Path: "/var/swap",
+							Size: apiresource.MustParse("1Gi"),
+						},
+					}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, windowResource)).To(Succeed())
+			DeferCleanup(func() {
+				deleteNodeSwap(ctx, windowResource)
+				mc := &mcfgv1.MachineConfig{ObjectMeta: metav1.ObjectMeta{Name: swapMCName}}
+				Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, mc))).To(Succeed())
+			})
+
+			controllerReconciler := &NodeSwapReconciler{
+				Client:      k8sClient,
+				Scheme:      k8sClient.Scheme(),
+				TemplateDir: "../../templates",
+			}
+			result, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: windowName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(BeNumerically("~", 12*time.Hour, time.Minute))
+
+			By("Verifying that no MachineConfig was written and the changes are pending")
+			err = k8sClient.Get(ctx, types.NamespacedName{Name: swapMCName}, &mcfgv1.MachineConfig{})
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+			Expect(k8sClient.Get(ctx, windowName, windowResource)).To(Succeed())
+			Expect(windowResource.Status.PendingChanges).NotTo(BeNil())
+			Expect(windowResource.Status.PendingChanges.Create).To(ContainElement(swapMCName))
+			Expect(windowResource.Status.MaintenanceWindow).NotTo(BeNil())
+			Expect(windowResource.Status.MaintenanceWindow.Open).To(BeFalse())
+			Expect(windowResource.Status.MaintenanceWindow.Start.Time).To(BeTemporally("~", start, time.Minute))
+			progressing := meta.FindStatusCondition(windowResource.Status.Conditions, typeProgressingNodeSwap)
+			Expect(progressing).NotTo(BeNil())
+			Expect(progressing.Reason).To(Equal(reasonOutsideMaintenanceWindow))
+
+			By("Opening the maintenance window")
+			windowResource.Spec.MaintenanceWindows[0].Schedule = "* * * * *"
+			Expect(k8sClient.Update(ctx, windowResource)).To(Succeed())
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: windowName})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: swapMCName}, &mcfgv1.MachineConfig{})).To(Succeed())
+			Expect(k8sClient.Get(ctx, windowName, windowResource)).To(Succeed())
+			Expect(windowResource.Status.PendingChanges).To(BeNil())
+			Expect(windowResource.Status.MaintenanceWindow.Open).To(BeTrue())
+		})
+		It("should hold the dedicated pool back outside of the maintenance windows", func() {
+			By("Creating a NodeSwap with a dedicated pool whose maintenance window starts in 12 hours")
+			windowName := types.NamespacedName{Name: "test-window-dedicated-resource"}
+			swapMCName := "99-filebased-swap-test-window-dedicated-resource-0"
+			start := time.Now().UTC().Add(12 * time.Hour)
+			createWorkerNodes(ctx, "bigmem", "small")
+			windowResource := &nodeswapv1beta1.NodeSwap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: windowName.Name,
+				},
+				Spec: nodeswapv1beta1.NodeSwapSpec{
+					NodeSelector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{"hw": "bigmem"},
+					},
+					DedicatedPool: &nodeswapv1beta1.DedicatedPoolSpec{Name: "swap-window-dedicated"},
+					MaintenanceWindows: []nodeswapv1beta1.MaintenanceWindow{{
+						Schedule: fmt.Sprintf("%d %d * * *", start.Minute(), start.Hour()),
+						Duration: metav1.Duration{Duration: time.Hour},
+					}},
+					Swaps: nodeswapv1beta1.Swaps{{
+						SwapType: nodeswapv1beta1.FileBasedSwap,
+						File: &nodeswapv1beta1.SwapFile{
+							Path: "/var/swap",
+							Size: apiresource.MustParse("1Gi"),
+						},
+					}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, windowResource)).To(Succeed())
+			DeferCleanup(func() {
+				deleteNodeSwap(ctx, windowResource)
+			})
+
+			controllerReconciler := &NodeSwapReconciler{
+				Client:      k8sClient,
+				Scheme:      k8sClient.Scheme(),
+				TemplateDir: "../../templates",
+			}
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: windowName})
+			Expect(err).NotTo(HaveOccurred())
+
+			By("Verifying that the pool creation is pending with the MachineConfigs")
+			err = k8sClient.Get(ctx, types.NamespacedName{Name: "swap-window-dedicated"}, &mcfgv1.MachineConfigPool{})
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+			Expect(k8sClient.Get(ctx, windowName, windowResource)).To(Succeed())
+			degraded := meta.FindStatusCondition(windowResource.Status.Conditions, typeDegradedNodeSwap)
+			Expect(degraded).NotTo(BeNil())
+			Expect(degraded.Status).To(Equal(metav1.ConditionFalse))
+			Expect(windowResource.Status.PendingChanges).NotTo(BeNil())
+			Expect(windowResource.Status.PendingChanges.CreateMachineConfigPool).To(Equal("swap-window-dedicated"))
+			Expect(windowResource.Status.PendingChanges.Create).To(ContainElement(swapMCName))
+		})
+		It("should hold the MachineConfigs back while the cluster is upgrading", func() {
+			By("Reporting a cluster upgrade in progress")
+			clusterVersion := &configv1.ClusterVersion{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: clusterVersionName}, clusterVersion)).To(Succeed())
+			setProgressing := func(status configv1.ConditionStatus) {
+				GinkgoHelper()
+				Expect(k8sClient.Get(ctx, types.NamespacedName{Name: clusterVersionName}, clusterVersion)).To(Succeed())
+				clusterVersion.Status.Conditions = []configv1.ClusterOperatorStatusCondition{{
+					Type:               configv1.OperatorProgressing,
+					Status:             status,
+					LastTransitionTime: metav1.Now(),
+				}}
+				Expect(k8sClient.Status().Update(ctx, clusterVersion)).To(Succeed())
+			}
+			setProgressing(configv1.ConditionTrue)
+			DeferCleanup(func() {
+				Expect(k8sClient.Get(ctx, types.NamespacedName{Name: clusterVersionName}, clusterVersion)).To(Succeed())
+				clusterVersion.Status.Conditions = nil
+				Expect(k8sClient.Status().Update(ctx, clusterVersion)).To(Succeed())
+			})
+
+			createPool(ctx, "worker", map[string]string{"node-role.kubernetes.io/role": "worker"})
+			upgradeName := types.NamespacedName{Name: "test-upgrade-resource"}
+			swapMCName := "99-filebased-swap-test-upgrade-resource-0"
+			upgradeResource := &nodeswapv1beta1.NodeSwap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: upgradeName.Name,
+				},
+				Spec: nodeswapv1beta1.NodeSwapSpec{
+					MachineConfigPoolSelector: "node-role.kubernetes.io/role:worker",
+					Swaps: nodeswapv1beta1.Swaps{{
+						SwapType: nodeswapv1beta1.FileBasedSwap,
+						File: &nodeswapv1beta1.SwapFile{
+							Path: "/var/swap",
+							Size: apiresource.MustParse("1Gi"),
+						},
+					}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, upgradeResource)).To(Succeed())
+			DeferCleanup(func() {
+				deleteNodeSwap(ctx, upgradeResource)
+				for _, name := range []string{swapMCName, renderconfig.KubeletMachineConfigName(upgradeName.Name)} {
+					mc := &mcfgv1.MachineConfig{ObjectMeta: metav1.ObjectMeta{Name: name}}
+					Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, mc))).To(Succeed())
+				}
+			})
+
+			controllerReconciler := &NodeSwapReconciler{
+				Client:      k8sClient,
+				Scheme:      k8sClient.Scheme(),
+				TemplateDir: "../../templates",
+			}
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: upgradeName})
+			Expect(err).NotTo(HaveOccurred())
+
+			By("Verifying that no MachineConfig was written and the changes are pending")
+			err = k8sClient.Get(ctx, types.NamespacedName{Name: swapMCName}, &mcfgv1.MachineConfig{})
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+			Expect(k8sClient.Get(ctx, upgradeName, upgradeResource)).To(Succeed())
+			Expect(upgradeResource.Status.PendingChanges).NotTo(BeNil())
+			Expect(upgradeResource.Status.PendingChanges.Create).To(ContainElement(swapMCName))
+			progressing := meta.FindStatusCondition(upgradeResource.Status.Conditions, typeProgressingNodeSwap)
+			Expect(progressing).NotTo(BeNil())
+			Expect(progressing.Status).To(Equal(metav1.ConditionFalse))
+			Expect(progressing.Reason).To(Equal(reasonWaitingForUpgrade))
+			Expect(progressing.Message).To(ContainSubstring(minimumClusterVersion))
+
+			By("Completing the upgrade")
+			setProgressing(configv1.ConditionFalse)
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: upgradeName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: swapMCName}, &mcfgv1.MachineConfig{})).To(Succeed())
+			Expect(k8sClient.Get(ctx, upgradeName, upgradeResource)).To(Succeed())
+			Expect(upgradeResource.Status.PendingChanges).To(BeNil())
+		})
+
+		It("should label the MachineConfigs for the pools matched by poolSelector", func() {
+			By("Creating a MachineConfigPool and a NodeSwap selecting it by label")
+			pool := &mcfgv1.MachineConfigPool{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:   "swap-selector-pool",
+					Labels: map[string]string{"swap": "enabled"},
+				},
+				Spec: mcfgv1.MachineConfigPoolSpec{
+					MachineConfigSelector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{"machineconfiguration.openshift.io/role": "swap-selector-pool"},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, pool)).To(Succeed())
+
+			selectorName := types.NamespacedName{Name: "test-selector-resource"}
+			swapMCName := "99-filebased-swap-test-selector-resource-0"
+			selectorResource := &nodeswapv1beta1.NodeSwap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: selectorName.Name,
+				},
+				Spec: nodeswapv1beta1.NodeSwapSpec{
+					PoolSelector: &metav1.LabelSelector{
+						MatchExpressions: []metav1.LabelSelectorRequirement{{
+							Key:      "swap",
+							Operator: metav1.LabelSelectorOpIn,
+							Values:   []string{"enabled"},
+						}},
+					},
+					Swaps: nodeswapv1beta1.Swaps{{
+						SwapType: nodeswapv1beta1.FileBasedSwap,
+						File: &nodeswapv1beta1.SwapFile{
+							Path: "/var/swap",
+							Size: apiresource.MustParse("1Gi"),
+						},
+					}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, selectorResource)).To(Succeed())
+			DeferCleanup(func() {
+				deleteNodeSwap(ctx, selectorResource)
+				Expect(k8sClient.Delete(ctx, pool)).To(Succeed())
+				for _, name := range []string{swapMCName, renderconfig.KubeletMachineConfigName(selectorResource.Name)} {
+					mc := &mcfgv1.MachineConfig{ObjectMeta: metav1.ObjectMeta{Name: name}}
+					Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, mc))).To(Succeed())
+				}
+			})
+
+			controllerReconciler := &NodeSwapReconciler{
+				Client:      k8sClient,
+				Scheme:      k8sClient.Scheme(),
+				TemplateDir: "../../templates",
+			}
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: selectorName})
+			Expect(err).NotTo(HaveOccurred())
+
+			By("Verifying that the swap MachineConfig is labeled for the matched pool")
+			mc := &mcfgv1.MachineConfig{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: swapMCName}, mc)).To(Succeed())
+			Expect(mc.Labels).To(HaveKeyWithValue("machineconfiguration.openshift.io/role", "swap-selector-pool"))
+
+			expectCondition := func(conditionType string, status metav1.ConditionStatus, reason string) {
+				GinkgoHelper()
+				Expect(k8sClient.Get(ctx, selectorName, selectorResource)).To(Succeed())
+				condition := meta.FindStatusCondition(selectorResource.Status.Conditions, conditionType)
+				Expect(condition).NotTo(BeNil())
+				Expect(condition.Status).To(Equal(status))
+				Expect(condition.Reason).To(Equal(reason))
+			}
+
+			By("Verifying that the NodeSwap is progressing until the pool renders its MachineConfigs")
+			expectCondition(typeProgressingNodeSwap, metav1.ConditionTrue, reasonRollingOut)
+			expectCondition(typeAvailableNodeSwap, metav1.ConditionFalse, reasonRollingOut)
+
+			By("Rolling the MachineConfigs out on the pool")
+			rollOutPool(ctx, pool, "rendered-swap-selector-pool-1", swapMCName, renderconfig.KubeletMachineConfigName(selectorResource.Name))
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: selectorName})
+			Expect(err).NotTo(HaveOccurred())
+			expectCondition(typeProgressingNodeSwap, metav1.ConditionFalse, reasonRolledOut)
+			expectCondition(typeAvailableNodeSwap, metav1.ConditionTrue, reasonRolledOut)
+			expectCondition(typeDegradedNodeSwap, metav1.ConditionFalse, "ReconciliationSucceeded")
+
+			By("Changing the size of the swap file in place")
+			Expect(k8sClient.Get(ctx, selectorName, selectorResource)).To(Succeed())
+			selectorResource.Spec.Swaps[0].File.Size = apiresource.MustParse("2Gi")
+			Expect(k8sClient.Update(ctx, selectorResource)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: selectorName})
+			Expect(err).NotTo(HaveOccurred())
+			expectCondition(typeProgressingNodeSwap, metav1.ConditionTrue, reasonRollingOut)
+			expectCondition(typeAvailableNodeSwap, metav1.ConditionFalse, reasonRollingOut)
+			Expect(selectorResource.Status.Pools).To(HaveLen(1))
+			Expect(selectorResource.Status.Pools[0].MachineConfigsRendered).To(BeFalse())
+
+			By("Rolling the new size out on the pool")
+			rollOutPool(ctx, pool, "rendered-swap-selector-pool-2", swapMCName, renderconfig.KubeletMachineConfigName(selectorResource.Name))
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: selectorName})
+			Expect(err).NotTo(HaveOccurred())
+			expectCondition(typeProgressingNodeSwap, metav1.ConditionFalse, reasonRolledOut)
+			expectCondition(typeAvailableNodeSwap, metav1.ConditionTrue, reasonRolledOut)
+
+			By("Mirroring a degraded pool")
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(pool), pool)).To(Succeed())
+			pool.Status.Conditions = []mcfgv1.MachineConfigPoolCondition{{
+				Type:               mcfgv1.MachineConfigPoolDegraded,
+				Status:             corev1.ConditionTrue,
+				LastTransitionTime: metav1.Now(),
+				Reason:             "1 nodes are reporting degraded status on sync",
+				Message:            "Node worker-0 is reporting: failed to enable swap",
+			}}
+			Expect(k8sClient.Status().Update(ctx, pool)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: selectorName})
+			Expect(err).NotTo(HaveOccurred())
+			expectCondition(typeDegradedNodeSwap, metav1.ConditionTrue, reasonPoolDegraded)
+			degraded := meta.FindStatusCondition(selectorResource.Status.Conditions, typeDegradedNodeSwap)
+			Expect(degraded.Message).To(ContainSubstring("swap-selector-pool"))
+			Expect(degraded.Message).To(ContainSubstring("failed to enable swap"))
+		})
+		It("should stage the rollout and the later changes across the pools", func() {
+			By("Creating a pool and a canary pool inheriting its MachineConfigs")
+			const role = "machineconfiguration.openshift.io/role"
+			worker := &mcfgv1.MachineConfigPool{
+				ObjectMeta: metav1.ObjectMeta{Name: "swap-staged-worker"},
+				Spec: mcfgv1.MachineConfigPoolSpec{
+					MachineConfigSelector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{role: "swap-staged-worker"},
+					},
+				},
+			}
+			canary := &mcfgv1.MachineConfigPool{
+				ObjectMeta: metav1.ObjectMeta{Name: "swap-staged-canary"},
+				Spec: mcfgv1.MachineConfigPoolSpec{
+					MachineConfigSelector: &metav1.LabelSelector{
+						MatchExpressions: []metav1.LabelSelectorRequirement{{
+							Key:      role,
+							Operator: metav1.LabelSelectorOpIn,
+							Values:   []string{"swap-staged-worker", "swap-staged-canary"},
+						}},
+					},
+				},
+			}
+			for _, pool := range []*mcfgv1.MachineConfigPool{worker, canary} {
+				Expect(k8sClient.Create(ctx, pool)).To(Succeed())
+			}
+
+			stagedName := types.NamespacedName{Name: "test-staged-resource"}
+			swapMCName := "99-filebased-swap-test-staged-resource-0"
+			kubeletMCName := renderconfig.KubeletMachineConfigName(stagedName.Name)
+			stagedResource := &nodeswapv1beta1.NodeSwap{
+				ObjectMeta: metav1.ObjectMeta{Name: stagedName.Name},
+				Spec: nodeswapv1beta1.NodeSwapSpec{
+					MachineConfigPoolNames: []string{worker.Name, canary.Name},
+					Rollout: &nodeswapv1beta1.RolloutSpec{
+						Stages: []nodeswapv1beta1.RolloutStage{{Pools: []string{canary.Name}}},
+					},
+					Swaps: nodeswapv1beta1.Swaps{{
+						SwapType: nodeswapv1beta1.FileBasedSwap,
+						File: &nodeswapv1beta1.SwapFile{
+							Path: "/var/swap",
+							Size: apiresource.MustParse("1Gi"),
+						},
+					}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, stagedResource)).To(Succeed())
+			DeferCleanup(func() {
+				deleteNodeSwap(ctx, stagedResource)
+				for _, pool := range []*mcfgv1.MachineConfigPool{worker, canary} {
+					Expect(k8sClient.Delete(ctx, pool)).To(Succeed())
+				}
+				for _, name := range []string{swapMCName, kubeletMCName} {
+					mc := &mcfgv1.MachineConfig{ObjectMeta: metav1.ObjectMeta{Name: name}}
+					Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, mc))).To(Succeed())
+				}
+			})
+
+			controllerReconciler := &NodeSwapReconciler{
+				Client:      k8sClient,
+				Scheme:      k8sClient.Scheme(),
+				TemplateDir: "../../templates",
+			}
+			reconcileStaged := func() {
+				GinkgoHelper()
+				_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: stagedName})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(k8sClient.Get(ctx, stagedName, stagedResource)).To(Succeed())
+			}
+			expectLabeled := func(roleValue string) {
+				GinkgoHelper()
+				for _, name := range []string{swapMCName, kubeletMCName} {
+					mc := &mcfgv1.MachineConfig{}
+					Expect(k8sClient.Get(ctx, types.NamespacedName{Name: name}, mc)).To(Succeed())
+					Expect(mc.Labels).To(HaveKeyWithValue(role, roleValue))
+				}
+			}
+			expectPaused := func(paused bool) {
+				GinkgoHelper()
+				Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(worker), worker)).To(Succeed())
+				Expect(worker.Spec.Paused).To(Equal(paused))
+				if paused {
+					Expect(stagedResource.Status.PausedPools).To(ConsistOf(worker.Name))
+				} else {
+					Expect(stagedResource.Status.PausedPools).To(BeEmpty())
+				}
+			}
+
+			By("Labeling the MachineConfigs for the canary stage only")
+			reconcileStaged()
+			expectLabeled(canary.Name)
+			expectPaused(false)
+			progressing := meta.FindStatusCondition(stagedResource.Status.Conditions, typeProgressingNodeSwap)
+			Expect(progressing).NotTo(BeNil())
+			Expect(progressing.Reason).To(Equal(reasonStagedRollout))
+
+			By("Labeling the MachineConfigs for both stages once the canary rolled them out")
+			rollOutPool(ctx, canary, "rendered-swap-staged-canary-1", swapMCName, kubeletMCName)
+			reconcileStaged()
+			expectLabeled(worker.Name)
+			expectPaused(false)
+			rollOutPool(ctx, worker, "rendered-swap-staged-worker-1", swapMCName, kubeletMCName)
+			reconcileStaged()
+			expectPaused(false)
+
+			By("Holding the worker pool back while the canary rolls a change out")
+			stagedResource.Spec.Swaps[0].File.Size = apiresource.MustParse("2Gi")
+			Expect(k8sClient.Update(ctx, stagedResource)).To(Succeed())
+			reconcileStaged()
+			expectLabeled(worker.Name)
+			expectPaused(true)
+
+			By("Resuming the worker pool once the canary rolled the change out")
+			rollOutPool(ctx, canary, "rendered-swap-staged-canary-2", swapMCName, kubeletMCName)
+			reconcileStaged()
+			expectPaused(false)
+		})
+
+		It("should roll an in-place change back when it degrades the pool", func() {
+			By("Rolling out a NodeSwap with autoRollback")
+			pool := &mcfgv1.MachineConfigPool{
+				ObjectMeta: metav1.ObjectMeta{Name: "swap-rollback-pool"},
+				Spec: mcfgv1.MachineConfigPoolSpec{
+					MachineConfigSelector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{"machineconfiguration.openshift.io/role": "swap-rollback-pool"},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, pool)).To(Succeed())
+
+			rollbackName := types.NamespacedName{Name: "test-rollback-resource"}
+			swapMCName := "99-filebased-swap-test-rollback-resource-0"
+			rollbackResource := &nodeswapv1beta1.NodeSwap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: rollbackName.Name,
+				},
+				Spec: nodeswapv1beta1.NodeSwapSpec{
+					MachineConfigPoolNames: []string{pool.Name},
+					AutoRollback:           true,
+					Swaps: nodeswapv1beta1.Swaps{{
+						SwapType: nodeswapv1beta1.FileBasedSwap,
+						File: &nodeswapv1beta1.SwapFile{
+							Path: "/var/swap",
+							Size: apiresource.MustParse("1Gi"),
+						},
+					}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, rollbackResource)).To(Succeed())
+			DeferCleanup(func() {
+				deleteNodeSwap(ctx, rollbackResource)
+				Expect(k8sClient.Delete(ctx, pool)).To(Succeed())
+				for _, name := range []string{swapMCName, renderconfig.KubeletMachineConfigName(rollbackResource.Name)} {
+					mc := &mcfgv1.MachineConfig{ObjectMeta: metav1.ObjectMeta{Name: name}}
+					Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, mc))).To(Succeed())
+				}
+			})
+
+			controllerReconciler := &NodeSwapReconciler{
+				Client:      k8sClient,
+				Scheme:      k8sClient.Scheme(),
+				TemplateDir: "../../templates",
+			}
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: rollbackName})
+			Expect(err).NotTo(HaveOccurred())
+			rollOutPool(ctx, pool, "rendered-swap-rollback-pool-1", swapMCName, renderconfig.KubeletMachineConfigName(rollbackResource.Name))
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: rollbackName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, rollbackName, rollbackResource)).To(Succeed())
+			Expect(rollbackResource.Status.LastAppliedSpec).NotTo(BeNil())
+			applied := &mcfgv1.MachineConfig{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: swapMCName}, applied)).To(Succeed())
+
+			By("Changing the size of the swap file in place")
+			rollbackResource.Spec.Swaps[0].File.Size = apiresource.MustParse("2Gi")
+			Expect(k8sClient.Update(ctx, rollbackResource)).To(Succeed())
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: rollbackName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, rollbackName, rollbackResource)).To(Succeed())
+			Expect(rollbackResource.Status.LastAppliedSpec.Swaps[0].File.Size.String()).To(Equal("1Gi"))
+
+			By("Degrading the pool before it renders the new size")
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(pool), pool)).To(Succeed())
+			pool.Status.Conditions = append(pool.Status.Conditions, mcfgv1.MachineConfigPoolCondition{
+				Type:               mcfgv1.MachineConfigPoolDegraded,
+				Status:             corev1.ConditionTrue,
+				Reason:             "1 nodes are reporting degraded status on sync",
+				Message:            "failed to enable swap",
+				LastTransitionTime: metav1.Now(),
+			})
+			Expect(k8sClient.Status().Update(ctx, pool)).To(Succeed())
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: rollbackName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, rollbackName, rollbackResource)).To(Succeed())
+			Expect(rollbackResource.Status.Rollback).To(BeNil())
+
+			By("Rendering the new size on the degraded pool")
+			renderPool(ctx, pool, "rendered-swap-rollback-pool-2", swapMCName, renderconfig.KubeletMachineConfigName(rollbackResource.Name))
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: rollbackName})
+			Expect(err).NotTo(HaveOccurred())
+
+			By("Verifying that the MachineConfigs are rolled back to the applied size")
+			Expect(k8sClient.Get(ctx, rollbackName, rollbackResource)).To(Succeed())
+			Expect(rollbackResource.Status.Rollback).NotTo(BeNil())
+			Expect(rollbackResource.Status.Rollback.MachineConfigPool).To(Equal(pool.Name))
+			mc := &mcfgv1.MachineConfig{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: swapMCName}, mc)).To(Succeed())
+			Expect(mc.Spec.Config.Raw).To(Equal(applied.Spec.Config.Raw))
+		})
+
+		It("should block the rollout when nodeSelector partially covers a pool", func() {
+			By("Creating a pool with two nodes and a NodeSwap selecting one of them")
+			pool := &mcfgv1.MachineConfigPool{
+				ObjectMeta: metav1.ObjectMeta{Name: "swap-coverage-pool"},
+				Spec: mcfgv1.MachineConfigPoolSpec{
+					MachineConfigSelector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{"machineconfiguration.openshift.io/role": "swap-coverage-pool"},
+					},
+					NodeSelector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{"node-role.kubernetes.io/swap-coverage-pool": ""},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, pool)).To(Succeed())
+			var nodes []*corev1.Node
+			for name, hw := range map[string]string{"swap-coverage-0": "bigmem", "swap-coverage-1": "small"} {
+				node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{
+					Name:   name,
+					Labels: map[string]string{"node-role.kubernetes.io/swap-coverage-pool": "", "hw": hw},
+				}}
+				Expect(k8sClient.Create(ctx, node)).To(Succeed())
+				nodes = append(nodes, node)
+			}
+
+			coverageName := types.NamespacedName{Name: "test-coverage-resource"}
+			coverageResource := &nodeswapv1beta1.NodeSwap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: coverageName.Name,
+				},
+				Spec: nodeswapv1beta1.NodeSwapSpec{
+					NodeSelector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{"hw": "bigmem"},
+					},
+					Swaps: nodeswapv1beta1.Swaps{{
+						SwapType: nodeswapv1beta1.FileBasedSwap,
+						File: &nodeswapv1beta1.SwapFile{
+							Path: "/var/swap",
+							Size: apiresource.MustParse("1Gi"),
+						},
+					}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, coverageResource)).To(Succeed())
+			DeferCleanup(func() {
+				deleteNodeSwap(ctx, coverageResource)
+				Expect(k8sClient.Delete(ctx, pool)).To(Succeed())
+				for _, node := range nodes {
+					Expect(k8sClient.Delete(ctx, node)).To(Succeed())
+				}
+			})
+
+			controllerReconciler := &NodeSwapReconciler{
+				Client:      k8sClient,
+				Scheme:      k8sClient.Scheme(),
+				TemplateDir: "../../templates",
+			}
+			result, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: coverageName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(BeNumerically(">", 0))
+
+			By("Verifying that the partially covered pool is reported")
+			Expect(k8sClient.Get(ctx, coverageName, coverageResource)).To(Succeed())
+			degraded := meta.FindStatusCondition(coverageResource.Status.Conditions, typeDegradedNodeSwap)
+			Expect(degraded).NotTo(BeNil())
+			Expect(degraded.Status).To(Equal(metav1.ConditionTrue))
+			Expect(degraded.Reason).To(Equal(reasonPoolsPartiallyCovered))
+			Expect(degraded.Message).To(ContainSubstring("swap-coverage-pool (1 of 2 nodes selected)"))
+		})
+
+		It("should report NoMatchingPools and remove the MachineConfigs of deleted pools", func() {
+			By("Creating a MachineConfigPool and a NodeSwap selecting it by label")
+			pool := &mcfgv1.MachineConfigPool{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:   "swap-deleted-pool",
+					Labels: map[string]string{"swap": "deleted"},
+				},
+				Spec: mcfgv1.MachineConfigPoolSpec{
+					MachineConfigSelector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{"machineconfiguration.openshift.io/role": "swap-deleted-pool"},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, pool)).To(Succeed())
+
+			deletedName := types.NamespacedName{Name: "test-deleted-pool-resource"}
+			swapMCName := "99-filebased-swap-test-deleted-pool-resource-0"
+			deletedResource := &nodeswapv1beta1.NodeSwap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: deletedName.Name,
+				},
+				Spec: nodeswapv1beta1.NodeSwapSpec{
+					PoolSelector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{"swap": "deleted"},
+					},
+					DeletedPoolPolicy: nodeswapv1beta1.DeletedPoolPolicyDelete,
+					Swaps: nodeswapv1beta1.Swaps{{
+						SwapType: nodeswapv1beta1.FileBasedSwap,
+						File: &nodeswapv1beta1.SwapFile{
+							Path: "/var/swap",
+							Size: apiresource.MustParse("1Gi"),
+						},
+					}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, deletedResource)).To(Succeed())
+			DeferCleanup(func() {
+				deleteNodeSwap(ctx, deletedResource)
+				Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, pool))).To(Succeed())
+				for _, name := range []string{swapMCName, renderconfig.KubeletMachineConfigName(deletedResource.Name)} {
+					mc := &mcfgv1.MachineConfig{ObjectMeta: metav1.ObjectMeta{Name: name}}
+					Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, mc))).To(Succeed())
+				}
+			})
+
+			controllerReconciler := &NodeSwapReconciler{
+				Client:      k8sClient,
+				Scheme:      k8sClient.Scheme(),
+				TemplateDir: "../../templates",
+			}
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: deletedName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: swapMCName}, &mcfgv1.MachineConfig{})).To(Succeed())
+
+			By("Deleting the MachineConfigPool")
+			Expect(k8sClient.Delete(ctx, pool)).To(Succeed())
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: deletedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			By("Verifying that NoMatchingPools is reported and the MachineConfigs are removed")
+			err = k8sClient.Get(ctx, types.NamespacedName{Name: swapMCName}, &mcfgv1.MachineConfig{})
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+			Expect(k8sClient.Get(ctx, deletedName, deletedResource)).To(Succeed())
+			degraded := meta.FindStatusCondition(deletedResource.Status.Conditions, typeDegradedNodeSwap)
+			Expect(degraded).NotTo(BeNil())
+			Expect(degraded.Status).To(Equal(metav1.ConditionTrue))
+			Expect(degraded.Reason).To(Equal(reasonNoMatchingPools))
+			Expect(degraded.Message).To(ContainSubstring("swap-deleted-pool"))
+			Expect(deletedResource.Status.Pools).To(BeEmpty())
+		})
+
+		It("should create, sync and tear down a dedicated MachineConfigPool", func() {
+			By("Creating a NodeSwap requesting a dedicated pool")
+			dedicatedName := types.NamespacedName{Name: "test-dedicated-resource"}
+			swapMCName := "99-filebased-swap-test-dedicated-resource-0"
+			dedicatedResource := &nodeswapv1beta1.NodeSwap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: dedicatedName.Name,
+				},
+				Spec: nodeswapv1beta1.NodeSwapSpec{
+					NodeSelector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{"hw": "bigmem"},
+					},
+					DedicatedPool: &nodeswapv1beta1.DedicatedPoolSpec{Name: "swap-dedicated"},
+					Swaps: nodeswapv1beta1.Swaps{{
+						SwapType: nodeswapv1beta1.FileBasedSwap,
+						File: &nodeswapv1beta1.SwapFile{
+							Path: "/var/swap",
+							Size: apiresource.MustParse("1Gi"),
+						},
+					}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, dedicatedResource)).To(Succeed())
+			DeferCleanup(func() {
+				for _, name := range []string{swapMCName, renderconfig.KubeletMachineConfigName(dedicatedResource.Name)} {
+					mc := &mcfgv1.MachineConfig{ObjectMeta: metav1.ObjectMeta{Name: name}}
+					Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, mc))).To(Succeed())
+				}
+			})
+
+			controllerReconciler := &NodeSwapReconciler{
+				Client:      k8sClient,
+				Scheme:      k8sClient.Scheme(),
+				TemplateDir: "../../templates",
+			}
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: dedicatedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			By("Verifying the pool inherits the worker MachineConfigs and selects the nodes")
+			pool := &mcfgv1.MachineConfigPool{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: "swap-dedicated"}, pool)).To(Succeed())
+			Expect(pool.Labels).To(HaveKeyWithValue(ownerNameLabel, dedicatedName.Name))
+			Expect(pool.Spec.NodeSelector.MatchLabels).To(Equal(map[string]string{"hw": "bigmem"}))
+			Expect(pool.Spec.MachineConfigSelector.MatchExpressions).To(ConsistOf(metav1.LabelSelectorRequirement{
+				Key:      "machineconfiguration.openshift.io/role",
+				Operator: metav1.LabelSelectorOpIn,
+				Values:   []string{"worker", "swap-dedicated"},
+			}))
+			mc := &mcfgv1.MachineConfig{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: swapMCName}, mc)).To(Succeed())
+			Expect(mc.Labels).To(HaveKeyWithValue("machineconfiguration.openshift.io/role", "swap-dedicated"))
+
+			By("Changing the node selector and verifying the pool follows")
+			Expect(k8sClient.Get(ctx, dedicatedName, dedicatedResource)).To(Succeed())
+			Expect(dedicatedResource.Finalizers).To(ContainElement(nodeSwapFinalizer))
+			dedicatedResource.Spec.NodeSelector.MatchLabels["hw"] = "hugemem"
+			Expect(k8sClient.Update(ctx, dedicatedResource)).To(Succeed())
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: dedicatedName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: "swap-dedicated"}, pool)).To(Succeed())
+			Expect(pool.Spec.NodeSelector.MatchLabels).To(Equal(map[string]string{"hw": "hugemem"}))
+
+			By("Deleting the NodeSwap and verifying the nodes are released first")
+			Expect(k8sClient.Delete(ctx, dedicatedResource)).To(Succeed())
+			result, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: dedicatedName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(BeNumerically(">", 0))
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: "swap-dedicated"}, pool)).To(Succeed())
+			Expect(pool.Spec.NodeSelector).To(BeNil())
+
+			By("Verifying the pool and the NodeSwap are removed once the pool is empty")
+			pool.Status.ObservedGeneration = pool.Generation
+			pool.Status.MachineCount = 0
+			Expect(k8sClient.Status().Update(ctx, pool)).To(Succeed())
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: dedicatedName})
+			Expect(err).NotTo(HaveOccurred())
+			err = k8sClient.Get(ctx, types.NamespacedName{Name: "swap-dedicated"}, pool)
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+			err = k8sClient.Get(ctx, dedicatedName, dedicatedResource)
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+		})
+
+		It("should remove the MachineConfigs before releasing a deleted NodeSwap", func() {
+			newNodeSwap := func(name string, swaps ...nodeswapv1beta1.SwapSpec) *nodeswapv1beta1.NodeSwap {
+				createPool(ctx, name, map[string]string{"machineconfiguration.openshift.io/role": name})
+				return &nodeswapv1beta1.NodeSwap{
+					ObjectMeta: metav1.ObjectMeta{Name: name},
+					Spec: nodeswapv1beta1.NodeSwapSpec{
+						MachineConfigPoolSelector: "machineconfiguration.openshift.io/role:" + name,
+						Swaps:                     swaps,
+					},
+				}
+			}
+
+			By("Creating and reconciling two NodeSwaps")
+			swapMCName := "99-filebased-swap-test-cleanup-file-0"
+			fileResource := newNodeSwap("test-cleanup-file", nodeswapv1beta1.SwapSpec{
+				SwapType: nodeswapv1beta1.FileBasedSwap,
+				File: &nodeswapv1beta1.SwapFile{
+					Path: "/var/swap",
+					Size: apiresource.MustParse("1Gi"),
+				},
+			})
+			kubeletResource := newNodeSwap("test-cleanup-kubelet")
+			controllerReconciler := &NodeSwapReconciler{
+				Client:      k8sClient,
+				Scheme:      k8sClient.Scheme(),
+				TemplateDir: "../../templates",
+			}
+			for _, resource := range []*nodeswapv1beta1.NodeSwap{fileResource, kubeletResource} {
+				Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+				_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+					NamespacedName: client.ObjectKeyFromObject(resource),
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(resource), resource)).To(Succeed())
+				Expect(resource.Finalizers).To(ContainElement(nodeSwapFinalizer))
+			}
+			DeferCleanup(func() {
+				deleteNodeSwap(ctx, kubeletResource)
+			})
+
+			By("Rendering the file swap MachineConfig on a pool")
+			pool := &mcfgv1.MachineConfigPool{ObjectMeta: metav1.ObjectMeta{Name: "swap-cleanup-pool"}}
+			Expect(k8sClient.Create(ctx, pool)).To(Succeed())
+			DeferCleanup(func() {
+				Expect(k8sClient.Delete(ctx, pool)).To(Succeed())
+			})
+			pool.Status.Configuration.Source = []corev1.ObjectReference{{Name: swapMCName}}
+			Expect(k8sClient.Status().Update(ctx, pool)).To(Succeed())
+
+			By("Deleting the file swap NodeSwap")
+			Expect(k8sClient.Delete(ctx, fileResource)).To(Succeed())
+			fileName := client.ObjectKeyFromObject(fileResource)
+			result, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: fileName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(BeNumerically(">", 0))
+
+			mc := &mcfgv1.MachineConfig{}
+			err = k8sClient.Get(ctx, types.NamespacedName{Name: swapMCName}, mc)
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+			err = k8sClient.Get(ctx, types.NamespacedName{Name: renderconfig.KubeletMachineConfigName(fileResource.Name)}, mc)
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: renderconfig.KubeletMachineConfigName(kubeletResource.Name)}, mc)).To(Succeed())
+			Expect(k8sClient.Get(ctx, fileName, fileResource)).To(Succeed())
+
+			By("Releasing the NodeSwap once the pool no longer renders its MachineConfigs")
+			pool.Status.Configuration.Source = nil
+			Expect(k8sClient.Status().Update(ctx, pool)).To(Succeed())
+			unrelated := &mcfgv1.MachineConfigPool{ObjectMeta: metav1.ObjectMeta{Name: "swap-cleanup-unrelated"}}
+			Expect(k8sClient.Create(ctx, unrelated)).To(Succeed())
+			DeferCleanup(func() {
+				Expect(k8sClient.Delete(ctx, unrelated)).To(Succeed())
+			})
+			unrelated.Status.Conditions = []mcfgv1.MachineConfigPoolCondition{{
+				Type:   mcfgv1.MachineConfigPoolUpdating,
+				Status: corev1.ConditionTrue,
+			}}
+			Expect(k8sClient.Status().Update(ctx, unrelated)).To(Succeed())
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: fileName})
+			Expect(err).NotTo(HaveOccurred())
+			err = k8sClient.Get(ctx, fileName, fileResource)
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+		})
+
+		It("should mark the NodeSwap losing a pool conflict", func() {
+			By("Creating a pool and two NodeSwaps selecting it")
+			pool := &mcfgv1.MachineConfigPool{
+				ObjectMeta: metav1.ObjectMeta{Name: "swap-conflict-pool"},
+				Spec: mcfgv1.MachineConfigPoolSpec{
+					MachineConfigSelector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{"machineconfiguration.openshift.io/role": "swap-conflict-pool"},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, pool)).To(Succeed())
+
+			controllerReconciler := &NodeSwapReconciler{
+				Client:      k8sClient,
+				Scheme:      k8sClient.Scheme(),
+				TemplateDir: "../../templates",
+			}
+			var resources []*nodeswapv1beta1.NodeSwap
+			for _, name := range []string{"test-conflict-a", "test-conflict-b"} {
+				resource := &nodeswapv1beta1.NodeSwap{
+					ObjectMeta: metav1.ObjectMeta{Name: name},
+					Spec: nodeswapv1beta1.NodeSwapSpec{
+						MachineConfigPoolNames: []string{pool.Name},
+					},
+				}
+				Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+				resources = append(resources, resource)
+			}
+			DeferCleanup(func() {
+				for _, resource := range resources {
+					deleteNodeSwap(ctx, resource)
+					mc := &mcfgv1.MachineConfig{ObjectMeta: metav1.ObjectMeta{Name: renderconfig.KubeletMachineConfigName(resource.Name)}}
+					Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, mc))).To(Succeed())
+				}
+				Expect(k8sClient.Delete(ctx, pool)).To(Succeed())
+			})
+
+			expectConflict := func(resource *nodeswapv1beta1.NodeSwap, other string) {
+				result, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+					NamespacedName: client.ObjectKeyFromObject(resource),
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(resource), resource)).To(Succeed())
+				conflict := meta.FindStatusCondition(resource.Status.Conditions, typeConflictNodeSwap)
+				Expect(conflict).NotTo(BeNil())
+				if other == "" {
+					Expect(conflict.Status).To(Equal(metav1.ConditionFalse))
+					return
+				}
+				Expect(result.RequeueAfter).To(Equal(conflictRequeueInterval))
+				Expect(conflict.Status).To(Equal(metav1.ConditionTrue))
+				Expect(conflict.Reason).To(Equal(reasonConflictingNodeSwap))
+				Expect(conflict.Message).To(ContainSubstring(other))
+				Expect(conflict.Message).To(ContainSubstring("MachineConfigPool swap-conflict-pool"))
+			}
+
+			By("Verifying the first NodeSwap wins")
+			expectConflict(resources[0], "")
+			expectConflict(resources[1], resources[0].Name)
+
+			By("Raising the precedence of the second NodeSwap")
+			resources[1].Spec.Precedence = 10
+			Expect(k8sClient.Update(ctx, resources[1])).To(Succeed())
+			expectConflict(resources[1], "")
+			expectConflict(resources[0], resources[1].Name)
+		})
+
+		It("should reconcile NodeSwaps concurrently with a shared reconciler", func() {
+			// Run with -race to catch state shared between reconciliations.
+			const count = 4
+
+			controllerReconciler := &NodeSwapReconciler{
+				Client:                  k8sClient,
+				Scheme:                  k8sClient.Scheme(),
+				TemplateDir:             "../../templates",
+				MaxConcurrentReconciles: count,
+			}
+
+			By("Creating NodeSwaps selecting distinct pools")
+			var pools []*mcfgv1.MachineConfigPool
+			var resources []*nodeswapv1beta1.NodeSwap
+			for i := range count {
+				name := fmt.Sprintf("swap-parallel-%d", i)
+				pool := &mcfgv1.MachineConfigPool{
+					ObjectMeta: metav1.ObjectMeta{Name: name},
+					Spec: mcfgv1.MachineConfigPoolSpec{
+						MachineConfigSelector: &metav1.LabelSelector{
+							MatchLabels: map[string]string{"machineconfiguration.openshift.io/role": name},
+						},
+					},
+				}
+				Expect(k8sClient.Create(ctx, pool)).To(Succeed())
+				pools = append(pools, pool)
+
+				resource := &nodeswapv1beta1.NodeSwap{
+					ObjectMeta: metav1.ObjectMeta{Name: "test-" + name},
+					Spec: nodeswapv1beta1.NodeSwapSpec{
+						MachineConfigPoolNames: []string{name},
+					},
+				}
+				Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+				resources = append(resources, resource)
+			}
+			DeferCleanup(func() {
+				for _, resource := range resources {
+					deleteNodeSwap(ctx, resource)
+					mc := &mcfgv1.MachineConfig{ObjectMeta: metav1.ObjectMeta{Name: renderconfig.KubeletMachineConfigName(resource.Name)}}
+					Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, mc))).To(Succeed())
+				}
+				for _, pool := range pools {
+					Expect(k8sClient.Delete(ctx, pool)).To(Succeed())
+				}
+			})
+
+			By("Reconciling all of them at once")
+			var wg sync.WaitGroup
+			for _, resource := range resources {
+				wg.Add(1)
+				go func() {
+					defer GinkgoRecover()
+					defer wg.Done()
+
+					_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+						NamespacedName: client.ObjectKeyFromObject(resource),
+					})
+					Expect(err).NotTo(HaveOccurred())
+				}()
+			}
+			wg.Wait()
+
+			By("Verifying every NodeSwap was reconciled on its own")
+			for i, resource := range resources {
+				kubeletMC := &mcfgv1.MachineConfig{}
+				Expect(k8sClient.Get(ctx, types.NamespacedName{Name: renderconfig.KubeletMachineConfigName(resource.Name)}, kubeletMC)).To(Succeed())
+				Expect(kubeletMC.Labels).To(HaveKeyWithValue("machineconfiguration.openshift.io/role", pools[i].Name))
+				Expect(ownerRequests(ctx, kubeletMC)).To(ConsistOf(reconcile.Request{
+					NamespacedName: client.ObjectKeyFromObject(resource),
+				}))
+
+				Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(resource), resource)).To(Succeed())
+				Expect(resource.Finalizers).To(ContainElement(nodeSwapFinalizer))
+				conflict := meta.FindStatusCondition(resource.Status.Conditions, typeConflictNodeSwap)
+				Expect(conflict).NotTo(BeNil())
+				Expect(conflict.Status).To(Equal(metav1.ConditionFalse))
+			}
+		})
+
+		It("should release the kubelet MachineConfig NodeSwaps used to share", func() {
+			controllerReconciler := &NodeSwapReconciler{
+				Client:      k8sClient,
+				Scheme:      k8sClient.Scheme(),
+				TemplateDir: "../../templates",
+			}
+
+			By("Creating two NodeSwaps owning the shared kubelet MachineConfig")
+			var pools []*mcfgv1.MachineConfigPool
+			var resources []*nodeswapv1beta1.NodeSwap
+			shared := &mcfgv1.MachineConfig{ObjectMeta: metav1.ObjectMeta{Name: renderconfig.SwapKubeletCgroupsMCPrefix}}
+			for _, name := range []string{"swap-legacy-a", "swap-legacy-b"} {
+				pool := &mcfgv1.MachineConfigPool{
+					ObjectMeta: metav1.ObjectMeta{Name: name},
+					Spec: mcfgv1.MachineConfigPoolSpec{
+						MachineConfigSelector: &metav1.LabelSelector{
+							MatchLabels: map[string]string{"machineconfiguration.openshift.io/role": name},
+						},
+					},
+				}
+				Expect(k8sClient.Create(ctx, pool)).To(Succeed())
+				pools = append(pools, pool)
+
+				resource := &nodeswapv1beta1.NodeSwap{
+					ObjectMeta: metav1.ObjectMeta{Name: "test-" + name},
+					Spec: nodeswapv1beta1.NodeSwapSpec{
+						MachineConfigPoolNames: []string{name},
+					},
+				}
+				Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+				resources = append(resources, resource)
+				shared.OwnerReferences = append(shared.OwnerReferences, metav1.OwnerReference{
+					APIVersion: nodeswapv1beta1.GroupVersion.String(),
+					Kind:       "NodeSwap",
+					Name:       resource.Name,
+					UID:        resource.UID,
+				})
+			}
+			Expect(k8sClient.Create(ctx, shared)).To(Succeed())
+			DeferCleanup(func() {
+				for _, resource := range resources {
+					deleteNodeSwap(ctx, resource)
+					mc := &mcfgv1.MachineConfig{ObjectMeta: metav1.ObjectMeta{Name: renderconfig.KubeletMachineConfigName(resource.Name)}}
+					Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, mc))).To(Succeed())
+				}
+				for _, pool := range pools {
+					Expect(k8sClient.Delete(ctx, pool)).To(Succeed())
+				}
+				Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, shared))).To(Succeed())
+			})
+
+			By("Reconciling the first NodeSwap")
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: client.ObjectKeyFromObject(resources[0]),
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(shared), shared)).To(Succeed())
+			Expect(ownerRequests(ctx, shared)).To(ConsistOf(reconcile.Request{
+				NamespacedName: client.ObjectKeyFromObject(resources[1]),
+			}))
+			kubeletMC := &mcfgv1.MachineConfig{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: renderconfig.KubeletMachineConfigName(resources[0].Name)}, kubeletMC)).To(Succeed())
+
+			By("Deleting it once the second NodeSwap released it too")
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: client.ObjectKeyFromObject(resources[1]),
+			})
+			Expect(err).NotTo(HaveOccurred())
+			err = k8sClient.Get(ctx, client.ObjectKeyFromObject(shared), shared)
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+		})
+
+		It("should block the rollout when cluster prerequisites are not met", func() {
+			By("Reporting a cluster version older than the minimum supported one")
+			clusterVersion := &configv1.ClusterVersion{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: clusterVersionName}, clusterVersion)).To(Succeed())
+			previousVersion := clusterVersion.Status.Desired.Version
+			clusterVersion.Status.Desired.Version = "4.14.0"
+			Expect(k8sClient.Status().Update(ctx, clusterVersion)).To(Succeed())
+			DeferCleanup(func() {
+				Expect(k8sClient.Get(ctx, types.NamespacedName{Name: clusterVersionName}, clusterVersion)).To(Succeed())
+				clusterVersion.Status.Desired.Version = previousVersion
+				Expect(k8sClient.Status().Update(ctx, clusterVersion)).To(Succeed())
+			})
+
+			By("Reconciling the created resource")
+			controllerReconciler := &NodeSwapReconciler{
+				Client:      k8sClient,
+				Scheme:      k8sClient.Scheme(),
+				TemplateDir: "../../templates",
+			}
+
+			result, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(prerequisitesRequeueInterval))
+
+			By("Verifying that the PrerequisitesMet condition explains what is missing")
+			updatedResource := &nodeswapv1beta1.NodeSwap{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, updatedResource)).To(Succeed())
+
+			prerequisitesCondition := meta.FindStatusCondition(updatedResource.Status.Conditions, "PrerequisitesMet")
+			Expect(prerequisitesCondition).NotTo(BeNil())
+			Expect(prerequisitesCondition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(prerequisitesCondition.Reason).To(Equal("PrerequisitesNotMet"))
+			Expect(prerequisitesCondition.Message).To(ContainSubstring("4.14.0"))
+
+			availableCondition := meta.FindStatusCondition(updatedResource.Status.Conditions, "Available")
+			Expect(availableCondition).NotTo(BeNil())
+			Expect(availableCondition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(availableCondition.Reason).To(Equal("PrerequisitesNotMet"))
 		})
 	})
 })
+
+// recordedEvents drains the events recorded so far.
+func recordedEvents(recorder *record.FakeRecorder) []string {
+	var events []string
+	for {
+		select {
+		case event := <-recorder.Events:
+			events = append(events, event)
+		default:
+			return events
+		}
+	}
+}
+
+// createPool creates a MachineConfigPool selecting MachineConfigs by
+// mcLabels, as machineConfigPoolSelector matches pools, and deletes it once
+// the spec is done.
+func createPool(ctx context.Context, name string, mcLabels map[string]string) {
+	GinkgoHelper()
+	pool := &mcfgv1.MachineConfigPool{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: mcfgv1.MachineConfigPoolSpec{
+			MachineConfigSelector: &metav1.LabelSelector{MatchLabels: mcLabels},
+		},
+	}
+	Expect(k8sClient.Create(ctx, pool)).To(Succeed())
+	DeferCleanup(func() {
+		Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, pool))).To(Succeed())
+	})
+}
+
+// createWorkerNodes creates the worker pool with one worker node per
+// hardware label, so that a node selector on the label covers part of it.
+func createWorkerNodes(ctx context.Context, hw ...string) {
+	GinkgoHelper()
+	pool := &mcfgv1.MachineConfigPool{
+		ObjectMeta: metav1.ObjectMeta{Name: "worker"},
+		Spec: mcfgv1.MachineConfigPoolSpec{
+			MachineConfigSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"machineconfiguration.openshift.io/role": "worker"},
+			},
+			NodeSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"node-role.kubernetes.io/worker": ""},
+			},
+		},
+	}
+	Expect(k8sClient.Create(ctx, pool)).To(Succeed())
+	DeferCleanup(func() {
+		Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, pool))).To(Succeed())
+	})
+
+	for _, label := range hw {
+		node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{
+			Name:   "worker-" + label,
+			Labels: map[string]string{"node-role.kubernetes.io/worker": "", "hw": label},
+		}}
+		Expect(k8sClient.Create(ctx, node)).To(Succeed())
+		DeferCleanup(func() {
+			Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, node))).To(Succeed())
+		})
+	}
+}
+
+// renderPool renders the named MachineConfigs into the rendered
+// configuration renderedName of the pool, as the machine-config operator
+// does.
+func renderPool(ctx context.Context, pool *mcfgv1.MachineConfigPool, renderedName string, names ...string) {
+	GinkgoHelper()
+	merged := ign3types.Config{Ignition: ign3types.Ignition{Version: ign3types.MaxVersion.String()}}
+	var sources []corev1.ObjectReference
+	for _, name := range names {
+		mc := &mcfgv1.MachineConfig{}
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: name}, mc)).To(Succeed())
+		config, err := common.ParseAndConvertConfig(mc.Spec.Config.Raw)
+		Expect(err).NotTo(HaveOccurred())
+		merged.Storage.Files = append(merged.Storage.Files, config.Storage.Files...)
+		merged.Systemd.Units = append(merged.Systemd.Units, config.Systemd.Units...)
+		sources = append(sources, corev1.ObjectReference{Name: name})
+	}
+	raw, err := json.Marshal(merged)
+	Expect(err).NotTo(HaveOccurred())
+	rendered := &mcfgv1.MachineConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: renderedName},
+		Spec:       mcfgv1.MachineConfigSpec{Config: runtime.RawExtension{Raw: raw}},
+	}
+	Expect(k8sClient.Create(ctx, rendered)).To(Succeed())
+	DeferCleanup(func() {
+		Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, rendered))).To(Succeed())
+	})
+
+	Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(pool), pool)).To(Succeed())
+	pool.Spec.Configuration = mcfgv1.MachineConfigPoolStatusConfiguration{
+		ObjectReference: corev1.ObjectReference{Name: renderedName},
+		Source:          sources,
+	}
+	Expect(k8sClient.Update(ctx, pool)).To(Succeed())
+}
+
+// rollOutPool renders the named MachineConfigs into the rendered
+// configuration renderedName and reports all the machines of the pool
+// updated to it.
+func rollOutPool(ctx context.Context, pool *mcfgv1.MachineConfigPool, renderedName string, names ...string) {
+	GinkgoHelper()
+	renderPool(ctx, pool, renderedName, names...)
+	pool.Status.Configuration = pool.Spec.Configuration
+	pool.Status.Conditions = []mcfgv1.MachineConfigPoolCondition{{
+		Type:               mcfgv1.MachineConfigPoolUpdated,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+	}}
+	Expect(k8sClient.Status().Update(ctx, pool)).To(Succeed())
+}
+
+// deleteNodeSwap deletes the NodeSwap and reconciles the deletion so that
+// its finalizer is released.
+func deleteNodeSwap(ctx context.Context, resource *nodeswapv1beta1.NodeSwap) {
+	Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+
+	controllerReconciler := &NodeSwapReconciler{
+		Client:      k8sClient,
+		Scheme:      k8sClient.Scheme(),
+		TemplateDir: "../../templates",
+	}
+	_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+		NamespacedName: client.ObjectKeyFromObject(resource),
+	})
+	Expect(err).NotTo(HaveOccurred())
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"slices"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	nodeswap "github.com/openshift-virtualization/swap-operator/api/v1beta1"
+)
+
+// ownerNameLabel identifies the NodeSwap owning a MachineConfig or a
+// MachineConfigPool. Unlike owner references, it can be used to list the
+// resources of a NodeSwap.
+const ownerNameLabel = "node-swap.openshift.io/owner-name"
+
+// ownerLabels returns the labels marking a resource as owned by the NodeSwap.
+func ownerLabels(s *reconcileState) map[string]string {
+	return map[string]string{
+		ownerNameLabel: s.desiredNodeSwap.Name,
+	}
+}
+
+// setOwnerReference adds the NodeSwap to the owners of obj. MachineConfigs
+// such as the kubelet one are shared, so the reference is not a controller
+// reference and the object is garbage collected once all its owners are gone.
+func (r *NodeSwapReconciler) setOwnerReference(s *reconcileState, obj client.Object) error {
+	if err := controllerutil.SetOwnerReference(&s.desiredNodeSwap, obj, r.Scheme); err != nil {
+		logf.FromContext(s.ctx).Error(err, "Failed to set owner reference", "name", obj.GetName())
+		return err
+	}
+
+	return nil
+}
+
+// ownerRequests maps an object owned by NodeSwaps, through its owner
+// references or its owner label, to reconcile requests for its owners.
+func ownerRequests(_ context.Context, obj client.Object) []reconcile.Request {
+	var requests []reconcile.Request
+	add := func(name string) {
+		request := reconcile.Request{NamespacedName: types.NamespacedName{Name: name}}
+		if !slices.Contains(requests, request) {
+			requests = append(requests, request)
+		}
+	}
+
+	for _, ref := range obj.GetOwnerReferences() {
+		gv, err := schema.ParseGroupVersion(ref.APIVersion)
+		if err == nil && gv.Group == nodeswap.GroupVersion.Group && ref.Kind == "NodeSwap" {
+			add(ref.Name)
+		}
+	}
+	if name := obj.GetLabels()[ownerNameLabel]; name != "" {
+		add(name)
+	}
+
+	return requests
+}
@@ -0,0 +1,72 @@
+package controller
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	mcfgv1 "github.com/openshift/api/machineconfiguration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestOwnerRequests(t *testing.T) {
+	ownerRef := func(apiVersion, kind, name string) metav1.OwnerReference {
+		return metav1.OwnerReference{APIVersion: apiVersion, Kind: kind, Name: name}
+	}
+	request := func(name string) reconcile.Request {
+		return reconcile.Request{NamespacedName: types.NamespacedName{Name: name}}
+	}
+
+	tests := []struct {
+		name   string
+		labels map[string]string
+		owners []metav1.OwnerReference
+		want   []reconcile.Request
+	}{
+		{
+			name: "not owned",
+		},
+		{
+			name: "owner references of every NodeSwap version",
+			owners: []metav1.OwnerReference{
+				ownerRef("node-swap.openshift.io/v1beta1", "NodeSwap", "a"),
+				ownerRef("node-swap.openshift.io/v1alpha1", "NodeSwap", "b"),
+			},
+			want: []reconcile.Request{request("a"), request("b")},
+		},
+		{
+			name: "foreign owner references are ignored",
+			owners: []metav1.OwnerReference{
+				ownerRef("machineconfiguration.openshift.io/v1", "MachineConfigPool", "worker"),
+				ownerRef("example.com/v1", "NodeSwap", "other"),
+			},
+		},
+		{
+			name:   "owner label",
+			labels: map[string]string{ownerNameLabel: "a"},
+			want:   []reconcile.Request{request("a")},
+		},
+		{
+			name:   "owner label and reference to the same NodeSwap",
+			labels: map[string]string{ownerNameLabel: "a"},
+			owners: []metav1.OwnerReference{ownerRef("node-swap.openshift.io/v1beta1", "NodeSwap", "a")},
+			want:   []reconcile.Request{request("a")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mc := &mcfgv1.MachineConfig{ObjectMeta: metav1.ObjectMeta{
+				Name:            "99-filebased-swap-0",
+				Labels:          tt.labels,
+				OwnerReferences: tt.owners,
+			}}
+			got := ownerRequests(context.Background(), mc)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("ownerRequests() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
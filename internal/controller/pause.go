@@ -0,0 +1,118 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"slices"
+
+	mcfgv1 "github.com/openshift/api/machineconfiguration/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// pausedByAnnotation is set on the MachineConfigPools a NodeSwap paused, to
+// the name of the NodeSwap. Pools paused by anyone else are never resumed.
+const pausedByAnnotation = "node-swap.openshift.io/paused-by"
+
+// pausePlan is the change to the paused MachineConfigPools.
+type pausePlan struct {
+	// pause are the pools to pause.
+	pause []*mcfgv1.MachineConfigPool
+	// resume are the pools paused by the NodeSwap to resume.
+	resume []*mcfgv1.MachineConfigPool
+	// paused are the names of the pools paused by the NodeSwap once the
+	// plan is applied.
+	paused []string
+}
+
+// ReconcilePause pauses the selected pools while spec.paused is set, and
+// the pools held back by the staged rollout otherwise. It resumes the other
+// pools the NodeSwap paused.
+func (r *NodeSwapReconciler) ReconcilePause(s *reconcileState) error {
+	names := s.heldPools
+	if s.desiredNodeSwap.Spec.Paused {
+		names = nil
+		for _, mcp := range s.pools {
+			names = append(names, mcp.Name)
+		}
+	}
+
+	return r.pausePools(s, names)
+}
+
+// pausePools pauses the named pools and resumes the other pools paused by
+// the NodeSwap.
+func (r *NodeSwapReconciler) pausePools(s *reconcileState, names []string) error {
+	mcpList := &mcfgv1.MachineConfigPoolList{}
+	if err := r.List(s.ctx, mcpList); err != nil {
+		logf.FromContext(s.ctx).Error(err, "Failed to list MachineConfigPools")
+		return err
+	}
+
+	plan := planPause(s.desiredNodeSwap.Name, names, mcpList.Items)
+	for _, mcp := range plan.pause {
+		mcp.Spec.Paused = true
+		if mcp.Annotations == nil {
+			mcp.Annotations = map[string]string{}
+		}
+		mcp.Annotations[pausedByAnnotation] = s.desiredNodeSwap.Name
+		if err := r.Update(s.ctx, mcp); err != nil {
+			logf.FromContext(s.ctx).Error(err, "Failed to pause MachineConfigPool", "name", mcp.Name)
+			return err
+		}
+		logf.FromContext(s.ctx).Info("Paused MachineConfigPool", "name", mcp.Name)
+	}
+	for _, mcp := range plan.resume {
+		mcp.Spec.Paused = false
+		delete(mcp.Annotations, pausedByAnnotation)
+		if err := r.Update(s.ctx, mcp); err != nil {
+			logf.FromContext(s.ctx).Error(err, "Failed to resume MachineConfigPool", "name", mcp.Name)
+			return err
+		}
+		logf.FromContext(s.ctx).Info("Resumed MachineConfigPool", "name", mcp.Name)
+	}
+	s.pausedPools = plan.paused
+
+	return nil
+}
+
+// planPause plans pausing the named pools for the NodeSwap. Pools that are
+// already paused by someone else are left alone, and so are not recorded as
+// paused by the NodeSwap. Pools the NodeSwap paused that are not named are
+// resumed.
+func planPause(nodeSwapName string, names []string, pools []mcfgv1.MachineConfigPool) *pausePlan {
+	pause := sets.New(names...)
+	plan := &pausePlan{}
+	for i := range pools {
+		mcp := &pools[i]
+		pausedBy, annotated := mcp.Annotations[pausedByAnnotation]
+		ours := annotated && pausedBy == nodeSwapName
+
+		switch {
+		case pause.Has(mcp.Name) && !mcp.Spec.Paused:
+			plan.pause = append(plan.pause, mcp)
+			plan.paused = append(plan.paused, mcp.Name)
+		case pause.Has(mcp.Name) && ours:
+			plan.paused = append(plan.paused, mcp.Name)
+		case !pause.Has(mcp.Name) && ours:
+			plan.resume = append(plan.resume, mcp)
+		}
+	}
+	slices.Sort(plan.paused)
+
+	return plan
+}
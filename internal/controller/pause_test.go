@@ -0,0 +1,78 @@
+package controller
+
+import (
+	"slices"
+	"testing"
+
+	mcfgv1 "github.com/openshift/api/machineconfiguration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPlanPause(t *testing.T) {
+	pool := func(name string, paused bool, pausedBy string) mcfgv1.MachineConfigPool {
+		mcp := mcfgv1.MachineConfigPool{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		mcp.Spec.Paused = paused
+		if pausedBy != "" {
+			mcp.Annotations = map[string]string{pausedByAnnotation: pausedBy}
+		}
+		return mcp
+	}
+	pools := []mcfgv1.MachineConfigPool{
+		pool("worker", false, ""),
+		// Paused by an administrator.
+		pool("infra", true, ""),
+		pool("canary", true, "swap"),
+		pool("master", false, ""),
+		// Paused by another NodeSwap.
+		pool("storage", true, "other"),
+	}
+
+	names := func(mcps []*mcfgv1.MachineConfigPool) []string {
+		var names []string
+		for _, mcp := range mcps {
+			names = append(names, mcp.Name)
+		}
+		return names
+	}
+
+	tests := []struct {
+		name       string
+		pause      []string
+		wantPause  []string
+		wantResume []string
+		wantPaused []string
+	}{
+		{
+			name:       "pause",
+			pause:      []string{"worker", "infra", "canary", "storage"},
+			wantPause:  []string{"worker"},
+			wantPaused: []string{"canary", "worker"},
+		},
+		{
+			name:       "resume",
+			wantResume: []string{"canary"},
+		},
+		{
+			name:       "pool no longer selected",
+			pause:      []string{"worker"},
+			wantPause:  []string{"worker"},
+			wantResume: []string{"canary"},
+			wantPaused: []string{"worker"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := planPause("swap", tt.pause, slices.Clone(pools))
+			if got := names(plan.pause); !slices.Equal(got, tt.wantPause) {
+				t.Errorf("planPause() pause = %v, want %v", got, tt.wantPause)
+			}
+			if got := names(plan.resume); !slices.Equal(got, tt.wantResume) {
+				t.Errorf("planPause() resume = %v, want %v", got, tt.wantResume)
+			}
+			if !slices.Equal(plan.paused, tt.wantPaused) {
+				t.Errorf("planPause() paused = %v, want %v", plan.paused, tt.wantPaused)
+			}
+		})
+	}
+}
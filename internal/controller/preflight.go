@@ -0,0 +1,175 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/coreos/go-semver/semver"
+	configv1 "github.com/openshift/api/config/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// clusterConfigName is the name of the cluster scoped config.openshift.io singletons.
+	clusterConfigName = "cluster"
+	// clusterVersionName is the name of the ClusterVersion singleton.
+	clusterVersionName = "version"
+
+	// nodeSwapFeatureGate is the kubelet feature gate LimitedSwap depends on.
+	nodeSwapFeatureGate configv1.FeatureGateName = "NodeSwap"
+
+	// minimumClusterVersion is the oldest OpenShift release the generated
+	// kubelet and swap MachineConfigs are known to work on.
+	minimumClusterVersion = "4.18.0"
+
+	// prerequisitesRequeueInterval is how often unmet prerequisites are re-checked.
+	prerequisitesRequeueInterval = 5 * time.Minute
+)
+
+// prerequisitesError reports the cluster prerequisites that are not met.
+// It blocks the rollout but is not a reconciliation failure, so it is
+// surfaced as a condition and re-checked periodically instead of retried
+// with backoff.
+type prerequisitesError struct {
+	missing []string
+}
+
+func (e *prerequisitesError) Error() string {
+	return fmt.Sprintf("cluster prerequisites not met: %s", strings.Join(e.missing, "; "))
+}
+
+// ReconcilePrerequisites verifies that the cluster can run swap-enabled
+// nodes before any MachineConfig is rolled out.
+func (r *NodeSwapReconciler) ReconcilePrerequisites(s *reconcileState) (ctrl.Result, error) {
+	var nodeConfig *configv1.Node
+	node := &configv1.Node{}
+	if err := r.Get(s.ctx, types.NamespacedName{Name: clusterConfigName}, node); err == nil {
+		nodeConfig = node
+	} else if !apierrors.IsNotFound(err) {
+		logf.FromContext(s.ctx).Error(err, "Failed to get cluster node config")
+		return ctrl.Result{}, err
+	}
+
+	var clusterVersion *configv1.ClusterVersion
+	cv := &configv1.ClusterVersion{}
+	if err := r.Get(s.ctx, types.NamespacedName{Name: clusterVersionName}, cv); err == nil {
+		clusterVersion = cv
+		s.clusterVersion = cv
+	} else if !apierrors.IsNotFound(err) {
+		logf.FromContext(s.ctx).Error(err, "Failed to get ClusterVersion")
+		return ctrl.Result{}, err
+	}
+
+	var featureGate *configv1.FeatureGate
+	fg := &configv1.FeatureGate{}
+	if err := r.Get(s.ctx, types.NamespacedName{Name: clusterConfigName}, fg); err == nil {
+		featureGate = fg
+	} else if !apierrors.IsNotFound(err) {
+		logf.FromContext(s.ctx).Error(err, "Failed to get FeatureGate")
+		return ctrl.Result{}, err
+	}
+
+	if missing := checkPrerequisites(nodeConfig, clusterVersion, featureGate); len(missing) > 0 {
+		err := &prerequisitesError{missing: missing}
+		logf.FromContext(s.ctx).Info("Cluster prerequisites not met", "missing", missing)
+		return ctrl.Result{RequeueAfter: prerequisitesRequeueInterval}, err
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// checkPrerequisites returns a human readable entry for every prerequisite
+// that is not met. A missing node config or FeatureGate means the cluster
+// runs with the defaults, which satisfy the prerequisites.
+func checkPrerequisites(node *configv1.Node, cv *configv1.ClusterVersion, fg *configv1.FeatureGate) []string {
+	var missing []string
+
+	if node != nil && node.Spec.CgroupMode != configv1.CgroupModeV2 && node.Spec.CgroupMode != configv1.CgroupModeEmpty {
+		missing = append(missing, fmt.Sprintf("LimitedSwap requires cgroup v2, nodes.config.openshift.io/%s has cgroupMode %q",
+			clusterConfigName, node.Spec.CgroupMode))
+	}
+
+	version := ""
+	if cv == nil {
+		missing = append(missing, fmt.Sprintf("clusterversions.config.openshift.io/%s not found", clusterVersionName))
+	} else {
+		version = cv.Status.Desired.Version
+		if msg := checkClusterVersion(version); msg != "" {
+			missing = append(missing, msg)
+		}
+	}
+
+	if fg != nil && isFeatureGateDisabled(fg, version, nodeSwapFeatureGate) {
+		missing = append(missing, fmt.Sprintf("feature gate %s is disabled in featuregates.config.openshift.io/%s",
+			nodeSwapFeatureGate, clusterConfigName))
+	}
+
+	return missing
+}
+
+// checkClusterVersion returns a message if the version cannot be parsed or
+// is older than minimumClusterVersion.
+func checkClusterVersion(version string) string {
+	if version == "" {
+		return "cluster version is not reported yet"
+	}
+
+	current, err := semver.NewVersion(version)
+	if err != nil {
+		return fmt.Sprintf("unable to parse cluster version %q: %v", version, err)
+	}
+
+	minimum := semver.New(minimumClusterVersion)
+	// Pre-release builds (nightlies, release candidates) of the minimum
+	// release are accepted.
+	release := semver.Version{Major: current.Major, Minor: current.Minor, Patch: current.Patch}
+	if release.LessThan(*minimum) {
+		return fmt.Sprintf("cluster version %s is older than the minimum supported version %s",
+			version, minimumClusterVersion)
+	}
+
+	return ""
+}
+
+// isFeatureGateDisabled reports whether gate is explicitly disabled, either
+// in the CustomNoUpgrade spec or in the status for the given payload version.
+func isFeatureGateDisabled(fg *configv1.FeatureGate, version string, gate configv1.FeatureGateName) bool {
+	if fg.Spec.FeatureSet == configv1.CustomNoUpgrade && fg.Spec.CustomNoUpgrade != nil &&
+		slices.Contains(fg.Spec.CustomNoUpgrade.Disabled, gate) {
+		return true
+	}
+
+	for _, details := range fg.Status.FeatureGates {
+		if details.Version != version {
+			continue
+		}
+		for _, disabled := range details.Disabled {
+			if disabled.Name == gate {
+				return true
+			}
+		}
+	}
+
+	return false
+}
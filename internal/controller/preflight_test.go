@@ -0,0 +1,110 @@
+package controller
+
+import (
+	"strings"
+	"testing"
+
+	configv1 "github.com/openshift/api/config/v1"
+)
+
+func TestCheckPrerequisites(t *testing.T) {
+	clusterVersion := func(version string) *configv1.ClusterVersion {
+		return &configv1.ClusterVersion{
+			Status: configv1.ClusterVersionStatus{
+				Desired: configv1.Release{Version: version},
+			},
+		}
+	}
+
+	tests := []struct {
+		name        string
+		node        *configv1.Node
+		cv          *configv1.ClusterVersion
+		fg          *configv1.FeatureGate
+		wantMissing []string
+	}{
+		{
+			name: "defaults on a supported release",
+			cv:   clusterVersion("4.18.3"),
+		},
+		{
+			name: "nightly of the minimum release",
+			node: &configv1.Node{Spec: configv1.NodeSpec{CgroupMode: configv1.CgroupModeV2}},
+			cv:   clusterVersion("4.18.0-0.nightly-2025-01-01-000000"),
+		},
+		{
+			name:        "cgroup v1",
+			node:        &configv1.Node{Spec: configv1.NodeSpec{CgroupMode: "v1"}},
+			cv:          clusterVersion("4.19.0"),
+			wantMissing: []string{"cgroup v2"},
+		},
+		{
+			name:        "missing cluster version",
+			wantMissing: []string{"clusterversions.config.openshift.io/version not found"},
+		},
+		{
+			name:        "old cluster version",
+			cv:          clusterVersion("4.16.9"),
+			wantMissing: []string{"older than the minimum supported version"},
+		},
+		{
+			name:        "unparsable cluster version",
+			cv:          clusterVersion("latest"),
+			wantMissing: []string{"unable to parse cluster version"},
+		},
+		{
+			name: "NodeSwap disabled through CustomNoUpgrade",
+			cv:   clusterVersion("4.19.0"),
+			fg: &configv1.FeatureGate{
+				Spec: configv1.FeatureGateSpec{FeatureGateSelection: configv1.FeatureGateSelection{
+					FeatureSet: configv1.CustomNoUpgrade,
+					CustomNoUpgrade: &configv1.CustomFeatureGates{
+						Disabled: []configv1.FeatureGateName{"NodeSwap"},
+					},
+				}},
+			},
+			wantMissing: []string{"feature gate NodeSwap is disabled"},
+		},
+		{
+			name: "NodeSwap disabled for the current payload",
+			cv:   clusterVersion("4.19.0"),
+			fg: &configv1.FeatureGate{
+				Status: configv1.FeatureGateStatus{FeatureGates: []configv1.FeatureGateDetails{
+					{Version: "4.18.0", Enabled: []configv1.FeatureGateAttributes{{Name: "NodeSwap"}}},
+					{Version: "4.19.0", Disabled: []configv1.FeatureGateAttributes{{Name: "NodeSwap"}}},
+				}},
+			},
+			wantMissing: []string{"feature gate NodeSwap is disabled"},
+		},
+		{
+			name: "NodeSwap only disabled for a previous payload",
+			cv:   clusterVersion("4.19.0"),
+			fg: &configv1.FeatureGate{
+				Status: configv1.FeatureGateStatus{FeatureGates: []configv1.FeatureGateDetails{
+					{Version: "4.18.0", Disabled: []configv1.FeatureGateAttributes{{Name: "NodeSwap"}}},
+					{Version: "4.19.0", Enabled: []configv1.FeatureGateAttributes{{Name: "NodeSwap"}}},
+				}},
+			},
+		},
+		{
+			name:        "every prerequisite missing",
+			node:        &configv1.Node{Spec: configv1.NodeSpec{CgroupMode: "v1"}},
+			cv:          clusterVersion("4.12.0"),
+			wantMissing: []string{"cgroup v2", "older than the minimum supported version"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			missing := checkPrerequisites(tt.node, tt.cv, tt.fg)
+			if len(missing) != len(tt.wantMissing) {
+				t.Fatalf("checkPrerequisites() = %q, want %d entries", missing, len(tt.wantMissing))
+			}
+			for i, want := range tt.wantMissing {
+				if !strings.Contains(missing[i], want) {
+					t.Errorf("checkPrerequisites()[%d] = %q, want it to contain %q", i, missing[i], want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,248 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	mcfgv1 "github.com/openshift/api/machineconfiguration/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/openshift-virtualization/swap-operator/internal/renderconfig"
+)
+
+const (
+	// revisionHashLength is the length of the content hash ending the name
+	// of a ControllerRevision.
+	revisionHashLength = 10
+	// revisionGenerationAnnotation is the generation of the NodeSwap a
+	// ControllerRevision was last rolled out for.
+	revisionGenerationAnnotation = "node-swap.openshift.io/generation"
+	// revisionHashAnnotation is the hash of the content of a
+	// ControllerRevision.
+	revisionHashAnnotation = "node-swap.openshift.io/content-hash"
+
+	// defaultRevisionHistoryLimit is the number of ControllerRevisions kept
+	// when spec.revisionHistoryLimit is unset.
+	defaultRevisionHistoryLimit = 10
+)
+
+// revisionData is the content of a ControllerRevision: the swap and
+// systemd-oomd MachineConfigs rolled out for a NodeSwap.
+type revisionData struct {
+	MachineConfigs []mcfgv1.MachineConfig `json:"machineConfigs"`
+}
+
+// ReconcileRevisions stores the MachineConfigs applied for the NodeSwap as a
+// ControllerRevision once every selected pool rolled them out, and prunes
+// the revisions beyond spec.revisionHistoryLimit. Revisions are only kept
+// when the reconciler has a namespace to store them in.
+func (r *NodeSwapReconciler) ReconcileRevisions(s *reconcileState) error {
+	if r.Namespace == "" || len(s.rendered) == 0 {
+		return nil
+	}
+	names := machineConfigNames(s.machineConfigs)
+	if slices.ContainsFunc(s.pools, func(mcp *mcfgv1.MachineConfigPool) bool {
+		return !rolledOut(mcp, names, s.renderedPools)
+	}) {
+		return nil
+	}
+
+	raw, hash, err := newRevisionData(s.rendered)
+	if err != nil {
+		logf.FromContext(s.ctx).Error(err, "Failed to encode ControllerRevision")
+		return err
+	}
+
+	revisions := &appsv1.ControllerRevisionList{}
+	if err := r.List(s.ctx, revisions, client.InNamespace(r.Namespace),
+		client.MatchingLabels(ownerLabels(s))); err != nil {
+		logf.FromContext(s.ctx).Error(err, "Failed to list ControllerRevisions")
+		return err
+	}
+
+	var latest int64
+	var current *appsv1.ControllerRevision
+	name := revisionName(s.desiredNodeSwap.Name, hash)
+	for i := range revisions.Items {
+		revision := &revisions.Items[i]
+		latest = max(latest, revision.Revision)
+		if revision.Name == name {
+			current = revision
+		}
+	}
+	generation := strconv.FormatInt(s.desiredNodeSwap.Generation, 10)
+
+	if current == nil {
+		current = &appsv1.ControllerRevision{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: r.Namespace,
+				Labels:    ownerLabels(s),
+				Annotations: map[string]string{
+					revisionGenerationAnnotation: generation,
+					revisionHashAnnotation:       hash,
+				},
+			},
+			Data:     runtime.RawExtension{Raw: raw},
+			Revision: latest + 1,
+		}
+		if err := r.setOwnerReference(s, current); err != nil {
+			return err
+		}
+		if err := r.Create(s.ctx, current); err != nil {
+			logf.FromContext(s.ctx).Error(err, "Failed to create ControllerRevision", "name", name)
+			return err
+		}
+		logf.FromContext(s.ctx).Info("Created ControllerRevision", "name", name, "revision", current.Revision)
+		revisions.Items = append(revisions.Items, *current)
+	} else if current.Revision != latest {
+		// Rolling out a previous revision again makes it the latest one.
+		current.Revision = latest + 1
+		current.Annotations[revisionGenerationAnnotation] = generation
+		if err := r.Update(s.ctx, current); err != nil {
+			logf.FromContext(s.ctx).Error(err, "Failed to update ControllerRevision", "name", name)
+			return err
+		}
+		logf.FromContext(s.ctx).Info("Updated ControllerRevision", "name", name, "revision", current.Revision)
+	}
+	s.currentRevision = name
+
+	limit := defaultRevisionHistoryLimit
+	if s.desiredNodeSwap.Spec.RevisionHistoryLimit != nil {
+		limit = int(*s.desiredNodeSwap.Spec.RevisionHistoryLimit)
+	}
+	for _, revision := range prunedRevisions(revisions.Items, limit, name, s.desiredNodeSwap.Spec.RollbackToRevision) {
+		if err := r.Delete(s.ctx, revision); client.IgnoreNotFound(err) != nil {
+			logf.FromContext(s.ctx).Error(err, "Failed to delete ControllerRevision", "name", revision.Name)
+			return err
+		}
+		logf.FromContext(s.ctx).Info("Deleted ControllerRevision", "name", revision.Name)
+	}
+
+	return nil
+}
+
+// revisionMachineConfigs returns the MachineConfigs of the ControllerRevision
+// named by spec.rollbackToRevision, labeled for the selected pools.
+func (r *NodeSwapReconciler) revisionMachineConfigs(s *reconcileState) ([]*mcfgv1.MachineConfig, error) {
+	name := s.desiredNodeSwap.Spec.RollbackToRevision
+	if r.Namespace == "" {
+		return nil, fmt.Errorf("cannot roll back to ControllerRevision %s, revision history is disabled", name)
+	}
+
+	revision := &appsv1.ControllerRevision{}
+	if err := r.Get(s.ctx, types.NamespacedName{Namespace: r.Namespace, Name: name}, revision); err != nil {
+		if apierrors.IsNotFound(err) {
+			err = fmt.Errorf("ControllerRevision %s not found in namespace %s", name, r.Namespace)
+		}
+		logf.FromContext(s.ctx).Error(err, "Failed to get ControllerRevision", "name", name)
+		return nil, err
+	}
+	if revision.Labels[ownerNameLabel] != s.desiredNodeSwap.Name {
+		return nil, fmt.Errorf("ControllerRevision %s is not a revision of NodeSwap %s", name, s.desiredNodeSwap.Name)
+	}
+
+	data := &revisionData{}
+	if err := json.Unmarshal(revision.Data.Raw, data); err != nil {
+		logf.FromContext(s.ctx).Error(err, "Failed to decode ControllerRevision", "name", name)
+		return nil, err
+	}
+	logf.FromContext(s.ctx).Info("Rolling back to ControllerRevision", "name", name, "revision", revision.Revision)
+
+	desired := make([]*mcfgv1.MachineConfig, 0, len(data.MachineConfigs))
+	for i := range data.MachineConfigs {
+		mc := &data.MachineConfigs[i]
+		mc.Labels = map[string]string{}
+		maps.Copy(mc.Labels, s.mcLabels)
+		maps.Copy(mc.Labels, ownerLabels(s))
+		if err := setMachineConfigHash(mc); err != nil {
+			return nil, err
+		}
+		desired = append(desired, mc)
+	}
+
+	return desired, nil
+}
+
+// newRevisionData encodes the name and spec of the MachineConfigs as the
+// content of a ControllerRevision and returns it with its hash.
+func newRevisionData(mcs []*mcfgv1.MachineConfig) ([]byte, string, error) {
+	data := revisionData{MachineConfigs: make([]mcfgv1.MachineConfig, 0, len(mcs))}
+	for _, mc := range mcs {
+		data.MachineConfigs = append(data.MachineConfigs, mcfgv1.MachineConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: mc.Name},
+			Spec:       mc.Spec,
+		})
+	}
+	slices.SortFunc(data.MachineConfigs, func(a, b mcfgv1.MachineConfig) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, "", err
+	}
+	sum := sha256.Sum256(raw)
+
+	return raw, hex.EncodeToString(sum[:]), nil
+}
+
+// revisionName returns the name of the ControllerRevision of a NodeSwap
+// with the given content hash. A long NodeSwap name is truncated so that
+// the name stays a valid DNS subdomain.
+func revisionName(nodeSwapName, hash string) string {
+	suffix := "-" + hash[:revisionHashLength]
+	maxLength := validation.DNS1123SubdomainMaxLength - len(suffix)
+	return renderconfig.TruncateName(nodeSwapName, maxLength) + suffix
+}
+
+// prunedRevisions returns the revisions beyond the limit, oldest first,
+// never including the kept ones.
+func prunedRevisions(revisions []appsv1.ControllerRevision, limit int, keep ...string) []*appsv1.ControllerRevision {
+	sorted := make([]*appsv1.ControllerRevision, 0, len(revisions))
+	for i := range revisions {
+		sorted = append(sorted, &revisions[i])
+	}
+	slices.SortFunc(sorted, func(a, b *appsv1.ControllerRevision) int {
+		return cmp.Compare(a.Revision, b.Revision)
+	})
+
+	var pruned []*appsv1.ControllerRevision
+	for _, revision := range sorted[:max(len(sorted)-limit, 0)] {
+		if !slices.Contains(keep, revision.Name) {
+			pruned = append(pruned, revision)
+		}
+	}
+
+	return pruned
+}
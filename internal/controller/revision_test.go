@@ -0,0 +1,92 @@
+package controller
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	mcfgv1 "github.com/openshift/api/machineconfiguration/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+func TestNewRevisionData(t *testing.T) {
+	mc := func(name, kernelArgument string) *mcfgv1.MachineConfig {
+		mc := &mcfgv1.MachineConfig{ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: map[string]string{"machineconfiguration.openshift.io/role": "worker"},
+		}}
+		mc.Spec.KernelArguments = []string{kernelArgument}
+		return mc
+	}
+
+	_, hash, err := newRevisionData([]*mcfgv1.MachineConfig{mc("99-filebased-swap-0", "a"), mc("99-swap-oomd", "b")})
+	if err != nil {
+		t.Fatalf("newRevisionData() error = %v", err)
+	}
+
+	// The order of the MachineConfigs and their labels do not matter.
+	reordered := []*mcfgv1.MachineConfig{mc("99-swap-oomd", "b"), mc("99-filebased-swap-0", "a")}
+	reordered[0].Labels = nil
+	if _, same, _ := newRevisionData(reordered); same != hash {
+		t.Errorf("newRevisionData() hash changed with the order or the labels")
+	}
+
+	if _, changed, _ := newRevisionData([]*mcfgv1.MachineConfig{mc("99-filebased-swap-0", "c")}); changed == hash {
+		t.Errorf("newRevisionData() hash did not change with the spec")
+	}
+
+	if name := revisionName("swap", hash); name != "swap-"+hash[:10] {
+		t.Errorf("revisionName() = %s", name)
+	}
+}
+
+func TestRevisionName(t *testing.T) {
+	hash := strings.Repeat("0123456789abcdef", 4)
+	long := strings.Repeat("a", validation.DNS1123SubdomainMaxLength)
+
+	name := revisionName(long, hash)
+	if len(name) > validation.DNS1123SubdomainMaxLength {
+		t.Errorf("revisionName() is %d characters long", len(name))
+	}
+	if !strings.HasSuffix(name, "-"+hash[:10]) {
+		t.Errorf("revisionName() = %s, want the hash suffix", name)
+	}
+	if other := revisionName(long[1:]+"b", hash); other == name {
+		t.Errorf("revisionName() = %s for two NodeSwaps", name)
+	}
+}
+
+func TestPrunedRevisions(t *testing.T) {
+	revision := func(name string, number int64) appsv1.ControllerRevision {
+		return appsv1.ControllerRevision{ObjectMeta: metav1.ObjectMeta{Name: name}, Revision: number}
+	}
+	revisions := []appsv1.ControllerRevision{
+		revision("swap-d", 4), revision("swap-a", 1), revision("swap-c", 3), revision("swap-b", 2),
+	}
+
+	tests := []struct {
+		name  string
+		limit int
+		keep  []string
+		want  []string
+	}{
+		{name: "within the limit", limit: 4},
+		{name: "oldest first", limit: 2, want: []string{"swap-a", "swap-b"}},
+		{name: "kept revisions", limit: 1, keep: []string{"swap-d", "swap-a"}, want: []string{"swap-b", "swap-c"}},
+		{name: "no history", limit: 0, keep: []string{"swap-d"}, want: []string{"swap-a", "swap-b", "swap-c"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, revision := range prunedRevisions(slices.Clone(revisions), tt.limit, tt.keep...) {
+				got = append(got, revision.Name)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("prunedRevisions() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
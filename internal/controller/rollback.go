@@ -0,0 +1,156 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"time"
+
+	mcfgv1 "github.com/openshift/api/machineconfiguration/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	nodeswap "github.com/openshift-virtualization/swap-operator/api/v1beta1"
+	"github.com/openshift-virtualization/swap-operator/internal/renderconfig"
+)
+
+// ReconcileRollback rolls the MachineConfigs back to status.lastAppliedSpec
+// when spec.autoRollback is set and the change drove a selected pool
+// Degraded. It renders s.config from the spec the MachineConfigs are rolled
+// out from.
+func (r *NodeSwapReconciler) ReconcileRollback(s *reconcileState) error {
+	nodeSwap := &s.desiredNodeSwap
+	if nodeSwap.Spec.AutoRollback && s.hold == "" && nodeSwap.Spec.RollbackToRevision == "" &&
+		!rolledBack(nodeSwap) &&
+		nodeSwap.Status.LastAppliedSpec != nil &&
+		!sameMachineConfigs(&nodeSwap.Spec, nodeSwap.Status.LastAppliedSpec) {
+		mcs, err := r.specMachineConfigs(s, &nodeSwap.Spec)
+		if err != nil {
+			return err
+		}
+		renderedPools, err := r.renderedContent(s, s.pools, mcs)
+		if err != nil {
+			return err
+		}
+
+		if rollback := rollbackCause(s.pools, renderedPools, nodeSwap.Status.Nodes.Failed); rollback != nil {
+			rollback.Generation = nodeSwap.Generation
+			rollback.Time = metav1.NewTime(time.Now())
+			logf.FromContext(s.ctx).Info("Rolling back the MachineConfigs", "pool", rollback.MachineConfigPool,
+				"node", rollback.Node, "reason", rollback.Reason, "message", rollback.Message)
+			nodeSwap.Status.Rollback = rollback
+
+			// Remove the MachineConfigs only the rolled back spec renders.
+			previous, err := renderedMachineConfigNames(nodeSwap.Name, nodeSwap.Status.LastAppliedSpec)
+			if err != nil {
+				logf.FromContext(s.ctx).Error(err, "Failed to create render config")
+				return err
+			}
+			names := make([]string, 0, len(mcs))
+			for _, mc := range mcs {
+				names = append(names, mc.Name)
+			}
+			for _, name := range sets.List(sets.New(names...).Difference(sets.New(previous...))) {
+				if err := r.deleteMachineConfig(s, name); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	config, err := renderconfig.Create(nodeSwap.Name, renderedSpec(nodeSwap))
+	if err != nil {
+		logf.FromContext(s.ctx).Error(err, "Failed to create render config")
+		return err
+	}
+	s.config = config
+
+	return nil
+}
+
+// rolledBack reports whether the current generation of the NodeSwap was
+// rolled back.
+func rolledBack(nodeSwap *nodeswap.NodeSwap) bool {
+	return nodeSwap.Status.Rollback != nil && nodeSwap.Status.Rollback.Generation == nodeSwap.Generation
+}
+
+// renderedSpec returns the spec the MachineConfigs are rendered from, the
+// last applied one while the current generation is rolled back.
+func renderedSpec(nodeSwap *nodeswap.NodeSwap) *nodeswap.NodeSwapSpec {
+	if rolledBack(nodeSwap) && nodeSwap.Status.LastAppliedSpec != nil {
+		return nodeSwap.Status.LastAppliedSpec
+	}
+	return &nodeSwap.Spec
+}
+
+// sameMachineConfigs reports whether both specs render the same
+// MachineConfigs.
+func sameMachineConfigs(a, b *nodeswap.NodeSwapSpec) bool {
+	return equality.Semantic.DeepEqual(a.Swaps, b.Swaps) && equality.Semantic.DeepEqual(a.Oomd, b.Oomd)
+}
+
+// renderedMachineConfigNames returns the names of the swap and
+// systemd-oomd MachineConfigs rendered for the spec of the named NodeSwap.
+func renderedMachineConfigNames(nodeSwapName string, spec *nodeswap.NodeSwapSpec) ([]string, error) {
+	configs, err := renderconfig.Create(nodeSwapName, spec)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(configs)+1)
+	for i := range configs {
+		names = append(names, configs[i].Name)
+	}
+	if spec.Oomd != nil {
+		names = append(names, renderconfig.OomdMachineConfigName(nodeSwapName))
+	}
+
+	return names, nil
+}
+
+// rollbackCause returns the first pool whose rendered configuration
+// includes the content of the spec and is Degraded, with the first failing
+// node when known, or nil when none is. A pool degraded by a configuration
+// rendered before the change does not roll it back.
+func rollbackCause(pools []*mcfgv1.MachineConfigPool, renderedPools sets.Set[string],
+	failed []nodeswap.NodeFailure) *nodeswap.RollbackStatus {
+	for _, mcp := range pools {
+		if !renderedPools.Has(mcp.Name) {
+			continue
+		}
+		degraded := findPoolCondition(mcp, mcfgv1.MachineConfigPoolDegraded)
+		if degraded == nil || degraded.Status != corev1.ConditionTrue {
+			continue
+		}
+
+		rollback := &nodeswap.RollbackStatus{
+			MachineConfigPool: mcp.Name,
+			Reason:            degraded.Reason,
+			Message:           degraded.Message,
+		}
+		if len(failed) > 0 {
+			rollback.Node = failed[0].Name
+			rollback.Reason = failed[0].Reason
+			rollback.Message = failed[0].Message
+		}
+		return rollback
+	}
+
+	return nil
+}
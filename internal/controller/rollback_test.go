@@ -0,0 +1,95 @@
+package controller
+
+import (
+	"reflect"
+	"testing"
+
+	mcfgv1 "github.com/openshift/api/machineconfiguration/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	nodeswap "github.com/openshift-virtualization/swap-operator/api/v1beta1"
+)
+
+func TestRollbackCause(t *testing.T) {
+	renderedPools := sets.New("infra", "worker")
+	pool := func(name string, degraded corev1.ConditionStatus) *mcfgv1.MachineConfigPool {
+		mcp := &mcfgv1.MachineConfigPool{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		mcp.Spec.Configuration.Name = "rendered-" + name
+		mcp.Spec.Configuration.Source = []corev1.ObjectReference{{Name: "99-filebased-swap-0"}}
+		mcp.Status.Conditions = []mcfgv1.MachineConfigPoolCondition{{
+			Type:    mcfgv1.MachineConfigPoolDegraded,
+			Status:  degraded,
+			Reason:  "1 nodes are reporting degraded status on sync",
+			Message: "failed to enable swap",
+		}}
+		return mcp
+	}
+	failed := []nodeswap.NodeFailure{{Name: "worker-1", Reason: "NodeDegraded", Message: "swapon failed"}}
+
+	tests := []struct {
+		name   string
+		pools  []*mcfgv1.MachineConfigPool
+		failed []nodeswap.NodeFailure
+		want   *nodeswap.RollbackStatus
+	}{
+		{
+			name:  "no pool degraded",
+			pools: []*mcfgv1.MachineConfigPool{pool("worker", corev1.ConditionFalse)},
+		},
+		{
+			name:  "degraded pool rendering the previous content",
+			pools: []*mcfgv1.MachineConfigPool{pool("storage", corev1.ConditionTrue)},
+		},
+		{
+			name:  "degraded pool",
+			pools: []*mcfgv1.MachineConfigPool{pool("infra", corev1.ConditionFalse), pool("worker", corev1.ConditionTrue)},
+			want: &nodeswap.RollbackStatus{
+				MachineConfigPool: "worker",
+				Reason:            "1 nodes are reporting degraded status on sync",
+				Message:           "failed to enable swap",
+			},
+		},
+		{
+			name:   "degraded pool with a failing node",
+			pools:  []*mcfgv1.MachineConfigPool{pool("worker", corev1.ConditionTrue)},
+			failed: failed,
+			want: &nodeswap.RollbackStatus{
+				MachineConfigPool: "worker",
+				Node:              "worker-1",
+				Reason:            "NodeDegraded",
+				Message:           "swapon failed",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rollbackCause(tt.pools, renderedPools, tt.failed); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("rollbackCause() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderedSpec(t *testing.T) {
+	last := &nodeswap.NodeSwapSpec{Oomd: &nodeswap.OomdSpec{}}
+	nodeSwap := &nodeswap.NodeSwap{ObjectMeta: metav1.ObjectMeta{Generation: 2}}
+	nodeSwap.Status.LastAppliedSpec = last
+
+	if got := renderedSpec(nodeSwap); got != &nodeSwap.Spec {
+		t.Fatalf("renderedSpec() = %+v, want the spec", got)
+	}
+
+	nodeSwap.Status.Rollback = &nodeswap.RollbackStatus{Generation: 2}
+	if got := renderedSpec(nodeSwap); got != last {
+		t.Fatalf("renderedSpec() = %+v, want the last applied spec", got)
+	}
+
+	// The rollback is not retried once the spec changes.
+	nodeSwap.Generation = 3
+	if got := renderedSpec(nodeSwap); got != &nodeSwap.Spec {
+		t.Fatalf("renderedSpec() = %+v, want the spec", got)
+	}
+}
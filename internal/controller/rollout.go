@@ -0,0 +1,280 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	mcfgv1 "github.com/openshift/api/machineconfiguration/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	nodeswap "github.com/openshift-virtualization/swap-operator/api/v1beta1"
+)
+
+// stagedRolloutRequeueInterval is how often the health checks of a stage are
+// re-checked. Nodes are not watched.
+const stagedRolloutRequeueInterval = time.Minute
+
+// rolloutPlan is the part of a staged rollout that can proceed.
+type rolloutPlan struct {
+	// pools are the pools the MachineConfigs are labeled for.
+	pools []*mcfgv1.MachineConfigPool
+	// held are the pools of the stages that have not started which pick up
+	// the MachineConfigs anyway, because an earlier change or the labels of
+	// a started stage reached them. They are paused until their stage
+	// starts.
+	held []*mcfgv1.MachineConfigPool
+	// waiting explains why the next stage has not started, it is empty once
+	// every stage has started.
+	waiting string
+	// requeueAfter is when the next stage may start without any event.
+	requeueAfter time.Duration
+}
+
+// ReconcileRollout returns the selected pools the MachineConfigs can be
+// rolled out to. Without spec.rollout, they all are. The pools it holds
+// back are recorded in s.heldPools, to be paused before the MachineConfigs
+// change.
+func (r *NodeSwapReconciler) ReconcileRollout(s *reconcileState, nodes []corev1.Node) ([]*mcfgv1.MachineConfigPool, ctrl.Result, error) {
+	s.heldPools = nil
+	rollout := s.desiredNodeSwap.Spec.Rollout
+	if rollout == nil {
+		return s.pools, ctrl.Result{}, nil
+	}
+
+	owned := &mcfgv1.MachineConfigList{}
+	if err := r.List(s.ctx, owned, client.MatchingLabels(ownerLabels(s))); err != nil {
+		logf.FromContext(s.ctx).Error(err, "Failed to list MachineConfigs")
+		return nil, ctrl.Result{}, err
+	}
+
+	if rollout.HealthChecks != nil && rollout.HealthChecks.NodesReady && nodes == nil {
+		nodeList := &corev1.NodeList{}
+		if err := r.List(s.ctx, nodeList); err != nil {
+			logf.FromContext(s.ctx).Error(err, "Failed to list Nodes")
+			return nil, ctrl.Result{}, err
+		}
+		nodes = nodeList.Items
+	}
+
+	// A stage is only done once it rolled out the content about to be
+	// written, so that later changes are staged as well.
+	desired, err := r.desiredMachineConfigs(s)
+	if err != nil {
+		return nil, ctrl.Result{}, err
+	}
+	kubelet, err := r.desiredKubeletMachineConfig(s)
+	if err != nil {
+		return nil, ctrl.Result{}, err
+	}
+	rendered, err := r.renderedContent(s, s.pools, append(desired, kubelet))
+	if err != nil {
+		return nil, ctrl.Result{}, err
+	}
+
+	plan, err := planRollout(rollout, s.pools, owned.Items, rendered, nodes, time.Now())
+	if err != nil {
+		logf.FromContext(s.ctx).Error(err, "Failed to plan the rollout")
+		return nil, ctrl.Result{}, err
+	}
+	if plan.waiting != "" {
+		logf.FromContext(s.ctx).Info("Staged rollout waiting", "reason", plan.waiting)
+	}
+	s.rolloutWaiting = plan.waiting
+	// Nothing is written while the MachineConfigs are held back, so there
+	// is nothing to hold the pools back from either.
+	if s.hold == "" {
+		for _, mcp := range plan.held {
+			s.heldPools = append(s.heldPools, mcp.Name)
+		}
+	}
+
+	return plan.pools, ctrl.Result{RequeueAfter: plan.requeueAfter}, nil
+}
+
+// planRollout walks the stages of the rollout in order and stops at the
+// first one that is not done rolling out the content rendered by the pools
+// of rendered. The selected pools left out of the stages form the last one.
+// Pools that already picked up the owned MachineConfigs keep them, so that a
+// failing stage never rolls back the previous ones, but they are held back
+// until their stage starts: a change to the MachineConfigs reaches them
+// after the previous stages only. So are the pools of later stages
+// inheriting the MachineConfigs of a started stage, such as custom pools of
+// the worker pool.
+func planRollout(rollout *nodeswap.RolloutSpec, pools []*mcfgv1.MachineConfigPool,
+	owned []mcfgv1.MachineConfig, rendered sets.Set[string], nodes []corev1.Node, now time.Time) (*rolloutPlan, error) {
+	byName := map[string]*mcfgv1.MachineConfigPool{}
+	for _, mcp := range pools {
+		byName[mcp.Name] = mcp
+	}
+
+	var stages [][]*mcfgv1.MachineConfigPool
+	staged := sets.New[string]()
+	for i, stage := range rollout.Stages {
+		var stagePools []*mcfgv1.MachineConfigPool
+		for _, name := range stage.Pools {
+			mcp, ok := byName[name]
+			if !ok {
+				return nil, fmt.Errorf("rollout stage %d lists MachineConfigPool %s, which is not selected", i+1, name)
+			}
+			if staged.Has(name) {
+				return nil, fmt.Errorf("rollout stage %d lists MachineConfigPool %s, which is in a previous stage", i+1, name)
+			}
+			staged.Insert(name)
+			stagePools = append(stagePools, mcp)
+		}
+		stages = append(stages, stagePools)
+	}
+	var rest []*mcfgv1.MachineConfigPool
+	for _, mcp := range pools {
+		if !staged.Has(mcp.Name) {
+			rest = append(rest, mcp)
+		}
+	}
+	if len(rest) > 0 {
+		stages = append(stages, rest)
+	}
+
+	names := make([]string, 0, len(owned))
+	for i := range owned {
+		names = append(names, owned[i].Name)
+	}
+
+	plan := &rolloutPlan{}
+	for i, stage := range stages {
+		plan.pools = append(plan.pools, stage...)
+		if i == len(stages)-1 {
+			break
+		}
+		if waiting, requeueAfter := stageDone(rollout, stage, names, rendered, nodes, now); waiting != "" {
+			plan.waiting = fmt.Sprintf("stage %d of %d: %s", i+1, len(stages), waiting)
+			plan.requeueAfter = requeueAfter
+			break
+		}
+	}
+
+	started := slices.Clone(plan.pools)
+	for _, mcp := range pools {
+		if slices.Contains(started, mcp) {
+			continue
+		}
+		if selectsAnyMachineConfig(mcp, owned) {
+			plan.pools = append(plan.pools, mcp)
+			plan.held = append(plan.held, mcp)
+		} else if inheritsMachineConfigs(mcp, started) {
+			plan.held = append(plan.held, mcp)
+		}
+	}
+
+	return plan, nil
+}
+
+// stageDone returns why the pools of a stage are not done rolling out the
+// named MachineConfigs, with the content the pools of rendered render, if
+// they are not, and when to check again.
+func stageDone(rollout *nodeswap.RolloutSpec, stage []*mcfgv1.MachineConfigPool, names []string,
+	rendered sets.Set[string], nodes []corev1.Node, now time.Time) (string, time.Duration) {
+	if len(names) == 0 {
+		return "waiting for the MachineConfigs to be created", 0
+	}
+
+	var updatedSince time.Time
+	for _, mcp := range stage {
+		if !rendered.Has(mcp.Name) || mcp.Status.Configuration.Name != mcp.Spec.Configuration.Name ||
+			!includesMachineConfigs(mcp.Status.Configuration, names) {
+			return fmt.Sprintf("waiting for MachineConfigPool %s to roll out the MachineConfigs", mcp.Name), 0
+		}
+		if degraded := findPoolCondition(mcp, mcfgv1.MachineConfigPoolDegraded); degraded != nil &&
+			degraded.Status == corev1.ConditionTrue {
+			return fmt.Sprintf("MachineConfigPool %s is degraded", mcp.Name), 0
+		}
+		updated := findPoolCondition(mcp, mcfgv1.MachineConfigPoolUpdated)
+		if updated == nil || updated.Status != corev1.ConditionTrue {
+			return fmt.Sprintf("waiting for MachineConfigPool %s to be updated", mcp.Name), 0
+		}
+		if updated.LastTransitionTime.After(updatedSince) {
+			updatedSince = updated.LastTransitionTime.Time
+		}
+
+		if rollout.HealthChecks != nil && rollout.HealthChecks.NodesReady {
+			if node := notReadyPoolNode(mcp, nodes); node != "" {
+				return fmt.Sprintf("node %s of MachineConfigPool %s is not ready", node, mcp.Name),
+					stagedRolloutRequeueInterval
+			}
+		}
+	}
+
+	if rollout.SoakTime != nil {
+		if remaining := updatedSince.Add(rollout.SoakTime.Duration).Sub(now); remaining > 0 {
+			return fmt.Sprintf("soaking until %s", now.Add(remaining).UTC().Format(time.RFC3339)), remaining
+		}
+	}
+
+	return "", 0
+}
+
+// selectsAnyMachineConfig reports whether the pool picks up one of the
+// MachineConfigs.
+func selectsAnyMachineConfig(mcp *mcfgv1.MachineConfigPool, mcs []mcfgv1.MachineConfig) bool {
+	return slices.ContainsFunc(mcs, func(mc mcfgv1.MachineConfig) bool {
+		return selectsMachineConfigLabels(mcp, mc.Labels)
+	})
+}
+
+// inheritsMachineConfigs reports whether the pool picks up the MachineConfigs
+// labeled for one of the pools.
+func inheritsMachineConfigs(mcp *mcfgv1.MachineConfigPool, pools []*mcfgv1.MachineConfigPool) bool {
+	return slices.ContainsFunc(pools, func(other *mcfgv1.MachineConfigPool) bool {
+		poolLabels := poolMachineConfigLabels(other)
+		return len(poolLabels) > 0 && selectsMachineConfigLabels(mcp, poolLabels)
+	})
+}
+
+// notReadyPoolNode returns the name of a node of the pool that is not
+// Ready, if any.
+func notReadyPoolNode(mcp *mcfgv1.MachineConfigPool, nodes []corev1.Node) string {
+	if mcp.Spec.NodeSelector == nil {
+		return ""
+	}
+	selector, err := metav1.LabelSelectorAsSelector(mcp.Spec.NodeSelector)
+	if err != nil {
+		return ""
+	}
+
+	for i := range nodes {
+		node := &nodes[i]
+		if !selector.Matches(labels.Set(node.Labels)) {
+			continue
+		}
+		ready := slices.ContainsFunc(node.Status.Conditions, func(condition corev1.NodeCondition) bool {
+			return condition.Type == corev1.NodeReady && condition.Status == corev1.ConditionTrue
+		})
+		if !ready {
+			return node.Name
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,236 @@
+package controller
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	mcfgv1 "github.com/openshift/api/machineconfiguration/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	nodeswap "github.com/openshift-virtualization/swap-operator/api/v1beta1"
+)
+
+func TestPlanRollout(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	const role = "machineconfiguration.openshift.io/role"
+
+	// pool returns a pool selecting MachineConfigs by its role, with the
+	// swap MachineConfig rolled out when updatedAt is set. Unless listed in
+	// changed, the pools that rolled it out render its current content.
+	pool := func(name string, updatedAt time.Time) *mcfgv1.MachineConfigPool {
+		mcp := newTestPool(name, nil, map[string]string{role: name})
+		mcp.Spec.NodeSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"pool": name}}
+		if !updatedAt.IsZero() {
+			mcp.Status.Configuration.Name = "rendered-" + name
+			mcp.Status.Configuration.Source = []corev1.ObjectReference{{Name: "99-filebased-swap-0"}}
+			mcp.Spec.Configuration = mcp.Status.Configuration
+			mcp.Status.Conditions = []mcfgv1.MachineConfigPoolCondition{{
+				Type:               mcfgv1.MachineConfigPoolUpdated,
+				Status:             corev1.ConditionTrue,
+				LastTransitionTime: metav1.NewTime(updatedAt),
+			}}
+		}
+		return mcp
+	}
+	owned := func(roleValue string) []mcfgv1.MachineConfig {
+		return []mcfgv1.MachineConfig{{ObjectMeta: metav1.ObjectMeta{
+			Name:   "99-filebased-swap-0",
+			Labels: map[string]string{role: roleValue},
+		}}}
+	}
+	node := func(name, poolName string, ready corev1.ConditionStatus) corev1.Node {
+		return corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"pool": poolName}},
+			Status: corev1.NodeStatus{Conditions: []corev1.NodeCondition{
+				{Type: corev1.NodeReady, Status: ready},
+			}},
+		}
+	}
+	canary := &nodeswap.RolloutSpec{Stages: []nodeswap.RolloutStage{{Pools: []string{"canary"}}}}
+	soaked := &nodeswap.RolloutSpec{
+		Stages:   canary.Stages,
+		SoakTime: &metav1.Duration{Duration: time.Hour},
+	}
+	healthChecked := &nodeswap.RolloutSpec{
+		Stages:       canary.Stages,
+		HealthChecks: &nodeswap.RolloutHealthChecks{NodesReady: true},
+	}
+
+	tests := []struct {
+		name             string
+		rollout          *nodeswap.RolloutSpec
+		pools            []*mcfgv1.MachineConfigPool
+		owned            []mcfgv1.MachineConfig
+		nodes            []corev1.Node
+		changed          []string
+		wantPools        []string
+		wantHeld         []string
+		wantWaiting      bool
+		wantRequeueAfter time.Duration
+		wantErr          bool
+	}{
+		{
+			name:        "canary first, before any MachineConfig exists",
+			rollout:     canary,
+			pools:       []*mcfgv1.MachineConfigPool{pool("worker", time.Time{}), pool("canary", time.Time{})},
+			wantPools:   []string{"canary"},
+			wantWaiting: true,
+		},
+		{
+			name:        "canary rolling out",
+			rollout:     canary,
+			pools:       []*mcfgv1.MachineConfigPool{pool("worker", time.Time{}), pool("canary", time.Time{})},
+			owned:       owned("canary"),
+			wantPools:   []string{"canary"},
+			wantWaiting: true,
+		},
+		{
+			name:      "canary updated",
+			rollout:   canary,
+			pools:     []*mcfgv1.MachineConfigPool{pool("worker", time.Time{}), pool("canary", now.Add(-time.Minute))},
+			owned:     owned("canary"),
+			wantPools: []string{"canary", "worker"},
+		},
+		{
+			name:             "canary soaking",
+			rollout:          soaked,
+			pools:            []*mcfgv1.MachineConfigPool{pool("worker", time.Time{}), pool("canary", now.Add(-time.Minute))},
+			owned:            owned("canary"),
+			wantPools:        []string{"canary"},
+			wantWaiting:      true,
+			wantRequeueAfter: 59 * time.Minute,
+		},
+		{
+			name:      "canary soaked",
+			rollout:   soaked,
+			pools:     []*mcfgv1.MachineConfigPool{pool("worker", time.Time{}), pool("canary", now.Add(-2*time.Hour))},
+			owned:     owned("canary"),
+			wantPools: []string{"canary", "worker"},
+		},
+		{
+			name:             "canary node not ready",
+			rollout:          healthChecked,
+			pools:            []*mcfgv1.MachineConfigPool{pool("worker", time.Time{}), pool("canary", now)},
+			owned:            owned("canary"),
+			nodes:            []corev1.Node{node("canary-0", "canary", corev1.ConditionFalse)},
+			wantPools:        []string{"canary"},
+			wantWaiting:      true,
+			wantRequeueAfter: stagedRolloutRequeueInterval,
+		},
+		{
+			name:      "canary nodes ready",
+			rollout:   healthChecked,
+			pools:     []*mcfgv1.MachineConfigPool{pool("worker", time.Time{}), pool("canary", now)},
+			owned:     owned("canary"),
+			nodes:     []corev1.Node{node("canary-0", "canary", corev1.ConditionTrue), node("worker-0", "worker", corev1.ConditionFalse)},
+			wantPools: []string{"canary", "worker"},
+		},
+		{
+			name:        "started pools are kept but held back when a stage regresses",
+			rollout:     canary,
+			pools:       []*mcfgv1.MachineConfigPool{pool("worker", time.Time{}), pool("canary", time.Time{})},
+			owned:       owned("worker"),
+			wantPools:   []string{"canary", "worker"},
+			wantHeld:    []string{"worker"},
+			wantWaiting: true,
+		},
+		{
+			name:    "change held back until the canary rolled it out",
+			rollout: canary,
+			pools: []*mcfgv1.MachineConfigPool{
+				pool("worker", now.Add(-time.Hour)), pool("canary", now.Add(-time.Hour)),
+			},
+			owned:       owned("worker"),
+			changed:     []string{"worker", "canary"},
+			wantPools:   []string{"canary", "worker"},
+			wantHeld:    []string{"worker"},
+			wantWaiting: true,
+		},
+		{
+			name:    "change rolled out by the canary",
+			rollout: canary,
+			pools: []*mcfgv1.MachineConfigPool{
+				pool("worker", now.Add(-time.Hour)), pool("canary", now.Add(-time.Minute)),
+			},
+			owned:     owned("worker"),
+			changed:   []string{"worker"},
+			wantPools: []string{"canary", "worker"},
+		},
+		{
+			name: "later stage inheriting the MachineConfigs of the first one",
+			rollout: &nodeswap.RolloutSpec{Stages: []nodeswap.RolloutStage{
+				{Pools: []string{"worker"}},
+			}},
+			pools: func() []*mcfgv1.MachineConfigPool {
+				custom := pool("custom", time.Time{})
+				custom.Spec.MachineConfigSelector = &metav1.LabelSelector{
+					MatchExpressions: []metav1.LabelSelectorRequirement{{
+						Key:      role,
+						Operator: metav1.LabelSelectorOpIn,
+						Values:   []string{"worker", "custom"},
+					}},
+				}
+				return []*mcfgv1.MachineConfigPool{pool("worker", time.Time{}), custom}
+			}(),
+			wantPools:   []string{"worker"},
+			wantHeld:    []string{"custom"},
+			wantWaiting: true,
+		},
+		{
+			name:    "stage pool not selected",
+			rollout: canary,
+			pools:   []*mcfgv1.MachineConfigPool{pool("worker", time.Time{})},
+			wantErr: true,
+		},
+		{
+			name: "pool in several stages",
+			rollout: &nodeswap.RolloutSpec{Stages: []nodeswap.RolloutStage{
+				{Pools: []string{"canary"}}, {Pools: []string{"canary"}},
+			}},
+			pools:   []*mcfgv1.MachineConfigPool{pool("canary", time.Time{})},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rendered := sets.New[string]()
+			for _, mcp := range tt.pools {
+				if mcp.Spec.Configuration.Name != "" && !slices.Contains(tt.changed, mcp.Name) {
+					rendered.Insert(mcp.Name)
+				}
+			}
+			plan, err := planRollout(tt.rollout, tt.pools, tt.owned, rendered, tt.nodes, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("planRollout() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			var got []string
+			for _, mcp := range plan.pools {
+				got = append(got, mcp.Name)
+			}
+			if !slices.Equal(got, tt.wantPools) {
+				t.Errorf("planRollout() pools = %v, want %v", got, tt.wantPools)
+			}
+			var held []string
+			for _, mcp := range plan.held {
+				held = append(held, mcp.Name)
+			}
+			if !slices.Equal(held, tt.wantHeld) {
+				t.Errorf("planRollout() held = %v, want %v", held, tt.wantHeld)
+			}
+			if (plan.waiting != "") != tt.wantWaiting {
+				t.Errorf("planRollout() waiting = %q, want waiting %v", plan.waiting, tt.wantWaiting)
+			}
+			if plan.requeueAfter != tt.wantRequeueAfter {
+				t.Errorf("planRollout() requeueAfter = %v, want %v", plan.requeueAfter, tt.wantRequeueAfter)
+			}
+		})
+	}
+}
@@ -0,0 +1,384 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	mcfgv1 "github.com/openshift/api/machineconfiguration/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	nodeswap "github.com/openshift-virtualization/swap-operator/api/v1beta1"
+)
+
+// workerPoolName is the name of the built-in worker MachineConfigPool.
+const workerPoolName = "worker"
+
+// poolSelector selects the MachineConfigPools a NodeSwap targets.
+type poolSelector struct {
+	selector labels.Selector
+	// legacy is set for selectors converted from the deprecated
+	// "key:value" machineConfigPoolSelector. They are matched against the
+	// pool's machineConfigSelector match labels rather than its own labels,
+	// and the key/value pair is set on the MachineConfigs as is.
+	legacy       bool
+	legacyLabels map[string]string
+}
+
+// newPoolSelector builds the pool selector of spec. PoolSelector takes
+// precedence; otherwise the legacy MachineConfigPoolSelector string is
+// converted into an equivalent label selector. It returns nil when pools are
+// only selected by name or node selector.
+func newPoolSelector(spec *nodeswap.NodeSwapSpec) (*poolSelector, error) {
+	if spec.PoolSelector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(spec.PoolSelector)
+		if err != nil {
+			return nil, &specError{err: fmt.Errorf("invalid pool selector: %w", err)}
+		}
+		return &poolSelector{selector: selector}, nil
+	}
+
+	// Pools may be selected by name or node selector only.
+	if spec.MachineConfigPoolSelector == "" && (len(spec.MachineConfigPoolNames) > 0 || spec.NodeSelector != nil) {
+		return nil, nil
+	}
+
+	labelSelector, err := convertLegacyPoolSelector(spec.MachineConfigPoolSelector)
+	if err != nil {
+		return nil, &specError{err: err}
+	}
+	selector, err := metav1.LabelSelectorAsSelector(labelSelector)
+	if err != nil {
+		return nil, &specError{err: fmt.Errorf("invalid label selector %q: %w", spec.MachineConfigPoolSelector, err)}
+	}
+
+	return &poolSelector{
+		selector:     selector,
+		legacy:       true,
+		legacyLabels: labelSelector.MatchLabels,
+	}, nil
+}
+
+// convertLegacyPoolSelector converts a "key:" or "key:value" selector into
+// a label selector matching exactly that key and value.
+func convertLegacyPoolSelector(selector string) (*metav1.LabelSelector, error) {
+	key, value, err := parseLabelSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	return &metav1.LabelSelector{MatchLabels: map[string]string{key: value}}, nil
+}
+
+// Matches returns true if the pool is selected.
+func (s *poolSelector) Matches(mcp *mcfgv1.MachineConfigPool) bool {
+	if !s.legacy {
+		return s.selector.Matches(labels.Set(mcp.Labels))
+	}
+
+	if mcp.Spec.MachineConfigSelector == nil || mcp.Spec.MachineConfigSelector.MatchLabels == nil {
+		return false
+	}
+	return s.selector.Matches(labels.Set(mcp.Spec.MachineConfigSelector.MatchLabels))
+}
+
+// machineConfigLabels returns the labels that make the selected pools pick
+// up the operator's MachineConfigs: the legacy selector's key and value, if
+// any, and the union of the pools' machineConfigSelector match labels, which
+// must agree with each other. Pools inheriting the MachineConfigs of another
+// selected pool, such as custom pools of the worker pool, agree with it.
+// A NodeSwap renders a single set of MachineConfigs, so pools selecting
+// different roles, such as role=a and role=b, are an invalid spec: they need
+// one NodeSwap each.
+func machineConfigLabels(selector *poolSelector, pools []*mcfgv1.MachineConfigPool) (map[string]string, error) {
+	result := map[string]string{}
+	owners := map[string]string{}
+	if selector != nil && selector.legacy {
+		for key, value := range selector.legacyLabels {
+			result[key] = value
+			owners[key] = "machineConfigPoolSelector"
+		}
+	}
+
+	var labeled []*mcfgv1.MachineConfigPool
+	for _, mcp := range pools {
+		poolLabels := poolMachineConfigLabels(mcp)
+		if len(poolLabels) == 0 {
+			return nil, fmt.Errorf("MachineConfigPool %s does not select MachineConfigs by matchLabels or by its own role", mcp.Name)
+		}
+		if selectsMachineConfigLabels(mcp, result) {
+			continue
+		}
+		for key, value := range poolLabels {
+			existing, ok := result[key]
+			if !ok || existing == value {
+				continue
+			}
+			candidate := maps.Clone(result)
+			maps.Copy(candidate, poolLabels)
+			if owners[key] == "machineConfigPoolSelector" || slices.ContainsFunc(labeled, func(other *mcfgv1.MachineConfigPool) bool {
+				return !selectsMachineConfigLabels(other, candidate)
+			}) {
+				return nil, &specError{err: fmt.Errorf("%s and MachineConfigPool %s select MachineConfigs with conflicting labels %s=%s and %s=%s, "+
+					"select them with one NodeSwap each", owners[key], mcp.Name, key, existing, key, value)}
+			}
+		}
+		for key, value := range poolLabels {
+			result[key] = value
+			owners[key] = "MachineConfigPool " + mcp.Name
+		}
+		labeled = append(labeled, mcp)
+	}
+
+	if len(result) == 0 {
+		return nil, fmt.Errorf("no MachineConfigPool is selected")
+	}
+
+	return result, nil
+}
+
+// selectsMachineConfigLabels reports whether the pool picks up the
+// MachineConfigs with the given labels.
+func selectsMachineConfigLabels(mcp *mcfgv1.MachineConfigPool, mcLabels map[string]string) bool {
+	if mcp.Spec.MachineConfigSelector == nil {
+		return false
+	}
+	selector, err := metav1.LabelSelectorAsSelector(mcp.Spec.MachineConfigSelector)
+	if err != nil {
+		return false
+	}
+
+	return selector.Matches(labels.Set(mcLabels))
+}
+
+// poolMachineConfigLabels returns the labels a MachineConfig needs to be
+// picked up by the pool: its machineConfigSelector match labels or, for
+// custom pools selecting "role In (worker, <pool>)", the pool's own role.
+func poolMachineConfigLabels(mcp *mcfgv1.MachineConfigPool) map[string]string {
+	selector := mcp.Spec.MachineConfigSelector
+	if selector == nil {
+		return nil
+	}
+	if len(selector.MatchLabels) > 0 {
+		return selector.MatchLabels
+	}
+	for _, requirement := range selector.MatchExpressions {
+		if requirement.Key == machineConfigRoleLabel && requirement.Operator == metav1.LabelSelectorOpIn &&
+			slices.Contains(requirement.Values, mcp.Name) {
+			return map[string]string{machineConfigRoleLabel: mcp.Name}
+		}
+	}
+
+	return nil
+}
+
+// poolSelection is the set of MachineConfigPools a NodeSwap targets.
+type poolSelection struct {
+	Pools []*mcfgv1.MachineConfigPool
+	// PartiallyCovered describes the pools of which spec.nodeSelector
+	// selects some, but not all, nodes.
+	PartiallyCovered []string
+}
+
+// specError reports a field of the spec that cannot be reconciled until it is
+// edited, such as an invalid selector.
+type specError struct {
+	err error
+}
+
+func (e *specError) Error() string {
+	return e.err.Error()
+}
+
+func (e *specError) Unwrap() error {
+	return e.err
+}
+
+// poolNotFoundError reports a pool listed by name that does not exist.
+type poolNotFoundError struct {
+	name string
+}
+
+func (e *poolNotFoundError) Error() string {
+	return fmt.Sprintf("MachineConfigPool %s not found", e.name)
+}
+
+// noMatchingPoolsError reports a NodeSwap selecting no MachineConfigPool.
+type noMatchingPoolsError struct {
+	// deleted are the pools the NodeSwap was rolled out to that were
+	// deleted.
+	deleted []string
+	// removed is set once the MachineConfigs of the deleted pools were
+	// removed.
+	removed bool
+}
+
+func (e *noMatchingPoolsError) Error() string {
+	message := "no MachineConfigPool matches the NodeSwap"
+	if len(e.deleted) == 0 {
+		return message
+	}
+	fate := "retained"
+	if e.removed {
+		fate = "removed"
+	}
+	return fmt.Sprintf("%s, MachineConfigPools %s were deleted and their MachineConfigs %s", message,
+		strings.Join(e.deleted, ", "), fate)
+}
+
+// deletedPools returns the pools of the status that do not exist anymore.
+func deletedPools(status []nodeswap.PoolStatus, pools []mcfgv1.MachineConfigPool) []string {
+	var deleted []string
+	for _, pool := range status {
+		if !slices.ContainsFunc(pools, func(mcp mcfgv1.MachineConfigPool) bool { return mcp.Name == pool.Name }) {
+			deleted = append(deleted, pool.Name)
+		}
+	}
+	return deleted
+}
+
+// poolCoverageError reports pools only partially covered by the node selector.
+type poolCoverageError struct {
+	pools []string
+}
+
+func (e *poolCoverageError) Error() string {
+	return fmt.Sprintf("nodeSelector partially covers MachineConfigPools: %s", strings.Join(e.pools, "; "))
+}
+
+// selectPools resolves the MachineConfigPools targeted by spec: the pools
+// matched by selector, the pools listed by name, the dedicated pool and the
+// pools whose nodes are all selected by the node selector. nodes is only used when the spec
+// has a node selector.
+func selectPools(spec *nodeswap.NodeSwapSpec, selector *poolSelector,
+	pools []mcfgv1.MachineConfigPool, nodes []corev1.Node) (*poolSelection, error) {
+	selected := map[string]bool{}
+
+	if selector != nil {
+		for i := range pools {
+			if selector.Matches(&pools[i]) {
+				selected[pools[i].Name] = true
+			}
+		}
+	}
+
+	for _, name := range spec.MachineConfigPoolNames {
+		if !slices.ContainsFunc(pools, func(mcp mcfgv1.MachineConfigPool) bool { return mcp.Name == name }) {
+			return nil, &poolNotFoundError{name: name}
+		}
+		selected[name] = true
+	}
+
+	// The dedicated pool is created before the pools are selected, or
+	// passed in as it would be created while the NodeSwap is held, and
+	// holds the selected nodes, even while none are selected yet.
+	if spec.DedicatedPool != nil {
+		selected[spec.DedicatedPool.Name] = true
+	}
+
+	selection := &poolSelection{}
+	if spec.NodeSelector != nil {
+		nodeSelector, err := metav1.LabelSelectorAsSelector(spec.NodeSelector)
+		if err != nil {
+			return nil, &specError{err: fmt.Errorf("invalid node selector: %w", err)}
+		}
+
+		members, err := poolMembers(pools, nodes)
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range pools {
+			poolNodes := members[pools[i].Name]
+			matched := 0
+			for _, node := range poolNodes {
+				if nodeSelector.Matches(labels.Set(node.Labels)) {
+					matched++
+				}
+			}
+			switch {
+			case matched == 0:
+			case matched == len(poolNodes):
+				selected[pools[i].Name] = true
+			default:
+				selection.PartiallyCovered = append(selection.PartiallyCovered,
+					fmt.Sprintf("%s (%d of %d nodes selected)", pools[i].Name, matched, len(poolNodes)))
+			}
+		}
+	}
+
+	for i := range pools {
+		if selected[pools[i].Name] {
+			selection.Pools = append(selection.Pools, &pools[i])
+		}
+	}
+
+	return selection, nil
+}
+
+// poolMembers returns the nodes of each pool. Like the machine-config
+// operator, a node matched by the worker pool and a custom pool belongs to
+// the custom pool only.
+func poolMembers(pools []mcfgv1.MachineConfigPool, nodes []corev1.Node) (map[string][]*corev1.Node, error) {
+	selectors := make([]labels.Selector, len(pools))
+	for i := range pools {
+		if pools[i].Spec.NodeSelector == nil {
+			selectors[i] = labels.Nothing()
+			continue
+		}
+		selector, err := metav1.LabelSelectorAsSelector(pools[i].Spec.NodeSelector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid node selector of MachineConfigPool %s: %w", pools[i].Name, err)
+		}
+		selectors[i] = selector
+	}
+
+	members := map[string][]*corev1.Node{}
+	for n := range nodes {
+		var matching []string
+		for i := range pools {
+			if selectors[i].Matches(labels.Set(nodes[n].Labels)) {
+				matching = append(matching, pools[i].Name)
+			}
+		}
+		if len(matching) > 1 {
+			matching = slices.DeleteFunc(matching, func(name string) bool { return name == workerPoolName })
+		}
+		for _, name := range matching {
+			members[name] = append(members[name], &nodes[n])
+		}
+	}
+
+	return members, nil
+}
+
+// String returns a description of the selector for logs and messages.
+func (s *poolSelector) String() string {
+	if s.legacy {
+		pairs := make([]string, 0, len(s.legacyLabels))
+		for key, value := range s.legacyLabels {
+			pairs = append(pairs, key+":"+value)
+		}
+		return strings.Join(pairs, ",")
+	}
+	return s.selector.String()
+}
@@ -0,0 +1,384 @@
+package controller
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	mcfgv1 "github.com/openshift/api/machineconfiguration/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	nodeswap "github.com/openshift-virtualization/swap-operator/api/v1beta1"
+)
+
+func newTestPool(name string, poolLabels, mcLabels map[string]string) *mcfgv1.MachineConfigPool {
+	mcp := &mcfgv1.MachineConfigPool{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: poolLabels},
+	}
+	if mcLabels != nil {
+		mcp.Spec.MachineConfigSelector = &metav1.LabelSelector{MatchLabels: mcLabels}
+	}
+	return mcp
+}
+
+func TestPoolSelectorMatches(t *testing.T) {
+	worker := newTestPool("worker",
+		map[string]string{"pools.operator.machineconfiguration.openshift.io/worker": ""},
+		map[string]string{"machineconfiguration.openshift.io/role": "worker"})
+	infra := newTestPool("infra",
+		map[string]string{"pools.operator.machineconfiguration.openshift.io/infra": "", "swap": "enabled"},
+		map[string]string{"machineconfiguration.openshift.io/role": "infra"})
+	master := newTestPool("master",
+		map[string]string{"pools.operator.machineconfiguration.openshift.io/master": ""},
+		map[string]string{"machineconfiguration.openshift.io/role": "master"})
+	pools := []*mcfgv1.MachineConfigPool{worker, infra, master}
+
+	tests := []struct {
+		name    string
+		spec    nodeswap.NodeSwapSpec
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "legacy string matches machineConfigSelector labels",
+			spec: nodeswap.NodeSwapSpec{MachineConfigPoolSelector: "machineconfiguration.openshift.io/role:worker"},
+			want: []string{"worker"},
+		},
+		{
+			name: "legacy string does not match pool labels",
+			spec: nodeswap.NodeSwapSpec{MachineConfigPoolSelector: "swap:enabled"},
+		},
+		{
+			name:    "invalid legacy string",
+			spec:    nodeswap.NodeSwapSpec{MachineConfigPoolSelector: "no-colon"},
+			wantErr: true,
+		},
+		{
+			name: "matchLabels against pool labels",
+			spec: nodeswap.NodeSwapSpec{PoolSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"swap": "enabled"},
+			}},
+			want: []string{"infra"},
+		},
+		{
+			name: "matchExpressions against pool labels",
+			spec: nodeswap.NodeSwapSpec{PoolSelector: &metav1.LabelSelector{
+				MatchExpressions: []metav1.LabelSelectorRequirement{{
+					Key:      "pools.operator.machineconfiguration.openshift.io/master",
+					Operator: metav1.LabelSelectorOpDoesNotExist,
+				}},
+			}},
+			want: []string{"worker", "infra"},
+		},
+		{
+			name: "poolSelector takes precedence over the legacy string",
+			spec: nodeswap.NodeSwapSpec{
+				MachineConfigPoolSelector: "machineconfiguration.openshift.io/role:worker",
+				PoolSelector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{"pools.operator.machineconfiguration.openshift.io/master": ""},
+				},
+			},
+			want: []string{"master"},
+		},
+		{
+			name: "invalid operator",
+			spec: nodeswap.NodeSwapSpec{PoolSelector: &metav1.LabelSelector{
+				MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "swap", Operator: "Near"}},
+			}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selector, err := newPoolSelector(&tt.spec)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var got []string
+			for _, mcp := range pools {
+				if selector.Matches(mcp) {
+					got = append(got, mcp.Name)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("matched pools = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMachineConfigLabels(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    nodeswap.NodeSwapSpec
+		pools   []*mcfgv1.MachineConfigPool
+		want    map[string]string
+		wantErr bool
+		// wantSpecErr is set for errors reported as an invalid spec.
+		wantSpecErr bool
+	}{
+		{
+			name: "legacy selector labels MachineConfigs with its key and value",
+			spec: nodeswap.NodeSwapSpec{MachineConfigPoolSelector: "node-role.kubernetes.io/role:worker"},
+			want: map[string]string{"node-role.kubernetes.io/role": "worker"},
+		},
+		{
+			name: "union of the matched pools' machineConfigSelector labels",
+			spec: nodeswap.NodeSwapSpec{PoolSelector: &metav1.LabelSelector{}},
+			pools: []*mcfgv1.MachineConfigPool{
+				newTestPool("a", nil, map[string]string{"role": "worker"}),
+				newTestPool("b", nil, map[string]string{"role": "worker", "swap": "on"}),
+			},
+			want: map[string]string{"role": "worker", "swap": "on"},
+		},
+		{
+			name: "conflicting labels",
+			spec: nodeswap.NodeSwapSpec{PoolSelector: &metav1.LabelSelector{}},
+			pools: []*mcfgv1.MachineConfigPool{
+				newTestPool("worker", nil, map[string]string{"role": "worker"}),
+				newTestPool("infra", nil, map[string]string{"role": "infra"}),
+			},
+			wantErr:     true,
+			wantSpecErr: true,
+		},
+		{
+			name: "pool without machineConfigSelector matchLabels",
+			spec: nodeswap.NodeSwapSpec{PoolSelector: &metav1.LabelSelector{}},
+			pools: []*mcfgv1.MachineConfigPool{
+				newTestPool("worker", nil, nil),
+			},
+			wantErr: true,
+		},
+		{
+			name: "legacy selector combined with named pools",
+			spec: nodeswap.NodeSwapSpec{
+				MachineConfigPoolSelector: "role:worker",
+				MachineConfigPoolNames:    []string{"infra"},
+			},
+			pools: []*mcfgv1.MachineConfigPool{
+				newTestPool("infra", nil, map[string]string{"swap": "on"}),
+			},
+			want: map[string]string{"role": "worker", "swap": "on"},
+		},
+		{
+			name: "custom pool inheriting worker MachineConfigs",
+			spec: nodeswap.NodeSwapSpec{MachineConfigPoolNames: []string{"worker-virt"}},
+			pools: []*mcfgv1.MachineConfigPool{{
+				ObjectMeta: metav1.ObjectMeta{Name: "worker-virt"},
+				Spec: mcfgv1.MachineConfigPoolSpec{MachineConfigSelector: &metav1.LabelSelector{
+					MatchExpressions: []metav1.LabelSelectorRequirement{{
+						Key:      "machineconfiguration.openshift.io/role",
+						Operator: metav1.LabelSelectorOpIn,
+						Values:   []string{"worker", "worker-virt"},
+					}},
+				}},
+			}},
+			want: map[string]string{"machineconfiguration.openshift.io/role": "worker-virt"},
+		},
+		{
+			name: "custom pool selected with the worker pool it inherits from",
+			spec: nodeswap.NodeSwapSpec{MachineConfigPoolNames: []string{"worker-virt", "worker"}},
+			pools: []*mcfgv1.MachineConfigPool{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "worker-virt"},
+					Spec: mcfgv1.MachineConfigPoolSpec{MachineConfigSelector: &metav1.LabelSelector{
+						MatchExpressions: []metav1.LabelSelectorRequirement{{
+							Key:      "machineconfiguration.openshift.io/role",
+							Operator: metav1.LabelSelectorOpIn,
+							Values:   []string{"worker", "worker-virt"},
+						}},
+					}},
+				},
+				newTestPool("worker", nil, map[string]string{"machineconfiguration.openshift.io/role": "worker"}),
+			},
+			want: map[string]string{"machineconfiguration.openshift.io/role": "worker"},
+		},
+		{
+			name:    "no matched pools",
+			spec:    nodeswap.NodeSwapSpec{PoolSelector: &metav1.LabelSelector{}},
+			wantErr: true,
+		},
+		{
+			name:    "no pools selected by name",
+			spec:    nodeswap.NodeSwapSpec{MachineConfigPoolNames: []string{"infra"}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selector, err := newPoolSelector(&tt.spec)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			got, err := machineConfigLabels(selector, tt.pools)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got nil (labels=%v)", got)
+				}
+				var specErr *specError
+				if tt.wantSpecErr != errors.As(err, &specErr) {
+					t.Fatalf("machineConfigLabels() error = %v, want spec error %v", err, tt.wantSpecErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("machineConfigLabels() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectPools(t *testing.T) {
+	withNodeSelector := func(mcp *mcfgv1.MachineConfigPool, nodeLabels map[string]string) mcfgv1.MachineConfigPool {
+		mcp.Spec.NodeSelector = &metav1.LabelSelector{MatchLabels: nodeLabels}
+		return *mcp
+	}
+	pools := []mcfgv1.MachineConfigPool{
+		withNodeSelector(newTestPool("master", nil, map[string]string{"role": "master"}),
+			map[string]string{"node-role.kubernetes.io/master": ""}),
+		withNodeSelector(newTestPool("worker", nil, map[string]string{"role": "worker"}),
+			map[string]string{"node-role.kubernetes.io/worker": ""}),
+		withNodeSelector(newTestPool("worker-virt", map[string]string{"swap": "enabled"}, map[string]string{"role": "worker-virt"}),
+			map[string]string{"node-role.kubernetes.io/worker-virt": ""}),
+		withNodeSelector(newTestPool("swap", nil, nil),
+			map[string]string{"swap": "enabled"}),
+	}
+	node := func(name string, nodeLabels map[string]string) corev1.Node {
+		return corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: nodeLabels}}
+	}
+	nodes := []corev1.Node{
+		node("master-0", map[string]string{"node-role.kubernetes.io/master": ""}),
+		node("worker-0", map[string]string{"node-role.kubernetes.io/worker": "", "hw": "small"}),
+		node("worker-1", map[string]string{"node-role.kubernetes.io/worker": "", "hw": "bigmem"}),
+		node("virt-0", map[string]string{"node-role.kubernetes.io/worker": "", "node-role.kubernetes.io/worker-virt": "", "hw": "bigmem"}),
+		node("virt-1", map[string]string{"node-role.kubernetes.io/worker": "", "node-role.kubernetes.io/worker-virt": "", "hw": "bigmem"}),
+	}
+
+	tests := []struct {
+		name        string
+		spec        nodeswap.NodeSwapSpec
+		want        []string
+		wantPartial []string
+		wantErr     bool
+	}{
+		{
+			name: "pools by name",
+			spec: nodeswap.NodeSwapSpec{MachineConfigPoolNames: []string{"worker-virt", "worker"}},
+			want: []string{"worker", "worker-virt"},
+		},
+		{
+			name:    "unknown pool name",
+			spec:    nodeswap.NodeSwapSpec{MachineConfigPoolNames: []string{"infra"}},
+			wantErr: true,
+		},
+		{
+			name: "union of pool selector and names",
+			spec: nodeswap.NodeSwapSpec{
+				PoolSelector:           &metav1.LabelSelector{MatchLabels: map[string]string{"swap": "enabled"}},
+				MachineConfigPoolNames: []string{"master"},
+			},
+			want: []string{"master", "worker-virt"},
+		},
+		{
+			name: "node selector fully covering a custom pool",
+			spec: nodeswap.NodeSwapSpec{NodeSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"node-role.kubernetes.io/worker-virt": ""},
+			}},
+			want: []string{"worker-virt"},
+		},
+		{
+			name: "node selector partially covering the worker pool",
+			spec: nodeswap.NodeSwapSpec{NodeSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"hw": "bigmem"},
+			}},
+			want:        []string{"worker-virt"},
+			wantPartial: []string{"worker (1 of 2 nodes selected)"},
+		},
+		{
+			name: "node selector matching nothing",
+			spec: nodeswap.NodeSwapSpec{NodeSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"hw": "gpu"},
+			}},
+		},
+		{
+			name: "dedicated pool selected while it holds no node yet",
+			spec: nodeswap.NodeSwapSpec{
+				NodeSelector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{"node-role.kubernetes.io/worker-virt": ""},
+				},
+				DedicatedPool: &nodeswap.DedicatedPoolSpec{Name: "swap"},
+			},
+			want: []string{"worker-virt", "swap"},
+		},
+		{
+			name: "invalid node selector",
+			spec: nodeswap.NodeSwapSpec{NodeSelector: &metav1.LabelSelector{
+				MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "hw", Operator: "Near"}},
+			}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selector, err := newPoolSelector(&tt.spec)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			selection, err := selectPools(&tt.spec, selector, pools, nodes)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var got []string
+			for _, mcp := range selection.Pools {
+				got = append(got, mcp.Name)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("selected pools = %v, want %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(selection.PartiallyCovered, tt.wantPartial) {
+				t.Fatalf("partially covered pools = %v, want %v", selection.PartiallyCovered, tt.wantPartial)
+			}
+		})
+	}
+}
+
+func TestDeletedPools(t *testing.T) {
+	status := []nodeswap.PoolStatus{{Name: "worker"}, {Name: "swap"}}
+	pools := []mcfgv1.MachineConfigPool{*newTestPool("worker", nil, nil), *newTestPool("infra", nil, nil)}
+
+	if got := deletedPools(status, pools); !reflect.DeepEqual(got, []string{"swap"}) {
+		t.Fatalf("deleted pools = %v, want [swap]", got)
+	}
+	if got := deletedPools(nil, pools); got != nil {
+		t.Fatalf("deleted pools without status = %v, want none", got)
+	}
+
+	err := &noMatchingPoolsError{deleted: []string{"swap"}}
+	want := "no MachineConfigPool matches the NodeSwap, MachineConfigPools swap were deleted and their MachineConfigs retained"
+	if err.Error() != want {
+		t.Fatalf("error = %q, want %q", err.Error(), want)
+	}
+}
@@ -0,0 +1,210 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	mcfgv1 "github.com/openshift/api/machineconfiguration/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	nodeswap "github.com/openshift-virtualization/swap-operator/api/v1beta1"
+)
+
+// machineConfigHash returns the SHA-256 of the MachineConfig spec. The
+// Ignition config is normalized first, so that its formatting and the order
+// of its keys do not change the hash.
+func machineConfigHash(mc *mcfgv1.MachineConfig) (string, error) {
+	spec := mc.Spec.DeepCopy()
+	if len(spec.Config.Raw) > 0 {
+		config, err := normalizeJSON(spec.Config.Raw)
+		if err != nil {
+			return "", err
+		}
+		spec.Config.Raw = []byte(config)
+	}
+
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// recordMachineConfig adds the MachineConfig to the ones reported in the
+// status of the NodeSwap.
+func (s *reconcileState) recordMachineConfig(mc *mcfgv1.MachineConfig) error {
+	hash, err := machineConfigHash(mc)
+	if err != nil {
+		return err
+	}
+	s.machineConfigs = append(s.machineConfigs, nodeswap.RenderedMachineConfig{Name: mc.Name, Hash: hash})
+
+	return nil
+}
+
+// setRolloutStatus reports the selected pools, their nodes and the rendered
+// MachineConfigs in the status of the NodeSwap. renderedPools are the pools
+// whose rendered configuration includes the content of the MachineConfigs.
+func setRolloutStatus(status *nodeswap.NodeSwapStatus, pools []*mcfgv1.MachineConfigPool,
+	machineConfigs []nodeswap.RenderedMachineConfig, renderedPools sets.Set[string], nodes nodeswap.NodeRolloutStatus) {
+	names := machineConfigNames(machineConfigs)
+	status.MachineConfigs = slices.SortedFunc(slices.Values(machineConfigs),
+		func(a, b nodeswap.RenderedMachineConfig) int { return strings.Compare(a.Name, b.Name) })
+	status.Nodes = nodes
+	status.Pools = make([]nodeswap.PoolStatus, 0, len(pools))
+	status.MachineCount, status.UpdatedMachineCount, status.DegradedMachineCount = 0, 0, 0
+	for _, mcp := range pools {
+		pool := newPoolStatus(mcp, names, renderedPools)
+		status.Pools = append(status.Pools, pool)
+		status.MachineCount += pool.MachineCount
+		status.DegradedMachineCount += pool.DegradedMachineCount
+		if pool.MachineConfigsRendered {
+			status.UpdatedMachineCount += pool.UpdatedMachineCount
+		}
+	}
+	slices.SortFunc(status.Pools, func(a, b nodeswap.PoolStatus) int { return strings.Compare(a.Name, b.Name) })
+}
+
+// newPoolStatus reports the rollout of the named MachineConfigs on the pool.
+func newPoolStatus(mcp *mcfgv1.MachineConfigPool, names []string, renderedPools sets.Set[string]) nodeswap.PoolStatus {
+	return nodeswap.PoolStatus{
+		Name:                   mcp.Name,
+		RenderedConfig:         mcp.Spec.Configuration.Name,
+		MachineConfigsRendered: includesMachineConfigs(mcp.Spec.Configuration, names) && renderedPools.Has(mcp.Name),
+		MachineCount:           mcp.Status.MachineCount,
+		UpdatedMachineCount:    mcp.Status.UpdatedMachineCount,
+		DegradedMachineCount:   mcp.Status.DegradedMachineCount,
+	}
+}
+
+// includesMachineConfigs reports whether the rendered configuration is made
+// of all the named MachineConfigs.
+func includesMachineConfigs(config mcfgv1.MachineConfigPoolStatusConfiguration, names []string) bool {
+	if config.Name == "" {
+		return false
+	}
+
+	return !slices.ContainsFunc(names, func(name string) bool {
+		return !slices.ContainsFunc(config.Source, func(source corev1.ObjectReference) bool {
+			return source.Name == name
+		})
+	})
+}
+
+func machineConfigNames(machineConfigs []nodeswap.RenderedMachineConfig) []string {
+	names := make([]string, 0, len(machineConfigs))
+	for _, mc := range machineConfigs {
+		names = append(names, mc.Name)
+	}
+
+	return names
+}
+
+// rolloutConditions returns the Progressing, Available and Degraded
+// conditions of a NodeSwap whose MachineConfigs are applied. A pool has
+// rolled them out once all its machines are updated to a configuration
+// including their current content, see rolledOut.
+func rolloutConditions(pools []*mcfgv1.MachineConfigPool,
+	machineConfigs []nodeswap.RenderedMachineConfig, renderedPools sets.Set[string]) []metav1.Condition {
+	names := machineConfigNames(machineConfigs)
+
+	var rollingOut, degraded []string
+	for _, mcp := range pools {
+		if !rolledOut(mcp, names, renderedPools) {
+			rollingOut = append(rollingOut, mcp.Name)
+		}
+		if condition := findPoolCondition(mcp, mcfgv1.MachineConfigPoolDegraded); condition != nil &&
+			condition.Status == corev1.ConditionTrue {
+			degraded = append(degraded, fmt.Sprintf("MachineConfigPool %s is degraded: %s: %s",
+				mcp.Name, condition.Reason, condition.Message))
+		}
+	}
+	slices.Sort(rollingOut)
+	slices.Sort(degraded)
+
+	conditions := make([]metav1.Condition, 0, 3)
+	if len(rollingOut) > 0 {
+		message := fmt.Sprintf("Waiting for MachineConfigPools %s to roll out the MachineConfigs",
+			strings.Join(rollingOut, ", "))
+		conditions = append(conditions,
+			metav1.Condition{
+				Type:    typeProgressingNodeSwap,
+				Status:  metav1.ConditionTrue,
+				Reason:  reasonRollingOut,
+				Message: message,
+			},
+			metav1.Condition{
+				Type:    typeAvailableNodeSwap,
+				Status:  metav1.ConditionFalse,
+				Reason:  reasonRollingOut,
+				Message: message,
+			})
+	} else {
+		conditions = append(conditions,
+			metav1.Condition{
+				Type:    typeProgressingNodeSwap,
+				Status:  metav1.ConditionFalse,
+				Reason:  reasonRolledOut,
+				Message: "",
+			},
+			metav1.Condition{
+				Type:    typeAvailableNodeSwap,
+				Status:  metav1.ConditionTrue,
+				Reason:  reasonRolledOut,
+				Message: "All MachineConfigPools rolled out the MachineConfigs",
+			})
+	}
+
+	if len(degraded) > 0 {
+		conditions = append(conditions, metav1.Condition{
+			Type:    typeDegradedNodeSwap,
+			Status:  metav1.ConditionTrue,
+			Reason:  reasonPoolDegraded,
+			Message: strings.Join(degraded, "; "),
+		})
+	} else {
+		conditions = append(conditions, metav1.Condition{
+			Type:    typeDegradedNodeSwap,
+			Status:  metav1.ConditionFalse,
+			Reason:  "ReconciliationSucceeded",
+			Message: "",
+		})
+	}
+
+	return conditions
+}
+
+func findPoolCondition(mcp *mcfgv1.MachineConfigPool,
+	conditionType mcfgv1.MachineConfigPoolConditionType) *mcfgv1.MachineConfigPoolCondition {
+	for i := range mcp.Status.Conditions {
+		if mcp.Status.Conditions[i].Type == conditionType {
+			return &mcp.Status.Conditions[i]
+		}
+	}
+
+	return nil
+}
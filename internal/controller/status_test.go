@@ -0,0 +1,193 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	mcfgv1 "github.com/openshift/api/machineconfiguration/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	nodeswap "github.com/openshift-virtualization/swap-operator/api/v1beta1"
+	"github.com/openshift-virtualization/swap-operator/internal/renderconfig"
+	"github.com/openshift-virtualization/swap-operator/internal/template"
+)
+
+func TestSetRolloutStatus(t *testing.T) {
+	pool := func(name, rendered string, sources []string, machines, updated, degraded int32) *mcfgv1.MachineConfigPool {
+		mcp := &mcfgv1.MachineConfigPool{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		mcp.Spec.Configuration.Name = rendered
+		for _, source := range sources {
+			mcp.Spec.Configuration.Source = append(mcp.Spec.Configuration.Source, corev1.ObjectReference{Name: source})
+		}
+		mcp.Status.MachineCount = machines
+		mcp.Status.UpdatedMachineCount = updated
+		mcp.Status.DegradedMachineCount = degraded
+		return mcp
+	}
+
+	machineConfigs := []nodeswap.RenderedMachineConfig{
+		{Name: "99-swap-kubelet-cgroups", Hash: "b"},
+		{Name: "99-filebased-swap-0", Hash: "a"},
+	}
+	pools := []*mcfgv1.MachineConfigPool{
+		// Still rolling out a configuration without swap.
+		pool("worker", "rendered-worker-1", []string{"00-worker"}, 3, 1, 1),
+		pool("infra", "rendered-infra-2",
+			[]string{"00-worker", "99-filebased-swap-0", "99-swap-kubelet-cgroups"}, 2, 1, 0),
+		// Still rendering the previous content of 99-filebased-swap-0.
+		pool("storage", "rendered-storage-1",
+			[]string{"00-worker", "99-filebased-swap-0", "99-swap-kubelet-cgroups"}, 1, 1, 0),
+	}
+
+	status := &nodeswap.NodeSwapStatus{}
+	nodes := nodeswap.NodeRolloutStatus{Applied: []string{"infra-0"}}
+	setRolloutStatus(status, pools, machineConfigs, sets.New("infra"), nodes)
+
+	want := &nodeswap.NodeSwapStatus{
+		Pools: []nodeswap.PoolStatus{
+			{
+				Name:                   "infra",
+				RenderedConfig:         "rendered-infra-2",
+				MachineConfigsRendered: true,
+				MachineCount:           2,
+				UpdatedMachineCount:    1,
+			},
+			{
+				Name:                "storage",
+				RenderedConfig:      "rendered-storage-1",
+				MachineCount:        1,
+				UpdatedMachineCount: 1,
+			},
+			{
+				Name:                 "worker",
+				RenderedConfig:       "rendered-worker-1",
+				MachineCount:         3,
+				UpdatedMachineCount:  1,
+				DegradedMachineCount: 1,
+			},
+		},
+		MachineConfigs: []nodeswap.RenderedMachineConfig{
+			{Name: "99-filebased-swap-0", Hash: "a"},
+			{Name: "99-swap-kubelet-cgroups", Hash: "b"},
+		},
+		MachineCount:         6,
+		UpdatedMachineCount:  1,
+		DegradedMachineCount: 1,
+		Nodes:                nodes,
+	}
+	if !reflect.DeepEqual(status, want) {
+		t.Fatalf("setRolloutStatus() = %+v, want %+v", status, want)
+	}
+}
+
+func TestMachineConfigHash(t *testing.T) {
+	mc := &mcfgv1.MachineConfig{ObjectMeta: metav1.ObjectMeta{Name: "99-filebased-swap-0"}}
+	mc.Spec.KernelArguments = []string{"a"}
+
+	hash, err := machineConfigHash(mc)
+	if err != nil {
+		t.Fatalf("machineConfigHash() error = %v", err)
+	}
+
+	mc.Labels = map[string]string{"a": "b"}
+	if same, _ := machineConfigHash(mc); same != hash {
+		t.Fatalf("machineConfigHash() changed with the labels")
+	}
+
+	mc.Spec.KernelArguments = []string{"b"}
+	if changed, _ := machineConfigHash(mc); changed == hash {
+		t.Fatalf("machineConfigHash() did not change with the spec")
+	}
+}
+
+func TestErrorReason(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{
+			name: "invalid swap",
+			err:  fmt.Errorf("render: %w", &renderconfig.InvalidSpecError{Err: errors.New("unknown swap type")}),
+			want: reasonInvalidSpec,
+		},
+		{name: "invalid selector", err: &specError{err: errors.New("invalid pool selector")}, want: reasonInvalidSpec},
+		{name: "template", err: &template.RenderError{Err: errors.New("failed to parse template")}, want: reasonRenderFailed},
+		{name: "missing pool", err: &poolNotFoundError{name: "swap"}, want: reasonPoolNotFound},
+		{name: "prerequisites", err: &prerequisitesError{missing: []string{"cgroup v2"}}, want: reasonPrerequisitesNotMet},
+		{name: "conflict", err: &conflictError{other: "swap", reason: "same pool"}, want: reasonConflictingNodeSwap},
+		{name: "partial coverage", err: &poolCoverageError{pools: []string{"worker"}}, want: reasonPoolsPartiallyCovered},
+		{name: "no matching pool", err: &noMatchingPoolsError{}, want: reasonNoMatchingPools},
+		{
+			name: "API call",
+			err:  apierrors.NewConflict(schema.GroupResource{Resource: "machineconfigs"}, "99-swap-oomd", errors.New("modified")),
+			want: reasonAPIError,
+		},
+		{name: "other", err: errors.New("no MachineConfigPool is selected"), want: reasonReconciliationFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := errorReason(tt.err); got != tt.want {
+				t.Errorf("errorReason() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetFailureConditions(t *testing.T) {
+	tests := []struct {
+		name            string
+		err             error
+		wantReason      string
+		wantDegraded    string
+		wantProgressing string
+	}{
+		{
+			name:            "failure",
+			err:             &poolNotFoundError{name: "swap"},
+			wantReason:      reasonPoolNotFound,
+			wantDegraded:    "Failed to reconcile: " + (&poolNotFoundError{name: "swap"}).Error(),
+			wantProgressing: "Reconciliation failed",
+		},
+		{
+			name:            "blocked rollout",
+			err:             &poolCoverageError{pools: []string{"worker"}},
+			wantReason:      reasonPoolsPartiallyCovered,
+			wantDegraded:    "nodeSelector partially covers MachineConfigPools: worker",
+			wantProgressing: blockedRolloutMessages[reasonPoolsPartiallyCovered],
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var conditions []metav1.Condition
+			setFailureConditions(&conditions, tt.err)
+
+			for conditionType, want := range map[string]struct {
+				status  metav1.ConditionStatus
+				message string
+			}{
+				typeDegradedNodeSwap:    {metav1.ConditionTrue, tt.wantDegraded},
+				typeProgressingNodeSwap: {metav1.ConditionFalse, tt.wantProgressing},
+				typeAvailableNodeSwap:   {metav1.ConditionFalse, ""},
+			} {
+				condition := meta.FindStatusCondition(conditions, conditionType)
+				if condition == nil {
+					t.Fatalf("missing %s condition", conditionType)
+				}
+				if condition.Status != want.status || condition.Reason != tt.wantReason || condition.Message != want.message {
+					t.Errorf("%s = %s/%s %q, want %s/%s %q", conditionType, condition.Status, condition.Reason,
+						condition.Message, want.status, tt.wantReason, want.message)
+				}
+			}
+		})
+	}
+}
@@ -25,6 +25,7 @@ import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -32,8 +33,10 @@ import (
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
-	nodeswapv1alpha1 "github.com/openshift-virtualization/swap-operator/api/v1alpha1"
+	nodeswapv1beta1 "github.com/openshift-virtualization/swap-operator/api/v1beta1"
+	configv1 "github.com/openshift/api/config/v1"
 	mcfgv1 "github.com/openshift/api/machineconfiguration/v1"
+	operatorv1 "github.com/openshift/api/operator/v1"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -60,12 +63,18 @@ var _ = BeforeSuite(func() {
 	ctx, cancel = context.WithCancel(context.TODO())
 
 	var err error
-	err = nodeswapv1alpha1.AddToScheme(scheme.Scheme)
+	err = nodeswapv1beta1.AddToScheme(scheme.Scheme)
 	Expect(err).NotTo(HaveOccurred())
 
 	err = mcfgv1.AddToScheme(scheme.Scheme)
 	Expect(err).NotTo(HaveOccurred())
 
+	err = configv1.AddToScheme(scheme.Scheme)
+	Expect(err).NotTo(HaveOccurred())
+
+	err = operatorv1.AddToScheme(scheme.Scheme)
+	Expect(err).NotTo(HaveOccurred())
+
 	// +kubebuilder:scaffold:scheme
 
 	By("bootstrapping test environment")
@@ -90,6 +99,18 @@ var _ = BeforeSuite(func() {
 	k8sClient, err = client.New(cfg, client.Options{Scheme: scheme.Scheme})
 	Expect(err).NotTo(HaveOccurred())
 	Expect(k8sClient).NotTo(BeNil())
+
+	By("creating the cluster version the preflight checks read")
+	clusterVersion := &configv1.ClusterVersion{
+		ObjectMeta: metav1.ObjectMeta{Name: clusterVersionName},
+		Spec:       configv1.ClusterVersionSpec{ClusterID: "6b1e1f5a-2d3c-4b5a-9e8f-0a1b2c3d4e5f"},
+	}
+	Expect(k8sClient.Create(ctx, clusterVersion)).To(Succeed())
+	clusterVersion.Status.Desired = configv1.Release{
+		Version: minimumClusterVersion,
+		Image:   "quay.io/openshift-release-dev/ocp-release:" + minimumClusterVersion + "-x86_64",
+	}
+	Expect(k8sClient.Status().Update(ctx, clusterVersion)).To(Succeed())
 })
 
 var _ = AfterSuite(func() {
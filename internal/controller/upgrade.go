@@ -0,0 +1,103 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	configv1 "github.com/openshift/api/config/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	nodeswap "github.com/openshift-virtualization/swap-operator/api/v1beta1"
+)
+
+// ReconcileClusterUpgrade holds the MachineConfigs back while the cluster
+// is upgrading, since rolling them out would reboot the nodes a second time.
+// The ClusterVersion is watched, so the NodeSwap is reconciled again once
+// the upgrade completes.
+func (r *NodeSwapReconciler) ReconcileClusterUpgrade(s *reconcileState) {
+	version, upgrading := clusterUpgrade(s.clusterVersion)
+	if !upgrading || s.hold != "" {
+		return
+	}
+
+	logf.FromContext(s.ctx).Info("Cluster upgrade in progress", "version", version)
+	s.hold = reasonWaitingForUpgrade
+	s.upgradeVersion = version
+}
+
+// clusterUpgrade returns the version the cluster is upgrading to and whether
+// an upgrade is in progress, that is the ClusterVersion is Progressing.
+func clusterUpgrade(cv *configv1.ClusterVersion) (string, bool) {
+	if cv == nil {
+		return "", false
+	}
+	for _, condition := range cv.Status.Conditions {
+		if condition.Type == configv1.OperatorProgressing {
+			return cv.Status.Desired.Version, condition.Status == configv1.ConditionTrue
+		}
+	}
+
+	return "", false
+}
+
+// clusterVersionRequests maps the ClusterVersion to every NodeSwap, as an
+// upgrade holds them all back.
+func (r *NodeSwapReconciler) clusterVersionRequests(ctx context.Context, obj client.Object) []reconcile.Request {
+	if obj.GetName() != clusterVersionName {
+		return nil
+	}
+
+	nodeSwaps := &nodeswap.NodeSwapList{}
+	if err := r.List(ctx, nodeSwaps); err != nil {
+		logf.FromContext(ctx).Error(err, "Failed to list NodeSwaps for ClusterVersion")
+		return nil
+	}
+	requests := make([]reconcile.Request, 0, len(nodeSwaps.Items))
+	for i := range nodeSwaps.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: nodeSwaps.Items[i].Name}})
+	}
+
+	return requests
+}
+
+// clusterVersionPredicate ignores ClusterVersion updates that neither start
+// nor complete an upgrade, such as the progress of the cluster operators or
+// the refresh of the available updates.
+func clusterVersionPredicate() predicate.Predicate {
+	return predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldCV, ok := e.ObjectOld.(*configv1.ClusterVersion)
+			if !ok {
+				return true
+			}
+			newCV, ok := e.ObjectNew.(*configv1.ClusterVersion)
+			if !ok {
+				return true
+			}
+
+			oldVersion, oldUpgrading := clusterUpgrade(oldCV)
+			newVersion, newUpgrading := clusterUpgrade(newCV)
+			return oldUpgrading != newUpgrading || oldVersion != newVersion
+		},
+	}
+}
@@ -0,0 +1,96 @@
+package controller
+
+import (
+	"testing"
+
+	configv1 "github.com/openshift/api/config/v1"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+func newTestClusterVersion(version string, progressing configv1.ConditionStatus) *configv1.ClusterVersion {
+	cv := &configv1.ClusterVersion{}
+	cv.Name = clusterVersionName
+	cv.Status.Desired.Version = version
+	cv.Status.Conditions = []configv1.ClusterOperatorStatusCondition{
+		{Type: configv1.OperatorAvailable, Status: configv1.ConditionTrue},
+		{Type: configv1.OperatorProgressing, Status: progressing},
+	}
+	return cv
+}
+
+func TestClusterUpgrade(t *testing.T) {
+	tests := []struct {
+		name          string
+		cv            *configv1.ClusterVersion
+		wantVersion   string
+		wantUpgrading bool
+	}{
+		{
+			name: "no ClusterVersion",
+		},
+		{
+			name: "no Progressing condition",
+			cv:   &configv1.ClusterVersion{},
+		},
+		{
+			name:        "upgraded",
+			cv:          newTestClusterVersion("4.19.2", configv1.ConditionFalse),
+			wantVersion: "4.19.2",
+		},
+		{
+			name:          "upgrading",
+			cv:            newTestClusterVersion("4.20.0", configv1.ConditionTrue),
+			wantVersion:   "4.20.0",
+			wantUpgrading: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			version, upgrading := clusterUpgrade(tt.cv)
+			if version != tt.wantVersion || upgrading != tt.wantUpgrading {
+				t.Errorf("clusterUpgrade() = %q, %v, want %q, %v", version, upgrading, tt.wantVersion, tt.wantUpgrading)
+			}
+		})
+	}
+}
+
+func TestClusterVersionPredicate(t *testing.T) {
+	tests := []struct {
+		name string
+		old  *configv1.ClusterVersion
+		new  *configv1.ClusterVersion
+		want bool
+	}{
+		{
+			name: "available updates refreshed",
+			old:  newTestClusterVersion("4.19.2", configv1.ConditionFalse),
+			new: func() *configv1.ClusterVersion {
+				cv := newTestClusterVersion("4.19.2", configv1.ConditionFalse)
+				cv.Status.AvailableUpdates = []configv1.Release{{Version: "4.19.3"}}
+				return cv
+			}(),
+		},
+		{
+			name: "upgrade started",
+			old:  newTestClusterVersion("4.19.2", configv1.ConditionFalse),
+			new:  newTestClusterVersion("4.20.0", configv1.ConditionTrue),
+			want: true,
+		},
+		{
+			name: "upgrade completed",
+			old:  newTestClusterVersion("4.20.0", configv1.ConditionTrue),
+			new:  newTestClusterVersion("4.20.0", configv1.ConditionFalse),
+			want: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := clusterVersionPredicate().Update(event.UpdateEvent{ObjectOld: tt.old, ObjectNew: tt.new})
+			if got != tt.want {
+				t.Errorf("Update() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
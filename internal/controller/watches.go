@@ -0,0 +1,196 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"maps"
+	"slices"
+
+	mcfgv1 "github.com/openshift/api/machineconfiguration/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	nodeswap "github.com/openshift-virtualization/swap-operator/api/v1beta1"
+)
+
+const (
+	// poolNameIndex indexes NodeSwaps by the names of the pools they
+	// select by name, including their dedicated pool.
+	poolNameIndex = "spec.machineConfigPoolNames"
+	// anyPoolIndexValue is indexed for NodeSwaps selecting pools by labels
+	// or by nodes, which may select any pool.
+	anyPoolIndexValue = "*"
+)
+
+// indexPoolNames returns the poolNameIndex values of a NodeSwap.
+func indexPoolNames(obj client.Object) []string {
+	ns, ok := obj.(*nodeswap.NodeSwap)
+	if !ok {
+		return nil
+	}
+
+	names := slices.Clone(ns.Spec.MachineConfigPoolNames)
+	if ns.Spec.DedicatedPool != nil {
+		names = append(names, ns.Spec.DedicatedPool.Name)
+	}
+	if ns.Spec.PoolSelector != nil || ns.Spec.MachineConfigPoolSelector != "" || ns.Spec.NodeSelector != nil {
+		names = append(names, anyPoolIndexValue)
+	}
+
+	return names
+}
+
+// poolRequests maps a MachineConfigPool to the NodeSwaps selecting it.
+func (r *NodeSwapReconciler) poolRequests(ctx context.Context, obj client.Object) []reconcile.Request {
+	mcp, ok := obj.(*mcfgv1.MachineConfigPool)
+	if !ok {
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, value := range []string{mcp.Name, anyPoolIndexValue} {
+		nodeSwaps := &nodeswap.NodeSwapList{}
+		if err := r.List(ctx, nodeSwaps, client.MatchingFields{poolNameIndex: value}); err != nil {
+			logf.FromContext(ctx).Error(err, "Failed to list NodeSwaps for MachineConfigPool", "name", mcp.Name)
+			continue
+		}
+		for i := range nodeSwaps.Items {
+			ns := &nodeSwaps.Items[i]
+			if value == anyPoolIndexValue && !selectsPool(&ns.Spec, mcp) {
+				continue
+			}
+			request := reconcile.Request{NamespacedName: types.NamespacedName{Name: ns.Name}}
+			if !slices.Contains(requests, request) {
+				requests = append(requests, request)
+			}
+		}
+	}
+
+	return requests
+}
+
+// poolEventHandler enqueues the NodeSwaps toRequests maps a MachineConfigPool
+// to. Updates enqueue those selecting the pool before and after the update, so
+// that NodeSwaps a relabeled pool is no longer selected by release it.
+func poolEventHandler(toRequests handler.MapFunc) handler.EventHandler {
+	enqueue := func(ctx context.Context, q workqueue.TypedRateLimitingInterface[reconcile.Request], objs ...client.Object) {
+		var requests []reconcile.Request
+		for _, obj := range objs {
+			for _, request := range toRequests(ctx, obj) {
+				if !slices.Contains(requests, request) {
+					requests = append(requests, request)
+				}
+			}
+		}
+		for _, request := range requests {
+			q.Add(request)
+		}
+	}
+
+	return handler.Funcs{
+		CreateFunc: func(ctx context.Context, e event.CreateEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			enqueue(ctx, q, e.Object)
+		},
+		UpdateFunc: func(ctx context.Context, e event.UpdateEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			enqueue(ctx, q, e.ObjectOld, e.ObjectNew)
+		},
+		DeleteFunc: func(ctx context.Context, e event.DeleteEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			enqueue(ctx, q, e.Object)
+		},
+		GenericFunc: func(ctx context.Context, e event.GenericEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			enqueue(ctx, q, e.Object)
+		},
+	}
+}
+
+// selectsPool reports whether the selectors of spec may select the pool.
+// Node selectors are resolved against the nodes of every pool, so they may
+// select any of them.
+func selectsPool(spec *nodeswap.NodeSwapSpec, mcp *mcfgv1.MachineConfigPool) bool {
+	if spec.NodeSelector != nil {
+		return true
+	}
+
+	selector, err := newPoolSelector(spec)
+	if err != nil || selector == nil {
+		return false
+	}
+
+	return selector.Matches(mcp)
+}
+
+// poolState is the part of a MachineConfigPool the reconciler acts upon.
+type poolState struct {
+	configuration string
+	machineCount  int32
+	updatedCount  int32
+	degradedCount int32
+	updated       corev1.ConditionStatus
+	updating      corev1.ConditionStatus
+	degraded      corev1.ConditionStatus
+}
+
+func newPoolState(mcp *mcfgv1.MachineConfigPool) poolState {
+	state := poolState{
+		configuration: mcp.Status.Configuration.Name,
+		machineCount:  mcp.Status.MachineCount,
+		updatedCount:  mcp.Status.UpdatedMachineCount,
+		degradedCount: mcp.Status.DegradedMachineCount,
+	}
+	for _, condition := range mcp.Status.Conditions {
+		switch condition.Type {
+		case mcfgv1.MachineConfigPoolUpdated:
+			state.updated = condition.Status
+		case mcfgv1.MachineConfigPoolUpdating:
+			state.updating = condition.Status
+		case mcfgv1.MachineConfigPoolDegraded:
+			state.degraded = condition.Status
+		}
+	}
+
+	return state
+}
+
+// machineConfigPoolPredicate ignores pool updates that change neither the
+// spec, the labels nor the rollout state, such as heartbeats of other
+// conditions or certificate expiry updates.
+func machineConfigPoolPredicate() predicate.Predicate {
+	return predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldPool, ok := e.ObjectOld.(*mcfgv1.MachineConfigPool)
+			if !ok {
+				return true
+			}
+			newPool, ok := e.ObjectNew.(*mcfgv1.MachineConfigPool)
+			if !ok {
+				return true
+			}
+
+			return oldPool.Generation != newPool.Generation ||
+				!maps.Equal(oldPool.Labels, newPool.Labels) ||
+				newPoolState(oldPool) != newPoolState(newPool)
+		},
+	}
+}
@@ -0,0 +1,239 @@
+package controller
+
+import (
+	"context"
+	"reflect"
+	"slices"
+	"testing"
+
+	mcfgv1 "github.com/openshift/api/machineconfiguration/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	nodeswap "github.com/openshift-virtualization/swap-operator/api/v1beta1"
+)
+
+func TestIndexPoolNames(t *testing.T) {
+	tests := []struct {
+		name string
+		spec nodeswap.NodeSwapSpec
+		want []string
+	}{
+		{
+			name: "nothing selected",
+		},
+		{
+			name: "pool names and dedicated pool",
+			spec: nodeswap.NodeSwapSpec{
+				MachineConfigPoolNames: []string{"worker", "infra"},
+				DedicatedPool:          &nodeswap.DedicatedPoolSpec{Name: "swap"},
+				NodeSelector:           &metav1.LabelSelector{},
+			},
+			want: []string{"worker", "infra", "swap", anyPoolIndexValue},
+		},
+		{
+			name: "pool selector",
+			spec: nodeswap.NodeSwapSpec{PoolSelector: &metav1.LabelSelector{}},
+			want: []string{anyPoolIndexValue},
+		},
+		{
+			name: "legacy pool selector",
+			spec: nodeswap.NodeSwapSpec{MachineConfigPoolSelector: "pools.operator.machineconfiguration.openshift.io/worker"},
+			want: []string{anyPoolIndexValue},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := indexPoolNames(&nodeswap.NodeSwap{Spec: tt.spec})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("indexPoolNames() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectsPool(t *testing.T) {
+	mcp := &mcfgv1.MachineConfigPool{ObjectMeta: metav1.ObjectMeta{
+		Name:   "infra",
+		Labels: map[string]string{"pools.operator.machineconfiguration.openshift.io/infra": ""},
+	}}
+
+	tests := []struct {
+		name string
+		spec nodeswap.NodeSwapSpec
+		want bool
+	}{
+		{
+			name: "matching pool selector",
+			spec: nodeswap.NodeSwapSpec{PoolSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"pools.operator.machineconfiguration.openshift.io/infra": ""},
+			}},
+			want: true,
+		},
+		{
+			name: "other pool selector",
+			spec: nodeswap.NodeSwapSpec{PoolSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"pools.operator.machineconfiguration.openshift.io/worker": ""},
+			}},
+		},
+		{
+			name: "node selector",
+			spec: nodeswap.NodeSwapSpec{NodeSelector: &metav1.LabelSelector{}},
+			want: true,
+		},
+		{
+			name: "pool names only",
+			spec: nodeswap.NodeSwapSpec{MachineConfigPoolNames: []string{"worker"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := selectsPool(&tt.spec, mcp); got != tt.want {
+				t.Fatalf("selectsPool() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPoolEventHandler(t *testing.T) {
+	// Each pool is selected by the NodeSwap named after its role label.
+	toRequests := func(_ context.Context, obj client.Object) []reconcile.Request {
+		role := obj.GetLabels()["role"]
+		return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: role}}}
+	}
+	pool := func(role string) *mcfgv1.MachineConfigPool {
+		return &mcfgv1.MachineConfigPool{ObjectMeta: metav1.ObjectMeta{
+			Name:   "swap",
+			Labels: map[string]string{"role": role},
+		}}
+	}
+
+	tests := []struct {
+		name string
+		old  string
+		new  string
+		want []string
+	}{
+		{
+			name: "unchanged labels",
+			old:  "a",
+			new:  "a",
+			want: []string{"a"},
+		},
+		{
+			name: "relabeled pool",
+			old:  "a",
+			new:  "b",
+			want: []string{"a", "b"},
+		},
+	}
+
+	h := poolEventHandler(toRequests)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := workqueue.NewTypedRateLimitingQueue(workqueue.DefaultTypedControllerRateLimiter[reconcile.Request]())
+			defer q.ShutDown()
+
+			h.Update(context.Background(), event.UpdateEvent{ObjectOld: pool(tt.old), ObjectNew: pool(tt.new)}, q)
+			var got []string
+			for q.Len() > 0 {
+				request, _ := q.Get()
+				got = append(got, request.Name)
+				q.Done(request)
+			}
+			slices.Sort(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Update() enqueued %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMachineConfigPoolPredicate(t *testing.T) {
+	pool := func(mutate func(*mcfgv1.MachineConfigPool)) *mcfgv1.MachineConfigPool {
+		mcp := &mcfgv1.MachineConfigPool{
+			ObjectMeta: metav1.ObjectMeta{Name: "worker", Generation: 1, Labels: map[string]string{"a": "b"}},
+			Status: mcfgv1.MachineConfigPoolStatus{
+				Configuration: mcfgv1.MachineConfigPoolStatusConfiguration{
+					ObjectReference: corev1.ObjectReference{Name: "rendered-worker-1"},
+				},
+				MachineCount:        3,
+				UpdatedMachineCount: 3,
+				Conditions: []mcfgv1.MachineConfigPoolCondition{
+					{Type: mcfgv1.MachineConfigPoolUpdated, Status: corev1.ConditionTrue},
+					{Type: mcfgv1.MachineConfigPoolUpdating, Status: corev1.ConditionFalse},
+					{Type: mcfgv1.MachineConfigPoolDegraded, Status: corev1.ConditionFalse},
+				},
+			},
+		}
+		if mutate != nil {
+			mutate(mcp)
+		}
+		return mcp
+	}
+
+	tests := []struct {
+		name   string
+		mutate func(*mcfgv1.MachineConfigPool)
+		want   bool
+	}{
+		{
+			name: "unchanged",
+		},
+		{
+			name: "irrelevant status change",
+			mutate: func(mcp *mcfgv1.MachineConfigPool) {
+				mcp.ResourceVersion = "2"
+				mcp.Status.ObservedGeneration = 1
+				mcp.Status.Conditions[0].LastTransitionTime = metav1.Now()
+				mcp.Status.Conditions = append(mcp.Status.Conditions, mcfgv1.MachineConfigPoolCondition{
+					Type: mcfgv1.MachineConfigPoolRenderDegraded, Status: corev1.ConditionFalse,
+				})
+			},
+		},
+		{
+			name:   "spec change",
+			mutate: func(mcp *mcfgv1.MachineConfigPool) { mcp.Generation = 2 },
+			want:   true,
+		},
+		{
+			name:   "label change",
+			mutate: func(mcp *mcfgv1.MachineConfigPool) { mcp.Labels["a"] = "c" },
+			want:   true,
+		},
+		{
+			name:   "new rendered configuration",
+			mutate: func(mcp *mcfgv1.MachineConfigPool) { mcp.Status.Configuration.Name = "rendered-worker-2" },
+			want:   true,
+		},
+		{
+			name:   "machine count change",
+			mutate: func(mcp *mcfgv1.MachineConfigPool) { mcp.Status.UpdatedMachineCount = 2 },
+			want:   true,
+		},
+		{
+			name: "updating",
+			mutate: func(mcp *mcfgv1.MachineConfigPool) {
+				mcp.Status.Conditions[1].Status = corev1.ConditionTrue
+			},
+			want: true,
+		},
+	}
+
+	p := machineConfigPoolPredicate()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := event.UpdateEvent{ObjectOld: pool(nil), ObjectNew: pool(tt.mutate)}
+			if got := p.Update(e); got != tt.want {
+				t.Fatalf("Update() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	nodeswap "github.com/openshift-virtualization/swap-operator/api/v1beta1"
+	"github.com/openshift-virtualization/swap-operator/internal/schedule"
+)
+
+// ReconcileMaintenanceWindow holds the MachineConfigs back outside of the
+// maintenance windows of the NodeSwap. It requeues when the window in
+// progress ends or the next one starts.
+func (r *NodeSwapReconciler) ReconcileMaintenanceWindow(s *reconcileState) (ctrl.Result, error) {
+	now := time.Now()
+	window, err := maintenanceWindowAt(s.desiredNodeSwap.Spec.MaintenanceWindows, now)
+	if err != nil {
+		logf.FromContext(s.ctx).Error(err, "Failed to evaluate maintenance windows")
+		return ctrl.Result{}, &specError{err: err}
+	}
+	s.maintenanceWindow = window
+	if window == nil {
+		return ctrl.Result{}, nil
+	}
+
+	if window.Open {
+		return ctrl.Result{RequeueAfter: window.End.Sub(now)}, nil
+	}
+
+	if s.hold == "" {
+		logf.FromContext(s.ctx).Info("Outside of the maintenance windows", "next", window.Start)
+		s.hold = reasonOutsideMaintenanceWindow
+	}
+	return ctrl.Result{RequeueAfter: window.Start.Sub(now)}, nil
+}
+
+// maintenanceWindowAt returns the maintenance window in progress at now, the
+// one ending last if several are, or else the next one to start. It returns
+// nil when there are no maintenance windows.
+func maintenanceWindowAt(windows []nodeswap.MaintenanceWindow, now time.Time) (*nodeswap.MaintenanceWindowStatus, error) {
+	var current, next *nodeswap.MaintenanceWindowStatus
+	for _, window := range windows {
+		location := time.UTC
+		if window.TimeZone != "" {
+			var err error
+			if location, err = time.LoadLocation(window.TimeZone); err != nil {
+				return nil, fmt.Errorf("invalid maintenance window time zone %q: %w", window.TimeZone, err)
+			}
+		}
+		sched, err := schedule.Parse(window.Schedule)
+		if err != nil {
+			return nil, fmt.Errorf("invalid maintenance window: %w", err)
+		}
+		if window.Duration.Duration <= 0 {
+			return nil, fmt.Errorf("maintenance window %q has a non-positive duration %s",
+				window.Schedule, window.Duration.Duration)
+		}
+
+		// The first start after now-duration is either in progress or the
+		// next one.
+		start := sched.Next(now.Add(-window.Duration.Duration).In(location))
+		if start.IsZero() {
+			return nil, fmt.Errorf("maintenance window %q never starts", window.Schedule)
+		}
+		status := &nodeswap.MaintenanceWindowStatus{
+			Open:  !start.After(now),
+			Start: metav1.NewTime(start),
+			End:   metav1.NewTime(start.Add(window.Duration.Duration)),
+		}
+
+		switch {
+		case status.Open:
+			if current == nil || status.End.After(current.End.Time) {
+				current = status
+			}
+		case next == nil || status.Start.Before(&next.Start):
+			next = status
+		}
+	}
+
+	if current != nil {
+		return current, nil
+	}
+	return next, nil
+}
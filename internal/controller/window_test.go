@@ -0,0 +1,102 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	nodeswap "github.com/openshift-virtualization/swap-operator/api/v1beta1"
+)
+
+func TestMaintenanceWindowAt(t *testing.T) {
+	window := func(schedule string, duration time.Duration, timeZone string) nodeswap.MaintenanceWindow {
+		return nodeswap.MaintenanceWindow{
+			Schedule: schedule,
+			Duration: metav1.Duration{Duration: duration},
+			TimeZone: timeZone,
+		}
+	}
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2025, 1, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	// Thursday.
+	now := at(2, 10, 30)
+
+	tests := []struct {
+		name      string
+		windows   []nodeswap.MaintenanceWindow
+		wantOpen  bool
+		wantStart time.Time
+		wantEnd   time.Time
+		wantNil   bool
+		wantError bool
+	}{
+		{name: "no windows", wantNil: true},
+		{
+			name:      "in progress",
+			windows:   []nodeswap.MaintenanceWindow{window("0 10 * * *", time.Hour, "")},
+			wantOpen:  true,
+			wantStart: at(2, 10, 0),
+			wantEnd:   at(2, 11, 0),
+		},
+		{
+			name:      "ended",
+			windows:   []nodeswap.MaintenanceWindow{window("0 9 * * *", time.Hour, "")},
+			wantStart: at(3, 9, 0),
+			wantEnd:   at(3, 10, 0),
+		},
+		{
+			name: "earliest next window",
+			windows: []nodeswap.MaintenanceWindow{
+				window("0 2 * * 6", 4*time.Hour, ""),
+				window("0 22 * * 4", 2*time.Hour, ""),
+			},
+			wantStart: at(2, 22, 0),
+			wantEnd:   at(3, 0, 0),
+		},
+		{
+			name: "longest window in progress",
+			windows: []nodeswap.MaintenanceWindow{
+				window("0 10 * * *", time.Hour, ""),
+				window("0 8 * * *", 4*time.Hour, ""),
+			},
+			wantOpen:  true,
+			wantStart: at(2, 8, 0),
+			wantEnd:   at(2, 12, 0),
+		},
+		{
+			name:      "time zone",
+			windows:   []nodeswap.MaintenanceWindow{window("0 5 * * *", time.Hour, "Europe/Paris")},
+			wantStart: at(3, 4, 0),
+			wantEnd:   at(3, 5, 0),
+		},
+		{name: "invalid schedule", windows: []nodeswap.MaintenanceWindow{window("0 25 * * *", time.Hour, "")}, wantError: true},
+		{name: "invalid time zone", windows: []nodeswap.MaintenanceWindow{window("0 2 * * *", time.Hour, "Mars/Olympus")}, wantError: true},
+		{name: "no duration", windows: []nodeswap.MaintenanceWindow{window("0 2 * * *", 0, "")}, wantError: true},
+		{name: "never starts", windows: []nodeswap.MaintenanceWindow{window("0 0 30 2 *", time.Hour, "")}, wantError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := maintenanceWindowAt(tt.windows, now)
+			if (err != nil) != tt.wantError {
+				t.Fatalf("maintenanceWindowAt() error = %v, wantError %v", err, tt.wantError)
+			}
+			if tt.wantError {
+				return
+			}
+			if (got == nil) != tt.wantNil {
+				t.Fatalf("maintenanceWindowAt() = %v, wantNil %v", got, tt.wantNil)
+			}
+			if got == nil {
+				return
+			}
+			if got.Open != tt.wantOpen || !got.Start.Time.Equal(tt.wantStart) || !got.End.Time.Equal(tt.wantEnd) {
+				t.Errorf("maintenanceWindowAt() = open %v from %v to %v, want open %v from %v to %v",
+					got.Open, got.Start.Time, got.End.Time, tt.wantOpen, tt.wantStart, tt.wantEnd)
+			}
+		})
+	}
+}
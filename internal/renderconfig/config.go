@@ -1,11 +1,15 @@
 package renderconfig
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"strconv"
+	"strings"
 
-	nodeswap "github.com/openshift-virtualization/swap-operator/api/v1alpha1"
+	nodeswap "github.com/openshift-virtualization/swap-operator/api/v1beta1"
 	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/validation"
 )
 
 const (
@@ -13,10 +17,13 @@ const (
 	DiskBasedSwapMCPrefix      = "99-diskbased-swap"
 	ZramBasedSwapMCPrefix      = "99-zrambased-swap"
 	SwapKubeletCgroupsMCPrefix = "99-swap-kubelet-cgroups"
+	OomdMCPrefix               = "99-swap-oomd"
 )
 
 type RenderConfig struct {
-	Name                string
+	Name string
+	// TemplateName is the template directory the MachineConfig is rendered from.
+	TemplateName        string
 	Index               int
 	EnableFileBasedSwap bool
 	EnableDiskBasedSwap bool
@@ -24,15 +31,42 @@ type RenderConfig struct {
 	SwapFileSize        string
 	SwapFilePath        string
 	SwapDevicePriotiry  uint
+
+	EnableOomd                     bool
+	OomdSwapUsedLimit              string
+	OomdDefaultMemoryPressureLimit string
+	OomdSlices                     []OomdSliceConfig
+}
+
+// InvalidSpecError is returned for a NodeSwap spec that cannot be rendered
+// until it is edited.
+type InvalidSpecError struct {
+	Err error
+}
+
+func (e *InvalidSpecError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *InvalidSpecError) Unwrap() error {
+	return e.Err
+}
+
+// OomdSliceConfig is the systemd-oomd policy rendered for a single slice.
+type OomdSliceConfig struct {
+	Name           string
+	ManagedOOMSwap string
 }
 
-func Create(spec *nodeswap.NodeSwapSpec) ([]RenderConfig, error) {
+// Create returns the render configs of the swap MachineConfigs of the
+// NodeSwap named nodeSwapName.
+func Create(nodeSwapName string, spec *nodeswap.NodeSwapSpec) ([]RenderConfig, error) {
 	configs := []RenderConfig{}
 
 	for idx, swap := range spec.Swaps {
-		config, err := render(idx, &swap)
+		config, err := render(nodeSwapName, idx, &swap)
 		if err != nil {
-			return nil, err
+			return nil, &InvalidSpecError{Err: err}
 		}
 		configs = append(configs, config)
 	}
@@ -40,24 +74,27 @@ func Create(spec *nodeswap.NodeSwapSpec) ([]RenderConfig, error) {
 	return configs, nil
 }
 
-func render(id int, swap *nodeswap.SwapSpec) (RenderConfig, error) {
+func render(nodeSwapName string, id int, swap *nodeswap.SwapSpec) (RenderConfig, error) {
 	var err error
 	config := RenderConfig{Index: id}
-	name, err := generateName(id, swap)
+	name, err := generateName(nodeSwapName, id, swap)
 	if err != nil {
 		return RenderConfig{}, err
 	}
 
 	switch swap.SwapType {
 	case nodeswap.FileBasedSwap:
-		config.EnableFileBasedSwap = true
 		config, err = generateFileBasedSwapConfig(swap.File)
+		config.EnableFileBasedSwap = true
+		config.TemplateName = FileBasedSwapMCPrefix
 	case nodeswap.SwapOnDisk:
-		config.EnableDiskBasedSwap = true
 		config, err = generateDiskBasedSwapConfig(swap.Disk)
+		config.EnableDiskBasedSwap = true
+		config.TemplateName = DiskBasedSwapMCPrefix
 	case nodeswap.SwapOnZram:
-		config.EnableZramBasedSwap = true
 		config, err = generateZramBasedSwapConfig(swap.Zram)
+		config.EnableZramBasedSwap = true
+		config.TemplateName = ZramBasedSwapMCPrefix
 	default:
 		return RenderConfig{}, fmt.Errorf("unknown swap type: %s", swap.SwapType)
 	}
@@ -67,24 +104,124 @@ func render(id int, swap *nodeswap.SwapSpec) (RenderConfig, error) {
 	}
 
 	config.Name = name
+	config.Index = id
 	config.SwapDevicePriotiry = uint(swap.Priority)
 	return config, nil
 }
 
-func generateName(id int, spec *nodeswap.SwapSpec) (string, error) {
+// CreateOomd returns the render config for the systemd-oomd MachineConfig
+// of the NodeSwap named nodeSwapName, or nil when systemd-oomd is not
+// enabled in the spec.
+func CreateOomd(nodeSwapName string, spec *nodeswap.NodeSwapSpec) (*RenderConfig, error) {
+	if spec.Oomd == nil {
+		return nil, nil
+	}
+
+	config, err := createOomd(nodeSwapName, spec.Oomd)
+	if err != nil {
+		return nil, &InvalidSpecError{Err: err}
+	}
+
+	return config, nil
+}
+
+func createOomd(nodeSwapName string, oomd *nodeswap.OomdSpec) (*RenderConfig, error) {
+	config := &RenderConfig{
+		Name:         OomdMachineConfigName(nodeSwapName),
+		TemplateName: OomdMCPrefix,
+		EnableOomd:   true,
+	}
+
+	var err error
+	if config.OomdSwapUsedLimit, err = OomdPercentArg(oomd.SwapUsedLimit); err != nil {
+		return nil, fmt.Errorf("invalid swapUsedLimit: %w", err)
+	}
+	if config.OomdDefaultMemoryPressureLimit, err = OomdPercentArg(oomd.DefaultMemoryPressureLimit); err != nil {
+		return nil, fmt.Errorf("invalid defaultMemoryPressureLimit: %w", err)
+	}
+
+	for _, slice := range oomd.Slices {
+		if !strings.HasSuffix(slice.Name, ".slice") {
+			return nil, fmt.Errorf("oomd slice name must end with .slice, got %q", slice.Name)
+		}
+		switch slice.ManagedOOMSwap {
+		case "", nodeswap.ManagedOOMAuto, nodeswap.ManagedOOMKill:
+		default:
+			return nil, fmt.Errorf("unknown ManagedOOMSwap mode %q for slice %s", slice.ManagedOOMSwap, slice.Name)
+		}
+		config.OomdSlices = append(config.OomdSlices, OomdSliceConfig{
+			Name:           slice.Name,
+			ManagedOOMSwap: string(slice.ManagedOOMSwap),
+		})
+	}
+
+	return config, nil
+}
+
+func generateName(nodeSwapName string, id int, spec *nodeswap.SwapSpec) (string, error) {
+	suffix := "-" + strconv.Itoa(id)
 	switch spec.SwapType {
 	case nodeswap.FileBasedSwap:
-		return fmt.Sprintf("%s-%d", FileBasedSwapMCPrefix, id), nil
+		return machineConfigName(FileBasedSwapMCPrefix, nodeSwapName, suffix), nil
 	case nodeswap.SwapOnDisk:
-		return fmt.Sprintf("%s-%d", DiskBasedSwapMCPrefix, id), nil
+		return machineConfigName(DiskBasedSwapMCPrefix, nodeSwapName, suffix), nil
 	case nodeswap.SwapOnZram:
-		return fmt.Sprintf("%s-%d", ZramBasedSwapMCPrefix, id), nil
+		return machineConfigName(ZramBasedSwapMCPrefix, nodeSwapName, suffix), nil
 	}
 
 	return "", fmt.Errorf("unknown swap type: %s", spec.SwapType)
 }
 
+// CreateKubelet returns the render config for the kubelet MachineConfig of
+// the NodeSwap named nodeSwapName, which enables LimitedSwap on its pools.
+func CreateKubelet(nodeSwapName string) RenderConfig {
+	return RenderConfig{
+		Name:         KubeletMachineConfigName(nodeSwapName),
+		TemplateName: SwapKubeletCgroupsMCPrefix,
+	}
+}
+
+// KubeletMachineConfigName returns the name of the kubelet MachineConfig of
+// the NodeSwap named nodeSwapName.
+func KubeletMachineConfigName(nodeSwapName string) string {
+	return machineConfigName(SwapKubeletCgroupsMCPrefix, nodeSwapName, "")
+}
+
+// OomdMachineConfigName returns the name of the systemd-oomd MachineConfig
+// of the NodeSwap named nodeSwapName.
+func OomdMachineConfigName(nodeSwapName string) string {
+	return machineConfigName(OomdMCPrefix, nodeSwapName, "")
+}
+
+// machineConfigName returns the name of a MachineConfig of the NodeSwap:
+// the MachineConfigs are cluster-scoped, so their names include the name of
+// the NodeSwap, shortened to keep them valid.
+func machineConfigName(prefix, nodeSwapName, suffix string) string {
+	maxLength := validation.DNS1123SubdomainMaxLength - len(prefix) - len(suffix) - 1
+	return prefix + "-" + TruncateName(nodeSwapName, maxLength) + suffix
+}
+
+// nameHashLength is the length of the hash ending a truncated name.
+const nameHashLength = 8
+
+// TruncateName shortens name to at most maxLength characters. A shortened
+// name ends with a hash of the full name, so that names sharing a long
+// prefix stay distinct.
+func TruncateName(name string, maxLength int) string {
+	if len(name) <= maxLength {
+		return name
+	}
+
+	sum := sha256.Sum256([]byte(name))
+	hash := hex.EncodeToString(sum[:])[:nameHashLength]
+	return strings.TrimRight(name[:maxLength-nameHashLength-1], "-.") + "-" + hash
+}
+
 func generateFileBasedSwapConfig(swapConfig *nodeswap.SwapFile) (RenderConfig, error) {
+	if swapConfig == nil {
+		return RenderConfig{}, fmt.Errorf("file based swap requires the file settings")
+	}
+
 	size, err := MkswapSizeArg(swapConfig.Size)
 	if err != nil {
 		return RenderConfig{}, err
@@ -138,3 +275,26 @@ func MkswapSizeArg(q resource.Quantity) (string, error) {
 	// Fallback: raw bytes
 	return "", fmt.Errorf("swap size must be in Ki Mi or Gi formats, got %d", bytes)
 }
+
+// OomdPercentArg validates a systemd-oomd percentage such as "90%" and
+// returns it unchanged. An empty value keeps the systemd default.
+func OomdPercentArg(value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+
+	number, found := strings.CutSuffix(value, "%")
+	if !found {
+		return "", fmt.Errorf("percentage must end with %%, got %q", value)
+	}
+
+	percent, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return "", fmt.Errorf("invalid percentage %q: %w", value, err)
+	}
+	if percent < 0 || percent > 100 {
+		return "", fmt.Errorf("percentage must be between 0%% and 100%%, got %q", value)
+	}
+
+	return value, nil
+}
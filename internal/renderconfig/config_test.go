@@ -1,10 +1,14 @@
 package renderconfig
 
 import (
+	"errors"
+	"reflect"
+	"strings"
 	"testing"
 
-	nodeswap "github.com/openshift-virtualization/swap-operator/api/v1alpha1"
+	nodeswap "github.com/openshift-virtualization/swap-operator/api/v1beta1"
 	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/validation"
 )
 
 func TestMkswapSizeArg(t *testing.T) {
@@ -62,11 +66,12 @@ func TestMkswapSizeArg(t *testing.T) {
 
 func TestRender(t *testing.T) {
 	tests := []struct {
-		name     string
-		id       int
-		spec     *nodeswap.SwapSpec
-		wantName string
-		wantErr  bool
+		name         string
+		id           int
+		spec         *nodeswap.SwapSpec
+		wantName     string
+		wantTemplate string
+		wantErr      bool
 	}{
 		{
 			name: "file-based swap",
@@ -78,7 +83,16 @@ func TestRender(t *testing.T) {
 					Size: resource.MustParse("1Gi"),
 				},
 			},
-			wantName: "99-filebased-swap-0",
+			wantName:     "99-filebased-swap-worker-0",
+			wantTemplate: "99-filebased-swap",
+		},
+		{
+			name: "file-based swap without file settings returns error",
+			id:   0,
+			spec: &nodeswap.SwapSpec{
+				SwapType: nodeswap.FileBasedSwap,
+			},
+			wantErr: true,
 		},
 		{
 			name: "disk-based swap",
@@ -91,7 +105,8 @@ func TestRender(t *testing.T) {
 					},
 				},
 			},
-			wantName: "99-diskbased-swap-3",
+			wantName:     "99-diskbased-swap-worker-3",
+			wantTemplate: "99-diskbased-swap",
 		},
 		{
 			name: "zram-based swap",
@@ -102,7 +117,8 @@ func TestRender(t *testing.T) {
 					Size: resource.MustParse("512Mi"),
 				},
 			},
-			wantName: "99-zrambased-swap-1",
+			wantName:     "99-zrambased-swap-worker-1",
+			wantTemplate: "99-zrambased-swap",
 		},
 		{
 			name: "unknown swap type returns error",
@@ -116,7 +132,7 @@ func TestRender(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := render(tt.id, tt.spec)
+			got, err := render("worker", tt.id, tt.spec)
 			if tt.wantErr {
 				if err == nil {
 					t.Fatalf("expected error, got nil (config=%+v)", got)
@@ -131,6 +147,149 @@ func TestRender(t *testing.T) {
 			if got.Name != tt.wantName {
 				t.Fatalf("render() Name = %q, want %q", got.Name, tt.wantName)
 			}
+
+			if got.TemplateName != tt.wantTemplate {
+				t.Fatalf("render() TemplateName = %q, want %q", got.TemplateName, tt.wantTemplate)
+			}
+
+			if got.Index != tt.id {
+				t.Fatalf("render() Index = %d, want %d", got.Index, tt.id)
+			}
+
+			enabled := map[nodeswap.SwapType]bool{
+				nodeswap.FileBasedSwap: got.EnableFileBasedSwap,
+				nodeswap.SwapOnDisk:    got.EnableDiskBasedSwap,
+				nodeswap.SwapOnZram:    got.EnableZramBasedSwap,
+			}
+			for swapType, isEnabled := range enabled {
+				if isEnabled != (swapType == tt.spec.SwapType) {
+					t.Fatalf("render() enabled %s = %v for swap type %s", swapType, isEnabled, tt.spec.SwapType)
+				}
+			}
+		})
+	}
+}
+
+func TestCreateOomd(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    *nodeswap.NodeSwapSpec
+		want    *RenderConfig
+		wantErr bool
+	}{
+		{
+			name: "disabled by default",
+			spec: &nodeswap.NodeSwapSpec{},
+		},
+		{
+			name: "limits and slices",
+			spec: &nodeswap.NodeSwapSpec{
+				Oomd: &nodeswap.OomdSpec{
+					SwapUsedLimit:              "90%",
+					DefaultMemoryPressureLimit: "60.5%",
+					Slices: []nodeswap.OomdSlice{
+						{Name: "kubepods.slice", ManagedOOMSwap: nodeswap.ManagedOOMKill},
+						{Name: "system.slice", ManagedOOMSwap: nodeswap.ManagedOOMAuto},
+					},
+				},
+			},
+			want: &RenderConfig{
+				Name:                           "99-swap-oomd-worker",
+				TemplateName:                   "99-swap-oomd",
+				EnableOomd:                     true,
+				OomdSwapUsedLimit:              "90%",
+				OomdDefaultMemoryPressureLimit: "60.5%",
+				OomdSlices: []OomdSliceConfig{
+					{Name: "kubepods.slice", ManagedOOMSwap: "kill"},
+					{Name: "system.slice", ManagedOOMSwap: "auto"},
+				},
+			},
+		},
+		{
+			name: "empty section keeps systemd defaults",
+			spec: &nodeswap.NodeSwapSpec{Oomd: &nodeswap.OomdSpec{}},
+			want: &RenderConfig{
+				Name:         "99-swap-oomd-worker",
+				TemplateName: "99-swap-oomd",
+				EnableOomd:   true,
+			},
+		},
+		{
+			name:    "percentage above 100",
+			spec:    &nodeswap.NodeSwapSpec{Oomd: &nodeswap.OomdSpec{SwapUsedLimit: "120%"}},
+			wantErr: true,
+		},
+		{
+			name:    "percentage without percent sign",
+			spec:    &nodeswap.NodeSwapSpec{Oomd: &nodeswap.OomdSpec{DefaultMemoryPressureLimit: "60"}},
+			wantErr: true,
+		},
+		{
+			name: "slice name without .slice suffix",
+			spec: &nodeswap.NodeSwapSpec{Oomd: &nodeswap.OomdSpec{
+				Slices: []nodeswap.OomdSlice{{Name: "kubepods", ManagedOOMSwap: nodeswap.ManagedOOMKill}},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "unknown ManagedOOMSwap mode",
+			spec: &nodeswap.NodeSwapSpec{Oomd: &nodeswap.OomdSpec{
+				Slices: []nodeswap.OomdSlice{{Name: "kubepods.slice", ManagedOOMSwap: "always"}},
+			}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CreateOomd("worker", tt.spec)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got nil (config=%+v)", got)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("CreateOomd() = %+v, want %+v", got, tt.want)
+			}
 		})
 	}
 }
+
+func TestCreateInvalidSpec(t *testing.T) {
+	spec := &nodeswap.NodeSwapSpec{
+		Swaps: nodeswap.Swaps{{SwapType: "tape"}},
+		Oomd:  &nodeswap.OomdSpec{SwapUsedLimit: "90"},
+	}
+
+	var specErr *InvalidSpecError
+	if _, err := Create("worker", spec); !errors.As(err, &specErr) {
+		t.Errorf("Create() error = %v, want an InvalidSpecError", err)
+	}
+	if _, err := CreateOomd("worker", spec); !errors.As(err, &specErr) {
+		t.Errorf("CreateOomd() error = %v, want an InvalidSpecError", err)
+	}
+}
+
+func TestMachineConfigName(t *testing.T) {
+	if got, want := machineConfigName(FileBasedSwapMCPrefix, "worker", "-0"), "99-filebased-swap-worker-0"; got != want {
+		t.Errorf("machineConfigName() = %q, want %q", got, want)
+	}
+	if got, want := KubeletMachineConfigName("worker"), "99-swap-kubelet-cgroups-worker"; got != want {
+		t.Errorf("KubeletMachineConfigName() = %q, want %q", got, want)
+	}
+
+	long := strings.Repeat("a", validation.DNS1123SubdomainMaxLength)
+	name := machineConfigName(FileBasedSwapMCPrefix, long, "-0")
+	if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+		t.Errorf("machineConfigName() = %q is invalid: %v", name, errs)
+	}
+	if other := machineConfigName(FileBasedSwapMCPrefix, long[1:]+"b", "-0"); other == name {
+		t.Errorf("machineConfigName() = %q for two NodeSwaps", name)
+	}
+}
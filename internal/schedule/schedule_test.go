@@ -0,0 +1,73 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name      string
+		expr      string
+		wantError bool
+	}{
+		{name: "every minute", expr: "* * * * *"},
+		{name: "lists, ranges and steps", expr: "0,30 1-5/2 */10 1-12 1-5"},
+		{name: "sunday as 7", expr: "0 2 * * 7"},
+		{name: "too few fields", expr: "0 2 * *", wantError: true},
+		{name: "out of range", expr: "60 2 * * *", wantError: true},
+		{name: "reversed range", expr: "0 5-1 * * *", wantError: true},
+		{name: "zero step", expr: "*/0 * * * *", wantError: true},
+		{name: "not a number", expr: "0 2 * jan *", wantError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.expr)
+			if (err != nil) != tt.wantError {
+				t.Errorf("Parse(%q) error = %v, wantError %v", tt.expr, err, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestNext(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("no time zone database: %v", err)
+	}
+
+	// Thursday.
+	from := time.Date(2025, 1, 2, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		expr string
+		from time.Time
+		want time.Time
+	}{
+		{name: "next minute", expr: "* * * * *", from: from, want: time.Date(2025, 1, 2, 10, 31, 0, 0, time.UTC)},
+		{name: "strictly after", expr: "30 10 * * *", from: from, want: time.Date(2025, 1, 3, 10, 30, 0, 0, time.UTC)},
+		{name: "later today", expr: "0 22 * * *", from: from, want: time.Date(2025, 1, 2, 22, 0, 0, 0, time.UTC)},
+		{name: "weekend", expr: "0 2 * * 6,7", from: from, want: time.Date(2025, 1, 4, 2, 0, 0, 0, time.UTC)},
+		{name: "step", expr: "*/20 * * * *", from: from, want: time.Date(2025, 1, 2, 10, 40, 0, 0, time.UTC)},
+		{name: "next month", expr: "0 0 1 * *", from: from, want: time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)},
+		// The 15th is a Wednesday, Sunday the 5th comes first.
+		{name: "day of month or week", expr: "0 0 15 * 0", from: from, want: time.Date(2025, 1, 5, 0, 0, 0, 0, time.UTC)},
+		{name: "leap day", expr: "0 0 29 2 *", from: from, want: time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{name: "never", expr: "0 0 31 2 *", from: from},
+		{name: "time zone", expr: "0 2 * * *", from: from.In(newYork), want: time.Date(2025, 1, 3, 7, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Parse(tt.expr)
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tt.expr, err)
+			}
+			if got := s.Next(tt.from); !got.Equal(tt.want) {
+				t.Errorf("Next(%v) = %v, want %v", tt.from, got, tt.want)
+			}
+		})
+	}
+}
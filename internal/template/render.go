@@ -6,8 +6,10 @@ import (
 	"maps"
 	"os"
 	"path/filepath"
+	"regexp"
 	"slices"
 	"sort"
+	"strings"
 	"text/template"
 
 	ctrlcommon "github.com/openshift-virtualization/swap-operator/internal/common"
@@ -26,6 +28,9 @@ const (
 	arbiterRole   = "arbiter"
 )
 
+// documentSeparator matches a YAML document marker on its own line.
+var documentSeparator = regexp.MustCompile(`(?m)^---[ \t]*$`)
+
 // generateTemplateMachineConfigs returns MachineConfig objects from the templateDir and a config object
 // expected directory structure for correctly templating machine configs: <templatedir>/<role>/<name>/<platform>/<type>/<tmpl_file>
 //
@@ -101,18 +106,32 @@ func GenerateMachineConfigsForRole(config *renderconfig.RenderConfig, role, temp
 	return cfgs, nil
 }
 
+// RenderError is returned when the templates of a MachineConfig cannot be
+// parsed, executed or transpiled to an Ignition config.
+type RenderError struct {
+	Err error
+}
+
+func (e *RenderError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RenderError) Unwrap() error {
+	return e.Err
+}
+
 // renderTemplate renders a template file with values from a RenderConfig
 // returns the rendered file data
 func renderTemplate(config *renderconfig.RenderConfig, path string, b []byte) ([]byte, error) {
 	funcs := ctrlcommon.GetTemplateFuncMap()
 	tmpl, err := template.New(path).Funcs(funcs).Parse(string(b))
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse template %s: %w", path, err)
+		return nil, &RenderError{Err: fmt.Errorf("failed to parse template %s: %w", path, err)}
 	}
 
 	buf := new(bytes.Buffer)
 	if err := tmpl.Execute(buf, config); err != nil {
-		return nil, fmt.Errorf("failed to execute template: %w", err)
+		return nil, &RenderError{Err: fmt.Errorf("failed to execute template: %w", err)}
 	}
 
 	return buf.Bytes(), nil
@@ -144,13 +163,25 @@ func filterTemplates(toFilter map[string]string, path string, config *renderconf
 			return err
 		}
 
+		// A template rendering a list, such as one unit per configured slice,
+		// separates the entries with YAML document markers. Each document
+		// becomes its own file or unit, keyed after the template so that the
+		// ordering stays stable.
+		documents := splitDocuments(renderedData)
+		if len(documents) > 1 {
+			for i, document := range documents {
+				toFilter[fmt.Sprintf("%s-%03d", info.Name(), i)] = string(document)
+			}
+			return nil
+		}
+
 		// A template may result in no data when rendered, for example if the
 		// whole template is conditioned to specific values in render config.
 		// The intention is there shouldn't be any resulting file or unit form
 		// this template and thus we filter it here.
 		// Also trim the data in case the data only consists of an extra line or space
-		if len(bytes.TrimSpace(renderedData)) > 0 {
-			toFilter[info.Name()] = string(renderedData)
+		if len(documents) == 1 {
+			toFilter[info.Name()] = string(documents[0])
 		}
 
 		return nil
@@ -159,6 +190,18 @@ func filterTemplates(toFilter map[string]string, path string, config *renderconf
 	return filepath.Walk(path, walkFn)
 }
 
+// splitDocuments splits rendered template data on YAML document markers and
+// drops the documents that are empty once trimmed.
+func splitDocuments(data []byte) [][]byte {
+	documents := [][]byte{}
+	for _, document := range documentSeparator.Split(string(data), -1) {
+		if len(strings.TrimSpace(document)) > 0 {
+			documents = append(documents, []byte(document))
+		}
+	}
+	return documents
+}
+
 // existsDir returns true if path exists and is a directory, false if the path
 // does not exist, and error if there is a runtime error or the path is not a directory
 func existsDir(path string) (bool, error) {
@@ -175,6 +218,11 @@ func existsDir(path string) (bool, error) {
 	return true, nil
 }
 
+// HasTemplates returns true if path is an existing template directory.
+func HasTemplates(path string) (bool, error) {
+	return existsDir(path)
+}
+
 func getPaths() []string {
 	platformBasedPaths := []string{platformBase}
 
@@ -269,11 +317,11 @@ func GenerateMachineConfigForName(config *renderconfig.RenderConfig, role, name,
 
 	ignCfg, err := ctrlcommon.TranspileCoreOSConfigToIgn(keySortVals(files), keySortVals(units))
 	if err != nil {
-		return nil, fmt.Errorf("error transpiling CoreOS config to Ignition config: %w", err)
+		return nil, &RenderError{Err: fmt.Errorf("error transpiling CoreOS config to Ignition config: %w", err)}
 	}
 	mcfg, err := ctrlcommon.MachineConfigFromIgnConfig(role, name, ignCfg)
 	if err != nil {
-		return nil, fmt.Errorf("error creating MachineConfig from Ignition config: %w", err)
+		return nil, &RenderError{Err: fmt.Errorf("error creating MachineConfig from Ignition config: %w", err)}
 	}
 
 	mcfg.Spec.Extensions = append(mcfg.Spec.Extensions, slices.Sorted(maps.Keys(extensions))...)
@@ -205,3 +205,67 @@ contents: |
 		})
 	}
 }
+
+func TestGenerateOomdMachineConfig(t *testing.T) {
+	templateDir := filepath.Join("..", "..", "templates")
+	config := &renderconfig.RenderConfig{
+		Name:              renderconfig.OomdMCPrefix,
+		TemplateName:      renderconfig.OomdMCPrefix,
+		EnableOomd:        true,
+		OomdSwapUsedLimit: "90%",
+		OomdSlices: []renderconfig.OomdSliceConfig{
+			{Name: "kubepods.slice", ManagedOOMSwap: "kill"},
+			{Name: "system.slice"},
+			{Name: "user.slice", ManagedOOMSwap: "auto"},
+		},
+	}
+
+	path := filepath.Join(templateDir, "worker", renderconfig.OomdMCPrefix)
+	mc, err := GenerateMachineConfigForName(config, "worker", renderconfig.OomdMCPrefix, templateDir, path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ignCfg, err := ctrlcommon.ParseAndConvertConfig(mc.Spec.Config.Raw)
+	if err != nil {
+		t.Fatalf("failed to parse ignition config: %v", err)
+	}
+
+	if len(ignCfg.Storage.Files) != 1 {
+		t.Fatalf("expected 1 file, got %d", len(ignCfg.Storage.Files))
+	}
+	if path := ignCfg.Storage.Files[0].Path; path != "/etc/systemd/oomd.conf.d/99-swap.conf" {
+		t.Errorf("wrong oomd.conf path: %s", path)
+	}
+	contents := ignCfg.Storage.Files[0].Contents.Source
+	if contents == nil || !strings.Contains(*contents, "SwapUsedLimit%3D90%25") {
+		t.Errorf("SwapUsedLimit rendered incorrectly, got %v", contents)
+	}
+	if contents != nil && strings.Contains(*contents, "DefaultMemoryPressureLimit") {
+		t.Errorf("unset DefaultMemoryPressureLimit should not be rendered, got %s", *contents)
+	}
+
+	// systemd-oomd.service plus one unit per slice with a ManagedOOMSwap policy
+	units := map[string]string{}
+	for _, unit := range ignCfg.Systemd.Units {
+		dropin := ""
+		for _, d := range unit.Dropins {
+			if d.Contents != nil {
+				dropin = *d.Contents
+			}
+		}
+		units[unit.Name] = dropin
+	}
+	if len(units) != 3 {
+		t.Fatalf("expected 3 units, got %v", units)
+	}
+	if _, ok := units["systemd-oomd.service"]; !ok {
+		t.Error("missing systemd-oomd.service")
+	}
+	if !strings.Contains(units["kubepods.slice"], "ManagedOOMSwap=kill") {
+		t.Errorf("kubepods.slice dropin rendered incorrectly, got %q", units["kubepods.slice"])
+	}
+	if !strings.Contains(units["user.slice"], "ManagedOOMSwap=auto") {
+		t.Errorf("user.slice dropin rendered incorrectly, got %q", units["user.slice"])
+	}
+}